@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
@@ -9,28 +10,110 @@ import (
 	"time"
 
 	"urlshortener/internal/config"
+	"urlshortener/internal/http/handlers/system/storage_stats"
 	"urlshortener/internal/http/server"
 	"urlshortener/internal/logger"
 	"urlshortener/internal/repository/filestore"
 	"urlshortener/internal/repository/inmemory"
 	"urlshortener/internal/repository/postgres"
 	"urlshortener/internal/services/auth"
+	"urlshortener/internal/services/geoip"
+	"urlshortener/internal/services/idempotency"
+	"urlshortener/internal/services/link_cache"
+	"urlshortener/internal/services/link_policy"
+	"urlshortener/internal/services/qr_code"
+	"urlshortener/internal/services/quota"
+	"urlshortener/internal/services/rate_limit"
+	"urlshortener/internal/services/short_code"
 	"urlshortener/internal/services/url_shortener"
 
 	"github.com/rs/zerolog"
 )
 
+// rateLimitPruneInterval как часто удалять восстановившиеся корзины ограничителя частоты
+const rateLimitPruneInterval = 5 * time.Minute
+
+// idempotencyPruneInterval как часто удалять истекшие ключи идемпотентности
+const idempotencyPruneInterval = 10 * time.Minute
+
+// variantClicksFlushInterval как часто записывать в хранилище переходы по A/B-вариантам
+const variantClicksFlushInterval = 5 * time.Second
+
 func main() {
 	ctxRoot := context.Background()
 	cfg := config.NewConfig()
 	log := logger.NewLogger()
 	fileStore := filestore.NewFileStore(*log, cfg.FileStoragePath)
 
+	geoResolver, err := geoip.NewResolver(cfg.GeoIPDBPath, cfg.TrustedProxies)
+	if err != nil {
+		log.
+			Fatal().
+			Err(err).
+			Msg("Failed to initialize GeoIP resolver")
+		return
+	}
+	defer geoResolver.Close()
+	if geoResolver.Enabled() {
+		log.
+			Info().
+			Str("path", cfg.GeoIPDBPath).
+			Msg("Geo targeting enabled")
+	}
+
+	linkPolicy, err := link_policy.NewPolicy(link_policy.Config{
+		BlocklistPath:  cfg.BlocklistPath,
+		AllowlistPath:  cfg.AllowlistPath,
+		AllowlistOnly:  cfg.AllowlistOnly,
+		BlockPrivateIP: cfg.BlockPrivateAddresses,
+		BaseURL:        cfg.BaseURL,
+	})
+	if err != nil {
+		log.
+			Fatal().
+			Err(err).
+			Msg("Failed to initialize link policy")
+		return
+	}
+	ctxPolicy, cancelPolicy := context.WithCancel(ctxRoot)
+	defer cancelPolicy()
+	go linkPolicy.Watch(ctxPolicy, cfg.PolicyReloadInterval, log)
+
+	urlOptions := []url_shortener.Option{
+		url_shortener.WithGeoTargeting(geoResolver.Enabled()),
+		url_shortener.WithURLNormalization(url_shortener.URLNormalization{
+			AllowedSchemes:   cfg.AllowedSchemes,
+			StripDefaultPort: cfg.StripDefaultPort,
+			StripFragment:    cfg.StripFragment,
+		}),
+		url_shortener.WithDestinationPolicy(linkPolicy),
+		url_shortener.WithReportThreshold(cfg.ReportThreshold),
+	}
+
+	quotaLimits := quota.Limits{
+		MaxActiveLinks: cfg.QuotaMaxLinks,
+		MaxBatchSize:   cfg.QuotaMaxBatch,
+	}
+
+	cacheConfig := link_cache.Config{
+		Size:        cfg.RedirectCacheSize,
+		TTL:         cfg.RedirectCacheTTL,
+		NegativeTTL: cfg.RedirectCacheNegativeTTL,
+	}
+
 	var urlService *url_shortener.URLShortener
+	var linkCache *link_cache.Storage
+	var linkChanges link_cache.ChangeFeed
+	var quotaService *quota.Service
+	var codePool *short_code.Pool
 	var authService *auth.Authentication
+	var limiterStore rate_limit.Store
+	var idempotencyStore idempotency.Store
+	var replicaChecks *postgres.PostgresStorage
+	var dbStats storage_stats.ServiceStorageStats
 
 	if cfg.DatabaseDSN != "" {
-		storage, err := initPostgres(ctxRoot, log, cfg.DatabaseDSN)
+		storage, err := initPostgres(ctxRoot, log, cfg)
 		if err != nil {
 			log.
 				Error().
@@ -43,7 +126,11 @@ func main() {
 			initPostgresData(ctxRoot, log, storage, fileStore)
 
 			var errAuth error
-			urlService = url_shortener.NewServiceURLShortener(storage, cfg.BaseURL)
+			quotaService = quota.NewService(storage, quotaLimits)
+			codePool = initCodePool(log, cfg, storage)
+			linkCache = link_cache.NewStorage(storage, cacheConfig)
+			urlService = url_shortener.NewServiceURLShortener(linkCache, cfg.BaseURL,
+				append(urlOptions, url_shortener.WithQuota(quotaService), url_shortener.WithCodePool(codePool))...)
 			authService, errAuth = auth.NewAuthentication(storage, cfg.JWTSecretKey, cfg.JWTAccessExpire)
 			if errAuth != nil {
 				log.
@@ -53,6 +140,14 @@ func main() {
 
 				urlService = nil
 				authService = nil
+			} else {
+				idempotencyStore = storage
+				linkChanges = storage
+				replicaChecks = storage
+				dbStats = storage
+				if cfg.RateLimitStore == config.RateLimitStorePostgres {
+					limiterStore = storage
+				}
 			}
 		}
 	}
@@ -67,12 +162,18 @@ func main() {
 		initInMemoryData(ctxRoot, log, storage, fileStore)
 
 		var errAuth error
-		urlService = url_shortener.NewServiceURLShortener(storage, cfg.BaseURL)
+		quotaService = quota.NewService(storage, quotaLimits)
+		codePool = initCodePool(log, cfg, storage)
+		linkCache = link_cache.NewStorage(storage, cacheConfig)
+		urlService = url_shortener.NewServiceURLShortener(linkCache, cfg.BaseURL,
+			append(urlOptions, url_shortener.WithQuota(quotaService), url_shortener.WithCodePool(codePool))...)
 		authService, errAuth = auth.NewAuthentication(storage, cfg.JWTSecretKey, cfg.JWTAccessExpire)
 		if errAuth != nil {
 			log.Error().Err(errAuth).Msg("Failed to initialize authentication with in-memory storage")
 			urlService = nil
 			authService = nil
+		} else {
+			idempotencyStore = storage
 		}
 	}
 
@@ -83,12 +184,65 @@ func main() {
 		return
 	}
 
+	if limiterStore == nil {
+		if cfg.RateLimitStore == config.RateLimitStorePostgres {
+			log.
+				Warn().
+				Msg("PostgreSQL is unavailable, rate limits are kept in memory of this instance")
+		}
+		limiterStore = rate_limit.NewMemoryStore()
+	}
+	limiter := rate_limit.NewLimiter(limiterStore, map[string]rate_limit.Rule{
+		rate_limit.ScopeCreate:   {PerMinute: cfg.RateLimitCreate},
+		rate_limit.ScopeRegister: {PerMinute: cfg.RateLimitRegister},
+		rate_limit.ScopeRedirect: {PerMinute: cfg.RateLimitRedirect},
+		rate_limit.ScopeReport:   {PerMinute: cfg.ReportRatePerMinute},
+	})
+	ctxCodePool, cancelCodePool := context.WithCancel(ctxRoot)
+	defer cancelCodePool()
+	go codePool.Run(ctxCodePool, log)
+
+	// Изменения ссылок с других экземпляров приходят только через PostgreSQL
+	if linkChanges != nil {
+		ctxLinkChanges, cancelLinkChanges := context.WithCancel(ctxRoot)
+		defer cancelLinkChanges()
+		go linkCache.Run(ctxLinkChanges, linkChanges, log)
+	}
+
+	// Реплики для чтения есть только у PostgreSQL
+	if replicaChecks != nil {
+		ctxReplicas, cancelReplicas := context.WithCancel(ctxRoot)
+		defer cancelReplicas()
+		go replicaChecks.RunReplicaChecks(ctxReplicas, log)
+	}
+
+	ctxLimiter, cancelLimiter := context.WithCancel(ctxRoot)
+	defer cancelLimiter()
+	go limiter.Run(ctxLimiter, rateLimitPruneInterval, log)
+
+	idempotencyService := idempotency.NewService(idempotencyStore, cfg.IdempotencyTTL)
+	ctxIdempotency, cancelIdempotency := context.WithCancel(ctxRoot)
+	defer cancelIdempotency()
+	go idempotencyService.Run(ctxIdempotency, idempotencyPruneInterval, log)
+
+	// Переходы, накопленные к остановке, записываются до закрытия хранилища
+	ctxVariantClicks, cancelVariantClicks := context.WithCancel(ctxRoot)
+	defer flushVariantClicks(ctxRoot, log, urlService)
+	defer cancelVariantClicks()
+	go urlService.RunVariantClicks(ctxVariantClicks, variantClicksFlushInterval, log)
+
 	srv, err := server.
-		NewServer(log,
-			*cfg,
-			urlService,
-			authService,
-		)
+		NewServer(log, *cfg, server.Deps{
+			URLService:  urlService,
+			QuotaSvc:    quotaService,
+			AuthService: authService,
+			QREncoder:   qr_code.NewEncoder(),
+			GeoResolver: geoResolver,
+			Limiter:     limiter,
+			Idempotency: idempotencyService,
+			LinkCache:   linkCache,
+			DBStats:     dbStats,
+		})
 	if err != nil {
 		log.
 			Fatal().
@@ -148,8 +302,18 @@ func runServer(srv *server.Server, log *zerolog.Logger) {
 	}
 }
 
-func initPostgres(ctx context.Context, log *zerolog.Logger, dsn string) (*postgres.PostgresStorage, error) {
-	storage, err := postgres.NewStorage(ctx, dsn)
+func initPostgres(ctx context.Context, log *zerolog.Logger, cfg *config.Config) (*postgres.PostgresStorage, error) {
+	storage, err := postgres.NewStorage(ctx, log, cfg.DatabaseDSN, postgres.PoolConfig{
+		MaxConns:        int32(min(cfg.DBMaxConns, math.MaxInt32)),
+		MinConns:        int32(min(cfg.DBMinConns, math.MaxInt32)),
+		MaxConnLifetime: cfg.DBMaxConnLifetime,
+		MaxConnIdleTime: cfg.DBMaxConnIdleTime,
+		QueryExecMode:   cfg.DBQueryExecMode,
+	}, postgres.ReplicaConfig{
+		DSNs:          cfg.DatabaseReplicaDSNs,
+		CheckInterval: cfg.DBReplicaCheckInterval,
+		MaxLag:        cfg.DBReplicaMaxLag,
+	})
 	if err != nil {
 		log.
 			Error().
@@ -160,10 +324,34 @@ func initPostgres(ctx context.Context, log *zerolog.Logger, dsn string) (*postgr
 
 	log.
 		Info().
+		Int("replicas", len(cfg.DatabaseReplicaDSNs)).
 		Msg("Using PostgreSQL storage")
 	return storage, nil
 }
 
+// codeStorage резервирует коды и выдает номера для последовательных стратегий
+type codeStorage interface {
+	short_code.Store
+	short_code.Counter
+}
+
+func initCodePool(log *zerolog.Logger, cfg *config.Config, storage codeStorage) *short_code.Pool {
+	generator, err := short_code.NewGenerator(short_code.Config{
+		Strategy:          cfg.CodeStrategy,
+		Length:            cfg.CodeLength,
+		Alphabet:          cfg.CodeAlphabet,
+		ExcludeLookalikes: cfg.CodeExcludeLookalikes,
+		Salt:              cfg.CodeSalt,
+	}, storage)
+	if err != nil {
+		log.
+			Fatal().
+			Err(err).
+			Msg("Failed to initialize short code generator")
+	}
+	return short_code.NewPool(storage, generator, cfg.CodePoolSize)
+}
+
 func initInMemory(log *zerolog.Logger) *inmemory.InmemoryStorage {
 	log.
 		Info().
@@ -171,6 +359,18 @@ func initInMemory(log *zerolog.Logger) *inmemory.InmemoryStorage {
 	return inmemory.NewStorage()
 }
 
+func flushVariantClicks(ctx context.Context, log *zerolog.Logger, svc *url_shortener.URLShortener) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := svc.FlushVariantClicks(ctx); err != nil {
+		log.
+			Error().
+			Err(err).
+			Msg("Failed to flush variant clicks")
+	}
+}
+
 func closePostgresStorage(log *zerolog.Logger, storage *postgres.PostgresStorage) {
 	if err := storage.Close(); err != nil {
 		log.
@@ -181,6 +381,7 @@ func closePostgresStorage(log *zerolog.Logger, storage *postgres.PostgresStorage
 	}
 	log.
 		Info().
+		Uint64("tx_retries", storage.TxRetries()).
 		Msg("PostgreSQL storage closed successfully")
 }
 
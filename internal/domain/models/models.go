@@ -2,6 +2,9 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -19,13 +22,286 @@ type (
 		DeletedFlag bool   // soft delete
 		CreatedAt   time.Time
 		DeletedAt   time.Time
+		Title       string // заголовок, который пользователь указал при создании
+
+		RedirectStatus int   // HTTP-код перенаправления (301, 302, 307, 308), 0 - значение сервера по умолчанию
+		PassQuery      bool  // передавать параметры запроса в URL назначения
+		PassPath       bool  // дописывать хвост пути после кода к URL назначения
+		UTMTemplateID  int64 // UTM-шаблон, добавляемый при перенаправлении, 0 - нет
+
+		RedirectRules []RedirectRule // правила выбора назначения, проверяются по порядку до OriginalURL
+
+		Variants       []LinkVariant // A/B-варианты назначения, выбираются по весу, если не сработало правило
+		StickyVariants bool          // закреплять выбранный вариант за клиентом через cookie
+
+		ActiveFrom  time.Time // до этого момента ссылка еще не работает, нулевое значение - без ограничения
+		ActiveUntil time.Time // с этого момента ссылка больше не работает, нулевое значение - без ограничения
+
+		Moderation string // ModerationNone | ModerationQuarantined | ModerationBanned
+	}
+
+	// AbuseReport жалоба на ссылку
+	AbuseReport struct {
+		ID        int64
+		LinkID    int64
+		Reason    string // одна из AbuseReason*
+		Details   string // необязательный комментарий
+		Reporter  string // адрес отправителя, жалобы одного отправителя считаются один раз
+		Resolved  bool   // жалоба рассмотрена администратором
+		CreatedAt time.Time
+	}
+
+	// AbuseReportSummary ссылка с нерассмотренными жалобами для очереди модерации
+	AbuseReportSummary struct {
+		Link         ShortenedLink
+		Reports      int // число нерассмотренных жалоб
+		Reporters    int // число разных отправителей среди них
+		LastReportAt time.Time
+	}
+
+	// LinkVariant одно из назначений ссылки с распределением трафика по весу
+	LinkVariant struct {
+		Name      string // идентификатор варианта в пределах ссылки, по нему считаются переходы
+		TargetURL string
+		Weight    int   // доля трафика относительно остальных вариантов, 0 - вариант на паузе
+		Clicks    int64 // число переходов, заполняется только в статистике
+	}
+
+	// VariantClicks переходы по варианту ссылки, накопленные между записями в хранилище
+	VariantClicks struct {
+		LinkID  int64
+		Variant string
+		Clicks  int64
 	}
+
+	// RedirectTarget результат выбора назначения перенаправления
+	RedirectTarget struct {
+		URL     string
+		Variant string // выбранный A/B-вариант, пусто - ссылка без вариантов или сработало правило
+	}
+
+	// RedirectRule отправляет на TargetURL запросы, подходящие под условие
+	RedirectRule struct {
+		Type      string // platform | language | header | country
+		Header    string // имя заголовка для Type == header
+		Value     string // платформа, языковой тег, значение заголовка (пусто - заголовок просто задан) или коды стран через запятую
+		TargetURL string
+	}
+
+	// RedirectRequest данные входящего запроса, от которых зависит адрес перенаправления
+	RedirectRequest struct {
+		ExtraPath string      // хвост пути после кода
+		Query     url.Values  // параметры запроса
+		Header    http.Header // заголовки запроса, в том числе User-Agent и Accept-Language
+		Country   string      // ISO-код страны клиента, пусто - не определена
+		Variant   string      // закрепленный за клиентом A/B-вариант из cookie
+	}
+
+	// UTMTemplate именованный набор utm_* параметров пользователя
+	UTMTemplate struct {
+		ID        int64
+		UserID    int64
+		Name      string
+		Source    string // utm_source
+		Medium    string // utm_medium
+		Campaign  string // utm_campaign
+		Term      string // utm_term
+		Content   string // utm_content
+		CreatedAt time.Time
+	}
+
+	// ShortenedLinkUpdate изменяемые настройки ссылки, nil - оставить без изменений
+	ShortenedLinkUpdate struct {
+		Title          *string
+		RedirectStatus *int
+		PassQuery      *bool
+		PassPath       *bool
+		UTMTemplate    *string         // имя UTM-шаблона, пустая строка - отвязать шаблон
+		RedirectRules  *[]RedirectRule // новый список правил целиком, пустой список - удалить правила
+		Variants       *[]LinkVariant  // новый список вариантов целиком, пустой список - удалить варианты
+		StickyVariants *bool
+		ActiveFrom     *time.Time // нулевое время - снять ограничение
+		ActiveUntil    *time.Time // нулевое время - снять ограничение
+	}
+
+	// QRCodeOptions параметры отрисовки QR-кода для короткой ссылки
+	QRCodeOptions struct {
+		Format string // png | svg
+		Size   int    // сторона изображения в пикселях
+		Margin int    // ширина "тихой зоны" в модулях, < 0 - по умолчанию
+		ECC    string // уровень коррекции ошибок: L | M | Q | H
+	}
+)
+
+// LinkStatus состояние ссылки для режима предпросмотра
+type LinkStatus string
+
+const (
+	LinkStatusActive  LinkStatus = "active"
+	LinkStatusDeleted LinkStatus = "deleted"
+	LinkStatusExpired LinkStatus = "expired"
+	// LinkStatusScheduled ссылка создана, но окно активности еще не началось
+	LinkStatusScheduled LinkStatus = "scheduled"
+	// LinkStatusQuarantined ссылка набрала жалобы и ждет проверки, вместо перехода показывается предупреждение
+	LinkStatusQuarantined LinkStatus = "quarantined"
+	// LinkStatusBanned ссылка заблокирована администратором
+	LinkStatusBanned LinkStatus = "banned"
+)
+
+// Состояние модерации ссылки
+const (
+	ModerationNone        = ""
+	ModerationQuarantined = "quarantined"
+	ModerationBanned      = "banned"
+)
+
+// Причины жалоб на ссылку
+const (
+	AbuseReasonPhishing = "phishing"
+	AbuseReasonMalware  = "malware"
+	AbuseReasonSpam     = "spam"
+	AbuseReasonIllegal  = "illegal"
+	AbuseReasonOther    = "other"
+)
+
+// Типы правил перенаправления
+const (
+	RedirectRulePlatform = "platform" // платформа из User-Agent
+	RedirectRuleLanguage = "language" // предпочитаемый язык из Accept-Language
+	RedirectRuleHeader   = "header"   // произвольный заголовок
+	RedirectRuleCountry  = "country"  // страна клиента по GeoIP
+)
+
+// Платформы, различаемые по User-Agent
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformMobile  = "mobile"
+	PlatformDesktop = "desktop"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+)
+
+// Режимы применения UTM-шаблона к ссылке
+const (
+	UTMApplyOnCreate   = "create"   // параметры дописываются в OriginalURL при создании
+	UTMApplyOnRedirect = "redirect" // шаблон привязывается к ссылке и применяется при перенаправлении
+)
+
+const (
+	QRCodeFormatPNG = "png"
+	QRCodeFormatSVG = "svg"
 )
 
 var (
-	ErrInvalidData = errors.New("invalid input data")
-	ErrUnfound     = errors.New("unfound data")
-	ErrEmpty       = errors.New("storage is empty")
-	ErrConflict    = errors.New("duplicate URL")
-	ErrGone        = errors.New("url deleted")
+	ErrInvalidData  = errors.New("invalid input data")
+	ErrUnfound      = errors.New("unfound data")
+	ErrEmpty        = errors.New("storage is empty")
+	ErrConflict     = errors.New("duplicate URL")
+	ErrGone         = errors.New("url deleted")
+	ErrNotYetActive = errors.New("url not yet active")
+	ErrExpired      = errors.New("url expired")
+	ErrBlocked      = errors.New("destination blocked by policy")
+	ErrQuarantined  = errors.New("url quarantined")
+	ErrBanned       = errors.New("url banned")
+	ErrQuota        = errors.New("quota exceeded")
+
+	ErrIdempotencyMismatch   = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is still in progress")
+)
+
+// Причины отказа политики адресов назначения
+const (
+	PolicyReasonBlocklisted    = "blocklisted"     // хост в блок-листе
+	PolicyReasonNotAllowlisted = "not_allowlisted" // режим allowlist-only, хоста нет в списке
+	PolicyReasonPrivateAddress = "private_address" // loopback, частный или служебный адрес
+	PolicyReasonSelfReference  = "self_reference"  // ссылка на сам сервис, петля перенаправлений
 )
+
+// PolicyViolation отказ политики адресов назначения с причиной для клиента
+type PolicyViolation struct {
+	Reason string // одна из PolicyReason*
+	Host   string
+}
+
+func (e *PolicyViolation) Error() string {
+	return fmt.Sprintf("%s: %s %s", ErrBlocked, e.Reason, e.Host)
+}
+
+func (e *PolicyViolation) Unwrap() error {
+	return ErrBlocked
+}
+
+// Квоты пользователя
+const (
+	QuotaActiveLinks = "active_links" // ссылки, которые не удалены
+	QuotaBatchSize   = "batch_size"   // ссылки в одном пакетном запросе
+)
+
+// QuotaUsage использование квот пользователем. Лимит 0 - без ограничения
+type QuotaUsage struct {
+	ActiveLinks    int
+	MaxActiveLinks int
+	MaxBatchSize   int
+}
+
+// QuotaExceeded отказ из-за превышения квоты с текущим использованием для клиента
+type QuotaExceeded struct {
+	Quota     string // одна из Quota*
+	Limit     int
+	Used      int // уже использовано, для batch_size - 0
+	Requested int
+}
+
+func (e *QuotaExceeded) Error() string {
+	if e.Quota == QuotaBatchSize {
+		return fmt.Sprintf("%s: %s limit %d, requested %d", ErrQuota, e.Quota, e.Limit, e.Requested)
+	}
+	return fmt.Sprintf("%s: %s limit %d, used %d, requested %d", ErrQuota, e.Quota, e.Limit, e.Used, e.Requested)
+}
+
+func (e *QuotaExceeded) Unwrap() error {
+	return ErrQuota
+}
+
+// IdempotencyRecord запрос с ключом идемпотентности и сохраненный ответ на него.
+// Пока StatusCode 0, запрос еще выполняется
+type IdempotencyRecord struct {
+	UserID      int64
+	Key         string
+	Fingerprint string // хэш метода, пути и тела запроса
+	Owner       string // токен запроса, занявшего ключ: только он сохраняет ответ или освобождает ключ
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Completed сообщает, сохранен ли уже ответ
+func (r IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
+
+// CacheStats счетчики кэша ссылок с момента запуска
+type CacheStats struct {
+	Capacity      int
+	Entries       int
+	Hits          uint64 // ответы из кэша, включая запомненное отсутствие кода
+	NegativeHits  uint64 // ответы "кода нет" из кэша
+	Misses        uint64
+	Loads         uint64 // обращения к хранилищу, одновременные промахи по одному коду дают одно
+	Evictions     uint64 // вытеснены из-за размера
+	Invalidations uint64 // сброшены из-за изменения ссылки
+	Flushes       uint64 // полные сбросы после потери уведомлений об изменениях
+}
+
+// HitRatio доля запросов, обслуженных кэшем
+func (s CacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
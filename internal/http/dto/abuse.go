@@ -0,0 +1,107 @@
+package dto
+
+import (
+	"time"
+	"urlshortener/internal/domain/models"
+)
+
+type (
+	// Для POST /api/report/{code}
+	AbuseReportRequest struct {
+		Reason  string `json:"reason"` // phishing | malware | spam | illegal | other
+		Details string `json:"details,omitempty"`
+	}
+
+	AbuseReportResponse struct {
+		ID     int64  `json:"id"`
+		Status string `json:"status"`
+	}
+
+	AbuseReportItem struct {
+		ID        int64     `json:"id"`
+		Reason    string    `json:"reason"`
+		Details   string    `json:"details,omitempty"`
+		Reporter  string    `json:"reporter"`
+		Resolved  bool      `json:"resolved"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	// Для GET /api/admin/reports
+	AbuseQueueItem struct {
+		ShortURL     string    `json:"short_url"`
+		OriginalURL  string    `json:"original_url"`
+		Moderation   string    `json:"moderation"` // none | quarantined | banned
+		Deleted      bool      `json:"deleted,omitempty"`
+		Reports      int       `json:"reports"`
+		Reporters    int       `json:"reporters"`
+		LastReportAt time.Time `json:"last_report_at"`
+	}
+
+	// Для GET /api/admin/reports/{code}
+	LinkReportsResponse struct {
+		ShortURL    string            `json:"short_url"`
+		OriginalURL string            `json:"original_url"`
+		Moderation  string            `json:"moderation"`
+		Deleted     bool              `json:"deleted,omitempty"`
+		Reports     []AbuseReportItem `json:"reports"`
+	}
+
+	// Для POST /api/admin/links/{code}/clear и /ban
+	ModerationResponse struct {
+		ShortURL   string `json:"short_url"`
+		Moderation string `json:"moderation"`
+	}
+)
+
+func AbuseReportDomainFromRequest(r AbuseReportRequest, reporter string) models.AbuseReport {
+	return models.AbuseReport{
+		Reason:   r.Reason,
+		Details:  r.Details,
+		Reporter: reporter,
+	}
+}
+
+func AbuseQueueFromDomain(summaries []models.AbuseReportSummary, shortURL func(code string) string) []AbuseQueueItem {
+	items := make([]AbuseQueueItem, len(summaries))
+	for i, summary := range summaries {
+		items[i] = AbuseQueueItem{
+			ShortURL:     shortURL(summary.Link.ShortCode),
+			OriginalURL:  summary.Link.OriginalURL,
+			Moderation:   ModerationName(summary.Link.Moderation),
+			Deleted:      summary.Link.DeletedFlag,
+			Reports:      summary.Reports,
+			Reporters:    summary.Reporters,
+			LastReportAt: summary.LastReportAt,
+		}
+	}
+	return items
+}
+
+func LinkReportsResponseFromDomain(link models.ShortenedLink, reports []models.AbuseReport, shortURL string) LinkReportsResponse {
+	items := make([]AbuseReportItem, len(reports))
+	for i, report := range reports {
+		items[i] = AbuseReportItem{
+			ID:        report.ID,
+			Reason:    report.Reason,
+			Details:   report.Details,
+			Reporter:  report.Reporter,
+			Resolved:  report.Resolved,
+			CreatedAt: report.CreatedAt,
+		}
+	}
+	return LinkReportsResponse{
+		ShortURL:    shortURL,
+		OriginalURL: link.OriginalURL,
+		Moderation:  ModerationName(link.Moderation),
+		Deleted:     link.DeletedFlag,
+		Reports:     items,
+	}
+}
+
+// ModerationName возвращает состояние модерации для ответа, отсутствие модерации - "none"
+func ModerationName(moderation string) string {
+	if moderation == models.ModerationNone {
+		return "none"
+	}
+	return moderation
+}
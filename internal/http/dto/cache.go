@@ -0,0 +1,37 @@
+package dto
+
+import "urlshortener/internal/domain/models"
+
+// Для GET /api/admin/cache
+type CacheStatsResponse struct {
+	Capacity      int     `json:"capacity"`
+	Entries       int     `json:"entries"`
+	Hits          uint64  `json:"hits"`
+	NegativeHits  uint64  `json:"negative_hits"`
+	Misses        uint64  `json:"misses"`
+	Loads         uint64  `json:"loads"`
+	Evictions     uint64  `json:"evictions"`
+	Invalidations uint64  `json:"invalidations"`
+	Flushes       uint64  `json:"flushes"`
+	HitRatio      float64 `json:"hit_ratio"`
+}
+
+func CacheStatsResponseFromDomain(stats models.CacheStats) CacheStatsResponse {
+	return CacheStatsResponse{
+		Capacity:      stats.Capacity,
+		Entries:       stats.Entries,
+		Hits:          stats.Hits,
+		NegativeHits:  stats.NegativeHits,
+		Misses:        stats.Misses,
+		Loads:         stats.Loads,
+		Evictions:     stats.Evictions,
+		Invalidations: stats.Invalidations,
+		Flushes:       stats.Flushes,
+		HitRatio:      stats.HitRatio(),
+	}
+}
+
+// Для GET /api/admin/storage
+type StorageStatsResponse struct {
+	TxRetries uint64 `json:"tx_retries"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+import "urlshortener/internal/domain/models"
+
+// PolicyErrorResponse отказ политики адресов назначения с машиночитаемой причиной
+type PolicyErrorResponse struct {
+	Error  string `json:"error"`
+	Reason string `json:"reason"` // blocklisted | not_allowlisted | private_address | self_reference
+	Host   string `json:"host"`
+}
+
+func PolicyErrorResponseFromDomain(v *models.PolicyViolation) PolicyErrorResponse {
+	return PolicyErrorResponse{
+		Error:  v.Error(),
+		Reason: v.Reason,
+		Host:   v.Host,
+	}
+}
@@ -0,0 +1,59 @@
+package dto
+
+import "urlshortener/internal/domain/models"
+
+type (
+	// QuotaErrorResponse отказ из-за квоты с текущим использованием
+	QuotaErrorResponse struct {
+		Error     string `json:"error"`
+		Quota     string `json:"quota"` // active_links | batch_size
+		Limit     int    `json:"limit"`
+		Used      int    `json:"used"`
+		Requested int    `json:"requested"`
+		Remaining int    `json:"remaining"`
+	}
+
+	// Для GET /api/user/quota, null - без ограничения
+	QuotaUsageResponse struct {
+		ActiveLinks  QuotaCounter `json:"active_links"`
+		MaxBatchSize *int         `json:"max_batch_size"`
+	}
+
+	QuotaCounter struct {
+		Used      int  `json:"used"`
+		Limit     *int `json:"limit"`
+		Remaining *int `json:"remaining"`
+	}
+)
+
+func QuotaErrorResponseFromDomain(e *models.QuotaExceeded) QuotaErrorResponse {
+	return QuotaErrorResponse{
+		Error:     e.Error(),
+		Quota:     e.Quota,
+		Limit:     e.Limit,
+		Used:      e.Used,
+		Requested: e.Requested,
+		Remaining: max(e.Limit-e.Used, 0),
+	}
+}
+
+func QuotaUsageResponseFromDomain(usage models.QuotaUsage) QuotaUsageResponse {
+	resp := QuotaUsageResponse{
+		ActiveLinks:  QuotaCounter{Used: usage.ActiveLinks},
+		MaxBatchSize: limitPtr(usage.MaxBatchSize),
+	}
+	if usage.MaxActiveLinks > 0 {
+		remaining := max(usage.MaxActiveLinks-usage.ActiveLinks, 0)
+		resp.ActiveLinks.Limit = limitPtr(usage.MaxActiveLinks)
+		resp.ActiveLinks.Remaining = &remaining
+	}
+	return resp
+}
+
+// limitPtr лимит 0 означает отсутствие ограничения и отдается как null
+func limitPtr(limit int) *int {
+	if limit <= 0 {
+		return nil
+	}
+	return &limit
+}
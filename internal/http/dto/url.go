@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"fmt"
 	"time"
 	"urlshortener/internal/domain/models"
 )
@@ -14,12 +15,66 @@ type (
 	}
 
 	ShortenedLinkSingleRequest struct {
-		URL string `json:"url"`
+		URL            string `json:"url"`
+		Title          string `json:"title,omitempty"`
+		RedirectStatus int    `json:"redirect_status,omitempty"`
+		PassQuery      bool   `json:"pass_query,omitempty"`
+		PassPath       bool   `json:"pass_path,omitempty"`
+		UTMTemplate    string `json:"utm_template,omitempty"`
+		UTMMode        string `json:"utm_mode,omitempty"` // create | redirect
+
+		RedirectRules  []RedirectRule `json:"rules,omitempty"`
+		Variants       []LinkVariant  `json:"variants,omitempty"`
+		StickyVariants bool           `json:"sticky_variants,omitempty"`
+
+		ActiveFrom  *time.Time `json:"active_from,omitempty"`  // RFC 3339
+		ActiveUntil *time.Time `json:"active_until,omitempty"` // RFC 3339
 	}
 
 	ShortenedLinkBatchRequest struct {
-		CorrelationID string `json:"correlation_id"`
-		OriginalURL   string `json:"original_url"`
+		CorrelationID  string `json:"correlation_id"`
+		OriginalURL    string `json:"original_url"`
+		RedirectStatus int    `json:"redirect_status,omitempty"`
+		PassQuery      bool   `json:"pass_query,omitempty"`
+		PassPath       bool   `json:"pass_path,omitempty"`
+		UTMTemplate    string `json:"utm_template,omitempty"`
+		UTMMode        string `json:"utm_mode,omitempty"` // create | redirect
+
+		RedirectRules  []RedirectRule `json:"rules,omitempty"`
+		Variants       []LinkVariant  `json:"variants,omitempty"`
+		StickyVariants bool           `json:"sticky_variants,omitempty"`
+
+		ActiveFrom  *time.Time `json:"active_from,omitempty"`
+		ActiveUntil *time.Time `json:"active_until,omitempty"`
+	}
+
+	// Для PATCH /api/user/urls/{code}, отсутствующие поля не меняются
+	ShortenedLinkUpdateRequest struct {
+		Title          *string         `json:"title,omitempty"`
+		RedirectStatus *int            `json:"redirect_status,omitempty"`
+		PassQuery      *bool           `json:"pass_query,omitempty"`
+		PassPath       *bool           `json:"pass_path,omitempty"`
+		UTMTemplate    *string         `json:"utm_template,omitempty"` // "" - отвязать шаблон
+		RedirectRules  *[]RedirectRule `json:"rules,omitempty"`        // [] - удалить правила
+		Variants       *[]LinkVariant  `json:"variants,omitempty"`     // [] - удалить варианты
+		StickyVariants *bool           `json:"sticky_variants,omitempty"`
+		ActiveFrom     *string         `json:"active_from,omitempty"`  // RFC 3339, "" - снять ограничение
+		ActiveUntil    *string         `json:"active_until,omitempty"` // RFC 3339, "" - снять ограничение
+	}
+
+	// RedirectRule правило выбора назначения, проверяются по порядку
+	RedirectRule struct {
+		Type      string `json:"type"`             // platform | language | header
+		Header    string `json:"header,omitempty"` // для type=header
+		Value     string `json:"value,omitempty"`
+		TargetURL string `json:"target_url"`
+	}
+
+	// LinkVariant A/B-вариант назначения
+	LinkVariant struct {
+		Name      string `json:"name,omitempty"`
+		TargetURL string `json:"target_url"`
+		Weight    int    `json:"weight"`
 	}
 )
 
@@ -55,6 +110,50 @@ type (
 	ShortenedLinkErrorResponse struct {
 		Error string `json:"error"`
 	}
+
+	// Для PATCH /api/user/urls/{code}
+	ShortenedLinkDetailsResponse struct {
+		ShortURL       string         `json:"short_url"`
+		OriginalURL    string         `json:"original_url"`
+		Title          string         `json:"title,omitempty"`
+		RedirectStatus int            `json:"redirect_status,omitempty"`
+		PassQuery      bool           `json:"pass_query"`
+		PassPath       bool           `json:"pass_path"`
+		UTMTemplateID  int64          `json:"utm_template_id,omitempty"`
+		RedirectRules  []RedirectRule `json:"rules,omitempty"`
+		Variants       []LinkVariant  `json:"variants,omitempty"`
+		StickyVariants bool           `json:"sticky_variants,omitempty"`
+		ActiveFrom     *time.Time     `json:"active_from,omitempty"`
+		ActiveUntil    *time.Time     `json:"active_until,omitempty"`
+		Moderation     string         `json:"moderation,omitempty"` // quarantined | banned
+		CreatedAt      time.Time      `json:"created_at"`
+	}
+
+	// Для GET /api/user/urls/{code}/stats
+	ShortenedLinkStatsResponse struct {
+		ShortURL    string             `json:"short_url"`
+		OriginalURL string             `json:"original_url"`
+		Clicks      int64              `json:"clicks"` // сумма переходов по вариантам
+		Variants    []LinkVariantStats `json:"variants"`
+	}
+
+	LinkVariantStats struct {
+		Name      string `json:"name"`
+		TargetURL string `json:"target_url"`
+		Weight    int    `json:"weight"`
+		Clicks    int64  `json:"clicks"`
+	}
+
+	// Для GET /{id}/info и GET /{id}+
+	ShortenedLinkPreviewResponse struct {
+		ShortURL    string     `json:"short_url"`
+		OriginalURL string     `json:"original_url"`
+		Title       string     `json:"title,omitempty"`
+		Status      string     `json:"status"`
+		ActiveFrom  *time.Time `json:"active_from,omitempty"`
+		ActiveUntil *time.Time `json:"active_until,omitempty"`
+		CreatedAt   time.Time  `json:"created_at"`
+	}
 )
 
 // ShortenedLinkTextRequestToDomain преобразует текстовый запрос в доменную модель
@@ -78,6 +177,16 @@ func ShortenedLinkSingleRequestToDomain(r ShortenedLinkSingleRequest, userID int
 		OriginalURL: r.URL,
 		UserID:      userID,
 		CreatedAt:   time.Now().UTC(),
+		Title:       r.Title,
+
+		RedirectStatus: r.RedirectStatus,
+		PassQuery:      r.PassQuery,
+		PassPath:       r.PassPath,
+		RedirectRules:  RedirectRulesToDomain(r.RedirectRules),
+		Variants:       LinkVariantsToDomain(r.Variants),
+		StickyVariants: r.StickyVariants,
+		ActiveFrom:     timeValue(r.ActiveFrom),
+		ActiveUntil:    timeValue(r.ActiveUntil),
 	}
 }
 
@@ -95,6 +204,15 @@ func ShortenedLinkBatchRequestToDomain(reqs []ShortenedLinkBatchRequest, userID
 			OriginalURL: r.OriginalURL,
 			UserID:      userID,
 			CreatedAt:   time.Now().UTC(),
+
+			RedirectStatus: r.RedirectStatus,
+			PassQuery:      r.PassQuery,
+			PassPath:       r.PassPath,
+			RedirectRules:  RedirectRulesToDomain(r.RedirectRules),
+			Variants:       LinkVariantsToDomain(r.Variants),
+			StickyVariants: r.StickyVariants,
+			ActiveFrom:     timeValue(r.ActiveFrom),
+			ActiveUntil:    timeValue(r.ActiveUntil),
 		}
 	}
 	return urls
@@ -123,3 +241,196 @@ func ShortenedLinkBatchGetResponseFromDomains(urls []models.ShortenedLink, baseU
 	}
 	return responses
 }
+
+// Для GET /{id}/info и GET /{id}+
+func ShortenedLinkPreviewResponseFromDomain(model models.ShortenedLink, status models.LinkStatus, shortURL string) ShortenedLinkPreviewResponse {
+	return ShortenedLinkPreviewResponse{
+		ShortURL:    shortURL,
+		OriginalURL: model.OriginalURL,
+		Title:       model.Title,
+		Status:      string(status),
+		ActiveFrom:  timePtr(model.ActiveFrom),
+		ActiveUntil: timePtr(model.ActiveUntil),
+		CreatedAt:   model.CreatedAt,
+	}
+}
+
+// Для PATCH /api/user/urls/{code}
+func ShortenedLinkUpdateRequestToDomain(r ShortenedLinkUpdateRequest) (models.ShortenedLinkUpdate, error) {
+	activeFrom, err := parseTimePtr("active_from", r.ActiveFrom)
+	if err != nil {
+		return models.ShortenedLinkUpdate{}, err
+	}
+	activeUntil, err := parseTimePtr("active_until", r.ActiveUntil)
+	if err != nil {
+		return models.ShortenedLinkUpdate{}, err
+	}
+
+	return models.ShortenedLinkUpdate{
+		Title:          r.Title,
+		RedirectStatus: r.RedirectStatus,
+		PassQuery:      r.PassQuery,
+		PassPath:       r.PassPath,
+		UTMTemplate:    r.UTMTemplate,
+		RedirectRules:  redirectRulesPtrToDomain(r.RedirectRules),
+		Variants:       linkVariantsPtrToDomain(r.Variants),
+		StickyVariants: r.StickyVariants,
+		ActiveFrom:     activeFrom,
+		ActiveUntil:    activeUntil,
+	}, nil
+}
+
+// Для PATCH /api/user/urls/{code}
+func ShortenedLinkDetailsResponseFromDomain(model models.ShortenedLink, shortURL string) ShortenedLinkDetailsResponse {
+	return ShortenedLinkDetailsResponse{
+		ShortURL:       shortURL,
+		OriginalURL:    model.OriginalURL,
+		Title:          model.Title,
+		RedirectStatus: model.RedirectStatus,
+		PassQuery:      model.PassQuery,
+		PassPath:       model.PassPath,
+		UTMTemplateID:  model.UTMTemplateID,
+		RedirectRules:  RedirectRulesFromDomain(model.RedirectRules),
+		Variants:       LinkVariantsFromDomain(model.Variants),
+		StickyVariants: model.StickyVariants,
+		ActiveFrom:     timePtr(model.ActiveFrom),
+		ActiveUntil:    timePtr(model.ActiveUntil),
+		Moderation:     model.Moderation,
+		CreatedAt:      model.CreatedAt,
+	}
+}
+
+// Для GET /api/user/urls/{code}/stats
+func ShortenedLinkStatsResponseFromDomain(model models.ShortenedLink, shortURL string) ShortenedLinkStatsResponse {
+	resp := ShortenedLinkStatsResponse{
+		ShortURL:    shortURL,
+		OriginalURL: model.OriginalURL,
+		Variants:    make([]LinkVariantStats, len(model.Variants)),
+	}
+	for i, variant := range model.Variants {
+		resp.Variants[i] = LinkVariantStats{
+			Name:      variant.Name,
+			TargetURL: variant.TargetURL,
+			Weight:    variant.Weight,
+			Clicks:    variant.Clicks,
+		}
+		resp.Clicks += variant.Clicks
+	}
+	return resp
+}
+
+func LinkVariantsToDomain(variants []LinkVariant) []models.LinkVariant {
+	if len(variants) == 0 {
+		return nil
+	}
+	result := make([]models.LinkVariant, len(variants))
+	for i, variant := range variants {
+		result[i] = models.LinkVariant{
+			Name:      variant.Name,
+			TargetURL: variant.TargetURL,
+			Weight:    variant.Weight,
+		}
+	}
+	return result
+}
+
+func LinkVariantsFromDomain(variants []models.LinkVariant) []LinkVariant {
+	if len(variants) == 0 {
+		return nil
+	}
+	result := make([]LinkVariant, len(variants))
+	for i, variant := range variants {
+		result[i] = LinkVariant{
+			Name:      variant.Name,
+			TargetURL: variant.TargetURL,
+			Weight:    variant.Weight,
+		}
+	}
+	return result
+}
+
+// linkVariantsPtrToDomain сохраняет разницу между "поле не передано" и "пустой список"
+func linkVariantsPtrToDomain(variants *[]LinkVariant) *[]models.LinkVariant {
+	if variants == nil {
+		return nil
+	}
+	result := LinkVariantsToDomain(*variants)
+	if result == nil {
+		result = []models.LinkVariant{}
+	}
+	return &result
+}
+
+func RedirectRulesToDomain(rules []RedirectRule) []models.RedirectRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	result := make([]models.RedirectRule, len(rules))
+	for i, rule := range rules {
+		result[i] = models.RedirectRule{
+			Type:      rule.Type,
+			Header:    rule.Header,
+			Value:     rule.Value,
+			TargetURL: rule.TargetURL,
+		}
+	}
+	return result
+}
+
+func RedirectRulesFromDomain(rules []models.RedirectRule) []RedirectRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	result := make([]RedirectRule, len(rules))
+	for i, rule := range rules {
+		result[i] = RedirectRule{
+			Type:      rule.Type,
+			Header:    rule.Header,
+			Value:     rule.Value,
+			TargetURL: rule.TargetURL,
+		}
+	}
+	return result
+}
+
+// redirectRulesPtrToDomain сохраняет разницу между "поле не передано" и "пустой список"
+func redirectRulesPtrToDomain(rules *[]RedirectRule) *[]models.RedirectRule {
+	if rules == nil {
+		return nil
+	}
+	result := RedirectRulesToDomain(*rules)
+	if result == nil {
+		result = []models.RedirectRule{}
+	}
+	return &result
+}
+
+func timeValue(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// timePtr скрывает в ответе нулевое время
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// parseTimePtr разбирает время в RFC 3339, пустая строка превращается в нулевое время
+func parseTimePtr(field string, value *string) (*time.Time, error) {
+	if value == nil {
+		return nil, nil
+	}
+	var t time.Time
+	if *value != "" {
+		var err error
+		if t, err = time.Parse(time.RFC3339, *value); err != nil {
+			return nil, fmt.Errorf("%w: %s must be RFC 3339 time", models.ErrInvalidData, field)
+		}
+	}
+	return &t, nil
+}
@@ -0,0 +1,61 @@
+package dto
+
+import (
+	"time"
+	"urlshortener/internal/domain/models"
+)
+
+// Для POST /api/user/utm
+type UTMTemplateRequest struct {
+	Name     string `json:"name"`
+	Source   string `json:"utm_source,omitempty"`
+	Medium   string `json:"utm_medium,omitempty"`
+	Campaign string `json:"utm_campaign,omitempty"`
+	Term     string `json:"utm_term,omitempty"`
+	Content  string `json:"utm_content,omitempty"`
+}
+
+// Для POST и GET /api/user/utm
+type UTMTemplateResponse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Source    string    `json:"utm_source,omitempty"`
+	Medium    string    `json:"utm_medium,omitempty"`
+	Campaign  string    `json:"utm_campaign,omitempty"`
+	Term      string    `json:"utm_term,omitempty"`
+	Content   string    `json:"utm_content,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func UTMTemplateRequestToDomain(r UTMTemplateRequest, userID int64) models.UTMTemplate {
+	return models.UTMTemplate{
+		UserID:   userID,
+		Name:     r.Name,
+		Source:   r.Source,
+		Medium:   r.Medium,
+		Campaign: r.Campaign,
+		Term:     r.Term,
+		Content:  r.Content,
+	}
+}
+
+func UTMTemplateResponseFromDomain(tpl models.UTMTemplate) UTMTemplateResponse {
+	return UTMTemplateResponse{
+		ID:        tpl.ID,
+		Name:      tpl.Name,
+		Source:    tpl.Source,
+		Medium:    tpl.Medium,
+		Campaign:  tpl.Campaign,
+		Term:      tpl.Term,
+		Content:   tpl.Content,
+		CreatedAt: tpl.CreatedAt,
+	}
+}
+
+func UTMTemplateResponseFromDomains(templates []models.UTMTemplate) []UTMTemplateResponse {
+	responses := make([]UTMTemplateResponse, len(templates))
+	for i, tpl := range templates {
+		responses[i] = UTMTemplateResponseFromDomain(tpl)
+	}
+	return responses
+}
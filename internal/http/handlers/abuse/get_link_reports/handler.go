@@ -0,0 +1,35 @@
+package get_link_reports
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/dto"
+	"urlshortener/internal/http/httputils"
+
+	"github.com/gorilla/mux"
+)
+
+type ServiceURLShortener interface {
+	GetLinkReports(ctx context.Context, shortKey string) (models.ShortenedLink, []models.AbuseReport, error)
+	GetShortURL(shortKey string) string
+}
+
+// HandlerGetLinkReports возвращает все жалобы на ссылку, включая рассмотренные
+func HandlerGetLinkReports(svc ServiceURLShortener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link, reports, err := svc.GetLinkReports(r.Context(), mux.Vars(r)["code"])
+		if err != nil {
+			if errors.Is(err, models.ErrUnfound) || errors.Is(err, models.ErrInvalidData) {
+				httputils.WriteJSONError(w, http.StatusNotFound, "URL not found")
+				return
+			}
+			httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		resp := dto.LinkReportsResponseFromDomain(link, reports, svc.GetShortURL(link.ShortCode))
+		httputils.WriteJSONResponse(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,27 @@
+package list_reports
+
+import (
+	"context"
+	"net/http"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/dto"
+	"urlshortener/internal/http/httputils"
+)
+
+type ServiceURLShortener interface {
+	GetPendingReports(ctx context.Context) ([]models.AbuseReportSummary, error)
+	GetShortURL(shortKey string) string
+}
+
+// HandlerListReports возвращает очередь модерации: сначала ссылки в карантине
+func HandlerListReports(svc ServiceURLShortener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		summaries, err := svc.GetPendingReports(r.Context())
+		if err != nil {
+			httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		httputils.WriteJSONResponse(w, http.StatusOK, dto.AbuseQueueFromDomain(summaries, svc.GetShortURL))
+	}
+}
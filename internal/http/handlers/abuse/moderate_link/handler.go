@@ -0,0 +1,47 @@
+package moderate_link
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/dto"
+	"urlshortener/internal/http/httputils"
+
+	"github.com/gorilla/mux"
+)
+
+type ServiceURLShortener interface {
+	ClearLink(ctx context.Context, shortKey string) (models.ShortenedLink, error)
+	BanLink(ctx context.Context, shortKey string) (models.ShortenedLink, error)
+	GetShortURL(shortKey string) string
+}
+
+// HandlerClearLink снимает карантин или блокировку, жалобы на ссылку закрываются
+func HandlerClearLink(svc ServiceURLShortener) http.HandlerFunc {
+	return handle(svc, svc.ClearLink)
+}
+
+// HandlerBanLink блокирует ссылку, жалобы на нее закрываются
+func HandlerBanLink(svc ServiceURLShortener) http.HandlerFunc {
+	return handle(svc, svc.BanLink)
+}
+
+func handle(svc ServiceURLShortener, action func(ctx context.Context, shortKey string) (models.ShortenedLink, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link, err := action(r.Context(), mux.Vars(r)["code"])
+		if err != nil {
+			if errors.Is(err, models.ErrUnfound) || errors.Is(err, models.ErrInvalidData) {
+				httputils.WriteJSONError(w, http.StatusNotFound, "URL not found")
+				return
+			}
+			httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		httputils.WriteJSONResponse(w, http.StatusOK, dto.ModerationResponse{
+			ShortURL:   svc.GetShortURL(link.ShortCode),
+			Moderation: dto.ModerationName(link.Moderation),
+		})
+	}
+}
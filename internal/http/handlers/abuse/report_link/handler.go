@@ -0,0 +1,62 @@
+package report_link
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/netip"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/dto"
+	"urlshortener/internal/http/httputils"
+
+	"github.com/gorilla/mux"
+)
+
+// maxReportBody ограничение размера тела жалобы
+const maxReportBody = 8 << 10
+
+type ServiceURLShortener interface {
+	ReportLink(ctx context.Context, shortKey string, report models.AbuseReport) (models.AbuseReport, error)
+}
+
+// ClientResolver определяет адрес клиента, жалобы с одного адреса считаются одним отправителем
+type ClientResolver interface {
+	ClientIP(remoteAddr string, forwardedFor []string) netip.Addr
+}
+
+// HandlerReportLink принимает жалобу на ссылку от любого клиента, авторизация не нужна.
+// Отвечает 202: жалоба сохранена и ждет рассмотрения
+func HandlerReportLink(svc ServiceURLShortener, clients ClientResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var req dto.AbuseReportRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxReportBody)).Decode(&req); err != nil {
+			httputils.WriteJSONError(w, http.StatusBadRequest, "invalid request format")
+			return
+		}
+
+		var reporter string
+		if ip := clients.ClientIP(r.RemoteAddr, r.Header.Values(httputils.HeaderXForwardedFor)); ip.IsValid() {
+			reporter = ip.String()
+		}
+
+		report, err := svc.ReportLink(ctx, mux.Vars(r)["code"], dto.AbuseReportDomainFromRequest(req, reporter))
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrGone):
+				httputils.WriteJSONError(w, http.StatusGone, "URL has been deleted")
+			case errors.Is(err, models.ErrUnfound):
+				httputils.WriteJSONError(w, http.StatusNotFound, "URL not found")
+			case errors.Is(err, models.ErrInvalidData):
+				httputils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+			default:
+				httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		httputils.WriteJSONResponse(w, http.StatusAccepted, dto.AbuseReportResponse{ID: report.ID, Status: "received"})
+	}
+}
@@ -0,0 +1,25 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"urlshortener/internal/http/httputils"
+)
+
+// MiddlewareAdmin пропускает только запросы с заголовком "Authorization: Bearer <token>".
+// Токены сравниваются за постоянное время
+func MiddlewareAdmin(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided, ok := strings.CutPrefix(r.Header.Get(httputils.HeaderAuthorization), "Bearer ")
+			if !ok || token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				w.Header().Set(httputils.HeaderWWWAuthenticate, "Bearer")
+				httputils.WriteJSONError(w, http.StatusUnauthorized, "admin token required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -14,8 +14,17 @@ type Authentication interface {
 	ValidateAndGetUser(ctx context.Context, jwtToken string) (models.User, error)
 }
 
-func MiddlewareAuth(auth Authentication) func(http.Handler) http.Handler {
+// MiddlewareAuth пропускает запросы с валидной кукой, остальным выдает нового пользователя.
+// registerGuard оборачивает регистрацию (например, ограничением частоты), nil - без обертки
+func MiddlewareAuth(auth Authentication, registerGuard func(http.Handler) http.Handler) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
+		var register http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			registerUser(w, r, auth)
+		})
+		if registerGuard != nil {
+			register = registerGuard(register)
+		}
+
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
 
@@ -31,25 +40,30 @@ func MiddlewareAuth(auth Authentication) func(http.Handler) http.Handler {
 			}
 
 			// 2. Если куки нет или она невалидна - создаем нового пользователя
-			var authUser models.User
-			authUser, tokenString, tokenExpiry, registerErr := auth.Register(ctx, authUser)
-			if registerErr != nil {
-				http.Error(w, "Authentication failed", http.StatusInternalServerError)
-				return
-			}
-
-			http.SetCookie(w, &http.Cookie{
-				Name:     "auth_token",
-				Value:    tokenString,
-				Path:     "/",
-				Expires:  tokenExpiry,
-				HttpOnly: true,
-				Secure:   true,
-				SameSite: http.SameSiteLaxMode,
-				// Надо будет пробывать SameSiteStrictMode
-			})
-
-			httputils.WriteTextResponse(w, http.StatusCreated, fmt.Sprintf("Authentication token issued, authUserID: %d", authUser.ID))
+			register.ServeHTTP(w, r)
 		})
 	}
 }
+
+func registerUser(w http.ResponseWriter, r *http.Request, auth Authentication) {
+	ctx := r.Context()
+	var authUser models.User
+	authUser, tokenString, tokenExpiry, registerErr := auth.Register(ctx, authUser)
+	if registerErr != nil {
+		http.Error(w, "Authentication failed", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "auth_token",
+		Value:    tokenString,
+		Path:     "/",
+		Expires:  tokenExpiry,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		// Надо будет пробывать SameSiteStrictMode
+	})
+
+	httputils.WriteTextResponse(w, http.StatusCreated, fmt.Sprintf("Authentication token issued, authUserID: %d", authUser.ID))
+}
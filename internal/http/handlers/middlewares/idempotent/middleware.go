@@ -0,0 +1,120 @@
+package idempotent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/httputils"
+	"urlshortener/internal/services/idempotency"
+
+	"github.com/rs/zerolog"
+)
+
+// maxBodySize тело запроса читается целиком для отпечатка, поэтому его размер ограничен
+const maxBodySize = 4 << 20
+
+type Service interface {
+	Begin(ctx context.Context, userID int64, key, fingerprint string) (*models.IdempotencyRecord, string, error)
+	Complete(ctx context.Context, userID int64, key, owner string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, userID int64, key, owner string) error
+}
+
+// MiddlewareIdempotency выполняет запрос с заголовком Idempotency-Key один раз: повтор с тем же телом
+// получает сохраненный ответ с заголовком Idempotent-Replayed, с другим телом - 422.
+// Ответы 5xx не сохраняются, такой запрос можно повторить. Запросы без заголовка проходят как есть
+func MiddlewareIdempotency(svc Service, log *zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(httputils.HeaderIdempotencyKey)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, ok := r.Context().Value("user_id").(int64)
+			if !ok || userID <= 0 {
+				httputils.WriteJSONError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+			if err != nil {
+				httputils.WriteJSONError(w, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			replay, owner, err := svc.Begin(r.Context(), userID, key, idempotency.Fingerprint(r.Method, r.URL.Path, body))
+			switch {
+			case errors.Is(err, models.ErrInvalidData):
+				httputils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+				return
+			case errors.Is(err, models.ErrIdempotencyMismatch):
+				httputils.WriteJSONError(w, http.StatusUnprocessableEntity, err.Error())
+				return
+			case errors.Is(err, models.ErrIdempotencyInProgress):
+				w.Header().Set(httputils.HeaderRetryAfter, "1")
+				httputils.WriteJSONError(w, http.StatusConflict, err.Error())
+				return
+			case err != nil:
+				// Без хранилища нельзя гарантировать однократное выполнение, поэтому запрос не выполняется
+				log.Error().Err(err).Msg("Idempotency store unavailable")
+				httputils.WriteJSONError(w, http.StatusServiceUnavailable, "idempotency store unavailable")
+				return
+			}
+
+			if replay != nil {
+				if replay.ContentType != "" {
+					w.Header().Set(httputils.HeaderContentType, replay.ContentType)
+				}
+				w.Header().Set(httputils.HeaderIdempotentReply, "true")
+				w.WriteHeader(replay.StatusCode)
+				w.Write(replay.Body)
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w}
+			// Ключ нужно сохранить или освободить, даже если клиент уже отключился
+			ctx := context.WithoutCancel(r.Context())
+			defer func() {
+				if recorder.status == 0 || recorder.status >= http.StatusInternalServerError {
+					if err := svc.Release(ctx, userID, key, owner); err != nil {
+						log.Error().Err(err).Msg("Failed to release idempotency key")
+					}
+					return
+				}
+				contentType := recorder.Header().Get(httputils.HeaderContentType)
+				if err := svc.Complete(ctx, userID, key, owner, recorder.status, contentType, recorder.body.Bytes()); err != nil {
+					log.Error().Err(err).Msg("Failed to save idempotent response")
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+		})
+	}
+}
+
+// responseRecorder передает ответ клиенту и запоминает его для повторов
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+	"urlshortener/internal/http/httputils"
+
+	"github.com/rs/zerolog"
+)
+
+// HeaderAPIKey заголовок с API-ключом интеграции
+const HeaderAPIKey = "X-API-Key"
+
+type Limiter interface {
+	Allow(ctx context.Context, scope, key string) (bool, time.Duration, error)
+}
+
+// ClientResolver определяет адрес клиента с учетом доверенных прокси
+type ClientResolver interface {
+	ClientIP(remoteAddr string, forwardedFor []string) netip.Addr
+}
+
+// KeyFunc возвращает ключ клиента, для которого считается бюджет
+type KeyFunc func(r *http.Request) string
+
+// MiddlewareRateLimit отвечает 429 с Retry-After, когда у клиента закончились токены области scope.
+// Если хранилище ограничителя недоступно, запрос пропускается: ограничение не должно ронять сервис
+func MiddlewareRateLimit(limiter Limiter, scope string, key KeyFunc, log *zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, retryAfter, err := limiter.Allow(r.Context(), scope, key(r))
+			if err != nil {
+				log.Error().Err(err).Str("scope", scope).Msg("Rate limiter unavailable, request allowed")
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !allowed {
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				if seconds < 1 {
+					seconds = 1
+				}
+				w.Header().Set(httputils.HeaderRetryAfter, strconv.Itoa(seconds))
+				httputils.WriteJSONError(w, http.StatusTooManyRequests, "too many requests")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientKey определяет клиента: по известному API-ключу, затем по пользователю, затем по адресу.
+// Неизвестный ключ не учитывается, иначе его смена обходила бы ограничение
+func ClientKey(apiKeys []string, clients ClientResolver) KeyFunc {
+	known := make(map[string]bool, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		known[hashKey(apiKey)] = true
+	}
+
+	return func(r *http.Request) string {
+		if apiKey := r.Header.Get(HeaderAPIKey); apiKey != "" {
+			if hash := hashKey(apiKey); known[hash] {
+				return "key:" + hash
+			}
+		}
+		if userID, ok := r.Context().Value("user_id").(int64); ok && userID > 0 {
+			return "user:" + strconv.FormatInt(userID, 10)
+		}
+		return IPKey(clients)(r)
+	}
+}
+
+// IPKey определяет клиента только по адресу
+func IPKey(clients ClientResolver) KeyFunc {
+	return func(r *http.Request) string {
+		return "ip:" + clients.ClientIP(r.RemoteAddr, r.Header.Values(httputils.HeaderXForwardedFor)).String()
+	}
+}
+
+// hashKey сам ключ в хранилище ограничителя не попадает
+func hashKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:8])
+}
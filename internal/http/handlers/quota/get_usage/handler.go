@@ -0,0 +1,34 @@
+package get_usage
+
+import (
+	"context"
+	"net/http"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/dto"
+	"urlshortener/internal/http/httputils"
+)
+
+type ServiceQuota interface {
+	Usage(ctx context.Context, userID int64) (models.QuotaUsage, error)
+}
+
+// HandlerGetQuotaUsage возвращает использование квот и остаток для текущего пользователя
+func HandlerGetQuotaUsage(svc ServiceQuota) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userID, ok := ctx.Value("user_id").(int64)
+		if !ok || userID == 0 {
+			httputils.WriteJSONError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		usage, err := svc.Usage(ctx, userID)
+		if err != nil {
+			httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		httputils.WriteJSONResponse(w, http.StatusOK, dto.QuotaUsageResponseFromDomain(usage))
+	}
+}
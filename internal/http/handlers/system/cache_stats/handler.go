@@ -0,0 +1,19 @@
+package cache_stats
+
+import (
+	"net/http"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/dto"
+	"urlshortener/internal/http/httputils"
+)
+
+type ServiceCache interface {
+	Stats() models.CacheStats
+}
+
+// HandlerCacheStats возвращает счетчики кэша ссылок и долю попаданий
+func HandlerCacheStats(svc ServiceCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		httputils.WriteJSONResponse(w, http.StatusOK, dto.CacheStatsResponseFromDomain(svc.Stats()))
+	}
+}
@@ -0,0 +1,18 @@
+package storage_stats
+
+import (
+	"net/http"
+	"urlshortener/internal/http/dto"
+	"urlshortener/internal/http/httputils"
+)
+
+type ServiceStorageStats interface {
+	TxRetries() uint64
+}
+
+// HandlerStorageStats возвращает счетчики хранилища с момента запуска
+func HandlerStorageStats(svc ServiceStorageStats) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		httputils.WriteJSONResponse(w, http.StatusOK, dto.StorageStatsResponse{TxRetries: svc.TxRetries()})
+	}
+}
@@ -8,13 +8,16 @@ import (
 	"urlshortener/internal/domain/models"
 	"urlshortener/internal/http/dto"
 	"urlshortener/internal/http/httputils"
+
+	"github.com/rs/zerolog"
 )
 
 type ServiceURLShortener interface {
 	SetURL(ctx context.Context, model models.ShortenedLink) (models.ShortenedLink, error)
+	ApplyUTMTemplate(ctx context.Context, link models.ShortenedLink, name, mode string) (models.ShortenedLink, error)
 }
 
-func HandlerSetURLJson(svc ServiceURLShortener, urlroot string) http.HandlerFunc {
+func HandlerSetURLJson(log *zerolog.Logger, svc ServiceURLShortener, urlroot string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
@@ -35,15 +38,34 @@ func HandlerSetURLJson(svc ServiceURLShortener, urlroot string) http.HandlerFunc
 			return
 		}
 
-		if req.URL == "" {
-			httputils.WriteJSONError(w, http.StatusBadRequest, httputils.ErrInvalidData.Error())
+		model, err := svc.ApplyUTMTemplate(ctx, dto.ShortenedLinkSingleRequestToDomain(req, userID), req.UTMTemplate, req.UTMMode)
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrInvalidData):
+				httputils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+			case errors.Is(err, models.ErrUnfound):
+				httputils.WriteJSONError(w, http.StatusNotFound, err.Error())
+			default:
+				// Ошибка хранилища шаблонов - не вина клиента
+				log.Error().Err(err).Msg("failed to apply UTM template")
+				httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			}
 			return
 		}
 
-		model := dto.ShortenedLinkSingleRequestToDomain(req, userID)
 		urlModel, err := svc.SetURL(ctx, model)
 
 		if err != nil {
+			var violation *models.PolicyViolation
+			if errors.As(err, &violation) {
+				httputils.WriteJSONResponse(w, http.StatusBadRequest, dto.PolicyErrorResponseFromDomain(violation))
+				return
+			}
+			var quotaErr *models.QuotaExceeded
+			if errors.As(err, &quotaErr) {
+				httputils.WriteJSONResponse(w, http.StatusForbidden, dto.QuotaErrorResponseFromDomain(quotaErr))
+				return
+			}
 			if errors.Is(err, httputils.ErrConflict) {
 				resp := dto.ShortenedLinkSingleResponseFromDomain(urlModel, urlroot)
 				httputils.WriteJSONResponse(w, http.StatusConflict, resp)
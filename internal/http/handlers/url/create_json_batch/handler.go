@@ -8,13 +8,18 @@ import (
 	"urlshortener/internal/domain/models"
 	"urlshortener/internal/http/dto"
 	"urlshortener/internal/http/httputils"
+
+	"github.com/rs/zerolog"
 )
 
 type ServiceURLShortener interface {
 	BatchCreate(ctx context.Context, urls []models.ShortenedLink) ([]models.ShortenedLink, error)
+	ApplyUTMTemplate(ctx context.Context, link models.ShortenedLink, name, mode string) (models.ShortenedLink, error)
+	NormalizeURL(rawURL string) (string, error)
+	CheckBatchSize(size int) error
 }
 
-func HandlerSetURLJsonBatch(svc ServiceURLShortener, urlroot string) http.HandlerFunc {
+func HandlerSetURLJsonBatch(log *zerolog.Logger, svc ServiceURLShortener, urlroot string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
@@ -31,16 +36,66 @@ func HandlerSetURLJsonBatch(svc ServiceURLShortener, urlroot string) http.Handle
 			return
 		}
 
-		// Создаем map для быстрого поиска correlation_id по URL
-		urlToCorrelation := make(map[string]string, len(requestBatch))
-		for _, req := range requestBatch {
-			urlToCorrelation[req.OriginalURL] = req.CorrelationID
+		// Слишком большой пакет отклоняется до обработки элементов
+		if err := svc.CheckBatchSize(len(requestBatch)); err != nil {
+			var quotaErr *models.QuotaExceeded
+			if errors.As(err, &quotaErr) {
+				httputils.WriteJSONResponse(w, http.StatusForbidden, dto.QuotaErrorResponseFromDomain(quotaErr))
+				return
+			}
+			if errors.Is(err, models.ErrInvalidData) {
+				httputils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			return
 		}
 
 		modelsBatch := dto.ShortenedLinkBatchRequestToDomain(requestBatch, userID)
 
+		// Создаем map для быстрого поиска correlation_id по URL.
+		// UTM-шаблон в режиме create и нормализация меняют URL, поэтому map строим по итоговому адресу
+		urlToCorrelation := make(map[string]string, len(requestBatch))
+		for i, req := range requestBatch {
+			model, err := svc.ApplyUTMTemplate(ctx, modelsBatch[i], req.UTMTemplate, req.UTMMode)
+			if err != nil {
+				switch {
+				case errors.Is(err, models.ErrInvalidData):
+					httputils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+				case errors.Is(err, models.ErrUnfound):
+					httputils.WriteJSONError(w, http.StatusNotFound, err.Error())
+				default:
+					// Ошибка хранилища шаблонов - не вина клиента
+					log.Error().Err(err).Msg("failed to apply UTM template")
+					httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+				}
+				return
+			}
+			if model.OriginalURL, err = svc.NormalizeURL(model.OriginalURL); err != nil {
+				httputils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			modelsBatch[i] = model
+			urlToCorrelation[model.OriginalURL] = req.CorrelationID
+		}
+
 		createdURLs, err := svc.BatchCreate(ctx, modelsBatch)
 		if err != nil {
+			var violation *models.PolicyViolation
+			if errors.As(err, &violation) {
+				httputils.WriteJSONResponse(w, http.StatusBadRequest, dto.PolicyErrorResponseFromDomain(violation))
+				return
+			}
+			var quotaErr *models.QuotaExceeded
+			if errors.As(err, &quotaErr) {
+				httputils.WriteJSONResponse(w, http.StatusForbidden, dto.QuotaErrorResponseFromDomain(quotaErr))
+				return
+			}
+			if errors.Is(err, models.ErrInvalidData) {
+				httputils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+
 			status := http.StatusInternalServerError
 			if errors.Is(err, models.ErrConflict) {
 				status = http.StatusConflict
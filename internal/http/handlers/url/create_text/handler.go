@@ -40,11 +40,6 @@ func HandlerSetURLText(svc ServiceURLShortener, urlroot string) http.HandlerFunc
 			URL: string(body),
 		}
 
-		if req.URL == "" {
-			httputils.WriteTextError(w, http.StatusBadRequest, httputils.ErrInvalidData.Error())
-			return
-		}
-
 		model := dto.ShortenedLinkTextRequestToDomain(req, userID)
 		urlModel, err := svc.SetURL(ctx, model)
 
@@ -54,6 +49,10 @@ func HandlerSetURLText(svc ServiceURLShortener, urlroot string) http.HandlerFunc
 				httputils.WriteTextResponse(w, http.StatusConflict, resp.ShortURL)
 				return
 			}
+			if errors.Is(err, models.ErrQuota) {
+				httputils.WriteTextError(w, http.StatusForbidden, err.Error())
+				return
+			}
 			httputils.WriteTextError(w, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -4,24 +4,90 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"html/template"
 	"net/http"
+	"net/netip"
 	"strings"
+	"time"
 	"urlshortener/internal/domain/models"
 	"urlshortener/internal/http/httputils"
+
+	"github.com/gorilla/mux"
 )
 
 type ServiceURLShortener interface {
 	GetURL(ctx context.Context, shortKey string) (models.ShortenedLink, error)
+	BuildRedirectURL(ctx context.Context, link models.ShortenedLink, req models.RedirectRequest) (models.RedirectTarget, error)
+	RecordVariantClick(ctx context.Context, link models.ShortenedLink, variant string) error
+}
+
+const (
+	variantCookiePrefix = "ab_"
+	variantCookieMaxAge = 30 * 24 * 60 * 60
+)
+
+// GeoResolver определяет страну клиента для правил по стране
+type GeoResolver interface {
+	ClientIP(remoteAddr string, forwardedFor []string) netip.Addr
+	Country(ip netip.Addr) string
+}
+
+// InactiveFallbacks адреса для переходов по ссылкам вне окна активности.
+// Пустой адрес - показать страницу-заглушку
+type InactiveFallbacks struct {
+	NotYetActiveURL string
+	ExpiredURL      string
 }
 
-func HandlerGetURLWithID(svc ServiceURLShortener) http.HandlerFunc {
+var inactiveTemplate = template.Must(template.New("inactive").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>{{.Heading}}</title>
+</head>
+<body>
+<h1>{{.Heading}}</h1>
+{{if not .At.IsZero}}<p>{{.Since}} {{.At.UTC.Format "2006-01-02 15:04 MST"}}</p>{{end}}
+</body>
+</html>
+`))
+
+var quarantineTemplate = template.Must(template.New("quarantine").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Warning: reported link</title>
+</head>
+<body>
+<h1>Warning: this link has been reported</h1>
+<p>Other users reported this link as potentially harmful. It is waiting for review.</p>
+<p>Destination: <code>{{.}}</code></p>
+<p><a href="{{.}}" rel="noopener noreferrer nofollow">Continue anyway</a></p>
+</body>
+</html>
+`))
+
+// HandlerGetURLWithID перенаправляет на оригинальный URL. Код ответа берется из настроек
+// ссылки, а если он не задан - из defaultStatus. Постоянные перенаправления (301, 308) разрешено
+// кэшировать на permanentMaxAge, временные не кэшируются.
+// Обслуживает GET /{id} и GET /{id}/{path}, хвост пути передается дальше только для ссылок с PassPath.
+// Хвост "info" сюда не попадает: /{id}/info занят предпросмотром.
+// Правила перенаправления ссылки проверяются раньше OriginalURL.
+// Переходы до начала и после окончания окна активности уходят на fallbacks или получают страницу-заглушку.
+// Для ссылок в карантине вместо перехода показывается предупреждение со ссылкой на адрес назначения
+func HandlerGetURLWithID(svc ServiceURLShortener, geo GeoResolver, defaultStatus int, permanentMaxAge time.Duration, fallbacks InactiveFallbacks) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		id := strings.TrimPrefix(r.URL.Path, "/")
+		vars := mux.Vars(r)
+		id := vars["id"]
 
 		url, err := svc.GetURL(ctx, id)
 
-		if err != nil {
+		// Ссылка в карантине проходит обычный выбор назначения, но вместо перехода получает предупреждение
+		quarantined := errors.Is(err, models.ErrQuarantined)
+		if err != nil && !quarantined {
 			if errors.Is(err, models.ErrGone) {
 				httputils.WriteTextError(w, http.StatusGone, "URL has been deleted")
 				return
@@ -30,10 +96,155 @@ func HandlerGetURLWithID(svc ServiceURLShortener) http.HandlerFunc {
 				httputils.WriteTextError(w, http.StatusNotFound, "URL not found")
 				return
 			}
+			if errors.Is(err, models.ErrNotYetActive) {
+				writeInactive(w, r, fallbacks.NotYetActiveURL, http.StatusNotFound, "This link is not available yet", "Available from", url.ActiveFrom)
+				return
+			}
+			if errors.Is(err, models.ErrExpired) {
+				writeInactive(w, r, fallbacks.ExpiredURL, http.StatusGone, "This link has expired", "Ended", url.ActiveUntil)
+				return
+			}
+			if errors.Is(err, models.ErrBanned) {
+				writeInactive(w, r, "", http.StatusGone, "This link has been disabled", "", time.Time{})
+				return
+			}
 			httputils.WriteTextError(w, http.StatusBadRequest, fmt.Sprintf("GetURL Error(): %v", err))
 			return
 		}
-		httputils.WriteRedirect(w, url.OriginalURL, false)
 
+		redirectReq := models.RedirectRequest{
+			ExtraPath: vars["path"],
+			Query:     r.URL.Query(),
+			Header:    r.Header,
+		}
+		// База GeoIP нужна только ссылкам с правилами по стране
+		if hasRuleType(url.RedirectRules, models.RedirectRuleCountry) {
+			ip := geo.ClientIP(r.RemoteAddr, r.Header.Values(httputils.HeaderXForwardedFor))
+			redirectReq.Country = geo.Country(ip)
+		}
+
+		if url.StickyVariants {
+			if cookie, err := r.Cookie(variantCookiePrefix + url.ShortCode); err == nil {
+				redirectReq.Variant = cookie.Value
+			}
+		}
+
+		target, err := svc.BuildRedirectURL(ctx, url, redirectReq)
+		if err != nil {
+			if errors.Is(err, models.ErrUnfound) {
+				httputils.WriteTextError(w, http.StatusNotFound, "URL not found")
+				return
+			}
+			httputils.WriteTextError(w, http.StatusInternalServerError, fmt.Sprintf("BuildRedirectURL Error(): %v", err))
+			return
+		}
+
+		if quarantined {
+			writeQuarantined(w, r, target.URL)
+			return
+		}
+
+		status := url.RedirectStatus
+		if status == 0 {
+			status = defaultStatus
+		}
+		if target.Variant != "" {
+			// Ошибка счетчика не должна мешать перенаправлению
+			_ = svc.RecordVariantClick(ctx, url, target.Variant)
+
+			if url.StickyVariants {
+				http.SetCookie(w, &http.Cookie{
+					Name:     variantCookiePrefix + url.ShortCode,
+					Value:    target.Variant,
+					Path:     "/" + url.ShortCode,
+					MaxAge:   variantCookieMaxAge,
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+		}
+
+		// Назначение зависит от заголовков, по которым проверяются правила.
+		// Ответы со случайным выбором варианта не должны попадать в общие кэши
+		vary := ruleHeaders(url.RedirectRules)
+		if len(url.Variants) > 0 {
+			vary = []string{"*"}
+		}
+		if len(vary) > 0 {
+			w.Header().Set(httputils.HeaderVary, strings.Join(vary, ", "))
+		}
+		httputils.WriteRedirect(w, target.URL, status, permanentMaxAge)
+
+	}
+}
+
+// writeInactive отправляет переход по неактивной ссылке на fallback или отдает страницу-заглушку.
+// Состояние ссылки меняется со временем, поэтому ответ не кэшируется
+func writeInactive(w http.ResponseWriter, r *http.Request, fallback string, status int, heading, since string, at time.Time) {
+	if fallback != "" {
+		httputils.WriteRedirect(w, fallback, http.StatusFound, 0)
+		return
+	}
+
+	w.Header().Set(httputils.HeaderCacheControl, "no-store")
+	if httputils.NegotiateContentType(r, httputils.MIMETextHTML, httputils.MIMETextPlain) != httputils.MIMETextHTML {
+		httputils.WriteTextError(w, status, heading)
+		return
+	}
+
+	w.Header().Set(httputils.HeaderContentType, httputils.MIMETextHTML+"; charset=utf-8")
+	w.WriteHeader(status)
+	inactiveTemplate.Execute(w, struct {
+		Heading string
+		Since   string
+		At      time.Time
+	}{Heading: heading, Since: since, At: at})
+}
+
+// writeQuarantined отдает страницу-предупреждение вместо перехода.
+// Карантин могут снять в любой момент, поэтому ответ не кэшируется
+func writeQuarantined(w http.ResponseWriter, r *http.Request, target string) {
+	w.Header().Set(httputils.HeaderCacheControl, "no-store")
+	if httputils.NegotiateContentType(r, httputils.MIMETextHTML, httputils.MIMETextPlain) != httputils.MIMETextHTML {
+		httputils.WriteTextError(w, http.StatusOK, "Warning: this link has been reported and is waiting for review. Destination: "+target)
+		return
+	}
+
+	w.Header().Set(httputils.HeaderContentType, httputils.MIMETextHTML+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	quarantineTemplate.Execute(w, target)
+}
+
+// ruleHeaders возвращает заголовки запроса, от которых зависят правила ссылки
+func ruleHeaders(rules []models.RedirectRule) []string {
+	var headers []string
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		var header string
+		switch rule.Type {
+		case models.RedirectRulePlatform:
+			header = httputils.HeaderUserAgent
+		case models.RedirectRuleLanguage:
+			header = httputils.HeaderAcceptLanguage
+		case models.RedirectRuleHeader:
+			header = rule.Header
+		case models.RedirectRuleCountry:
+			// Страна зависит от адреса клиента, такой ответ не должен попадать в общие кэши
+			return []string{"*"}
+		}
+		if header != "" && !seen[header] {
+			seen[header] = true
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+func hasRuleType(rules []models.RedirectRule, ruleType string) bool {
+	for _, rule := range rules {
+		if rule.Type == ruleType {
+			return true
+		}
 	}
+	return false
 }
@@ -0,0 +1,157 @@
+package find_by_id
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/httputils"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeService отдает одну ссылку и перенаправляет на ее адрес или первый вариант
+type fakeService struct {
+	link models.ShortenedLink
+	err  error
+}
+
+func (f *fakeService) GetURL(ctx context.Context, shortKey string) (models.ShortenedLink, error) {
+	return f.link, f.err
+}
+
+func (f *fakeService) BuildRedirectURL(ctx context.Context, link models.ShortenedLink, req models.RedirectRequest) (models.RedirectTarget, error) {
+	if len(link.Variants) > 0 {
+		return models.RedirectTarget{URL: link.Variants[0].TargetURL, Variant: link.Variants[0].Name}, nil
+	}
+	return models.RedirectTarget{URL: link.OriginalURL}, nil
+}
+
+func (f *fakeService) RecordVariantClick(ctx context.Context, link models.ShortenedLink, variant string) error {
+	return nil
+}
+
+type fakeGeo struct{}
+
+func (fakeGeo) ClientIP(remoteAddr string, forwardedFor []string) netip.Addr { return netip.Addr{} }
+func (fakeGeo) Country(ip netip.Addr) string                                 { return "" }
+
+func TestHandlerGetURLWithID_CacheControl(t *testing.T) {
+	link := models.ShortenedLink{ShortCode: "abc", OriginalURL: "https://example.com/"}
+	withStatus := func(status int) models.ShortenedLink {
+		l := link
+		l.RedirectStatus = status
+		return l
+	}
+
+	tests := []struct {
+		name          string
+		link          models.ShortenedLink
+		err           error
+		defaultStatus int
+		maxAge        time.Duration
+		fallbacks     InactiveFallbacks
+		wantStatus    int
+		wantCache     string
+	}{
+		{
+			name:          "301 кэшируется на заданный срок",
+			link:          withStatus(http.StatusMovedPermanently),
+			defaultStatus: http.StatusTemporaryRedirect,
+			maxAge:        time.Hour,
+			wantStatus:    http.StatusMovedPermanently,
+			wantCache:     "public, max-age=3600",
+		},
+		{
+			name:          "308 кэшируется на заданный срок",
+			link:          withStatus(http.StatusPermanentRedirect),
+			defaultStatus: http.StatusTemporaryRedirect,
+			maxAge:        time.Hour,
+			wantStatus:    http.StatusPermanentRedirect,
+			wantCache:     "public, max-age=3600",
+		},
+		{
+			name:          "302 не кэшируется",
+			link:          withStatus(http.StatusFound),
+			defaultStatus: http.StatusMovedPermanently,
+			maxAge:        time.Hour,
+			wantStatus:    http.StatusFound,
+			wantCache:     "private, no-store",
+		},
+		{
+			name:          "307 не кэшируется",
+			link:          withStatus(http.StatusTemporaryRedirect),
+			defaultStatus: http.StatusMovedPermanently,
+			maxAge:        time.Hour,
+			wantStatus:    http.StatusTemporaryRedirect,
+			wantCache:     "private, no-store",
+		},
+		{
+			name:          "Код по умолчанию",
+			link:          link,
+			defaultStatus: http.StatusPermanentRedirect,
+			maxAge:        time.Hour,
+			wantStatus:    http.StatusPermanentRedirect,
+			wantCache:     "public, max-age=3600",
+		},
+		{
+			name:          "Срок ограничен сутками",
+			link:          withStatus(http.StatusMovedPermanently),
+			defaultStatus: http.StatusTemporaryRedirect,
+			maxAge:        30 * 24 * time.Hour,
+			wantStatus:    http.StatusMovedPermanently,
+			wantCache:     "public, max-age=86400",
+		},
+		{
+			name:          "Нулевой срок запрещает кэширование постоянных",
+			link:          withStatus(http.StatusMovedPermanently),
+			defaultStatus: http.StatusTemporaryRedirect,
+			wantStatus:    http.StatusMovedPermanently,
+			wantCache:     "private, no-store",
+		},
+		{
+			name: "Ответ с cookie варианта не попадает в общие кэши",
+			link: models.ShortenedLink{
+				ShortCode:      "abc",
+				OriginalURL:    "https://example.com/",
+				RedirectStatus: http.StatusMovedPermanently,
+				StickyVariants: true,
+				Variants:       []models.LinkVariant{{Name: "a", TargetURL: "https://example.com/a", Weight: 1}},
+			},
+			defaultStatus: http.StatusTemporaryRedirect,
+			maxAge:        time.Hour,
+			wantStatus:    http.StatusMovedPermanently,
+			wantCache:     "private, max-age=3600",
+		},
+		{
+			name:          "Перенаправление на заглушку истекшей ссылки не кэшируется",
+			link:          withStatus(http.StatusMovedPermanently),
+			err:           models.ErrExpired,
+			defaultStatus: http.StatusTemporaryRedirect,
+			maxAge:        time.Hour,
+			fallbacks:     InactiveFallbacks{ExpiredURL: "https://example.com/expired"},
+			wantStatus:    http.StatusFound,
+			wantCache:     "private, no-store",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeService{link: tt.link, err: tt.err}
+			handler := HandlerGetURLWithID(svc, fakeGeo{}, tt.defaultStatus, tt.maxAge, tt.fallbacks)
+
+			r := httptest.NewRequest(http.MethodGet, "/abc", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": "abc"})
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantCache, w.Header().Get(httputils.HeaderCacheControl))
+			assert.NotEmpty(t, w.Header().Get(httputils.HeaderLocation))
+		})
+	}
+}
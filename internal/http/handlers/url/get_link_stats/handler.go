@@ -0,0 +1,44 @@
+package get_link_stats
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/dto"
+	"urlshortener/internal/http/httputils"
+
+	"github.com/gorilla/mux"
+)
+
+type ServiceURLShortener interface {
+	GetLinkStats(ctx context.Context, userID int64, shortKey string) (models.ShortenedLink, error)
+	GetShortURL(shortKey string) string
+}
+
+// HandlerGetLinkStats отдает статистику переходов ссылки пользователя по A/B-вариантам
+func HandlerGetLinkStats(svc ServiceURLShortener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userID, ok := ctx.Value("user_id").(int64)
+		if !ok || userID == 0 {
+			httputils.WriteJSONError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		link, err := svc.GetLinkStats(ctx, userID, mux.Vars(r)["code"])
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrUnfound), errors.Is(err, models.ErrInvalidData):
+				httputils.WriteJSONError(w, http.StatusNotFound, "URL not found")
+			default:
+				httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		resp := dto.ShortenedLinkStatsResponseFromDomain(link, svc.GetShortURL(link.ShortCode))
+		httputils.WriteJSONResponse(w, http.StatusOK, resp)
+	}
+}
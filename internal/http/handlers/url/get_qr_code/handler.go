@@ -0,0 +1,141 @@
+package get_qr_code
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/httputils"
+
+	"github.com/gorilla/mux"
+)
+
+// QR-код для ссылки не меняется, пока не меняется BaseURL, поэтому кэшируем надолго
+const qrCacheMaxAge = 24 * 60 * 60
+
+type ServiceURLShortener interface {
+	GetURL(ctx context.Context, shortKey string) (models.ShortenedLink, error)
+	GetShortURL(shortKey string) string
+}
+
+type QRCodeEncoder interface {
+	Normalize(opts models.QRCodeOptions) (models.QRCodeOptions, error)
+	Encode(content string, opts models.QRCodeOptions) ([]byte, error)
+}
+
+func HandlerGetQRCode(svc ServiceURLShortener, encoder QRCodeEncoder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userID, ok := ctx.Value("user_id").(int64)
+		if !ok || userID == 0 {
+			httputils.WriteJSONError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		code := mux.Vars(r)["code"]
+		opts, err := parseOptions(r)
+		if err != nil {
+			httputils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		opts, err = encoder.Normalize(opts)
+		if err != nil {
+			httputils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		link, err := svc.GetURL(ctx, code)
+		// QR-код печатают заранее, окно активности на него не влияет.
+		// Ссылка в карантине продолжает работать через предупреждение
+		if errors.Is(err, models.ErrNotYetActive) || errors.Is(err, models.ErrExpired) || errors.Is(err, models.ErrQuarantined) {
+			err = nil
+		}
+		if err != nil {
+			if errors.Is(err, models.ErrGone) {
+				httputils.WriteJSONError(w, http.StatusGone, "URL has been deleted")
+				return
+			}
+			if errors.Is(err, models.ErrBanned) {
+				httputils.WriteJSONError(w, http.StatusGone, "URL has been disabled")
+				return
+			}
+			if errors.Is(err, models.ErrUnfound) || errors.Is(err, models.ErrInvalidData) {
+				httputils.WriteJSONError(w, http.StatusNotFound, "URL not found")
+				return
+			}
+			httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		// Чужие ссылки не раскрываем
+		if link.UserID != userID {
+			httputils.WriteJSONError(w, http.StatusNotFound, "URL not found")
+			return
+		}
+
+		shortURL := svc.GetShortURL(link.ShortCode)
+		etag := buildETag(shortURL, opts)
+
+		w.Header().Set(httputils.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", qrCacheMaxAge))
+		w.Header().Set(httputils.HeaderETag, etag)
+
+		if r.Header.Get(httputils.HeaderIfNoneMatch) == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		image, err := encoder.Encode(shortURL, opts)
+		if err != nil {
+			httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		contentType := httputils.MIMEImagePNG
+		if opts.Format == models.QRCodeFormatSVG {
+			contentType = httputils.MIMEImageSVG
+		}
+
+		w.Header().Set(httputils.HeaderContentType, contentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(image)
+	}
+}
+
+func parseOptions(r *http.Request) (models.QRCodeOptions, error) {
+	query := r.URL.Query()
+
+	opts := models.QRCodeOptions{
+		Format: query.Get("format"),
+		ECC:    query.Get("ecc"),
+		Margin: -1,
+	}
+
+	if raw := query.Get("size"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil {
+			return models.QRCodeOptions{}, fmt.Errorf("%w: invalid size", models.ErrInvalidData)
+		}
+		opts.Size = size
+	}
+
+	if raw := query.Get("margin"); raw != "" {
+		margin, err := strconv.Atoi(raw)
+		if err != nil || margin < 0 {
+			return models.QRCodeOptions{}, fmt.Errorf("%w: invalid margin", models.ErrInvalidData)
+		}
+		opts.Margin = margin
+	}
+
+	return opts, nil
+}
+
+func buildETag(shortURL string, opts models.QRCodeOptions) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%d|%s", shortURL, opts.Format, opts.Size, opts.Margin, opts.ECC)))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
@@ -0,0 +1,76 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/dto"
+	"urlshortener/internal/http/httputils"
+
+	"github.com/gorilla/mux"
+)
+
+type ServiceURLShortener interface {
+	GetLinkInfo(ctx context.Context, shortKey string) (models.ShortenedLink, models.LinkStatus, error)
+	GetShortURL(shortKey string) string
+}
+
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Link preview: {{.ShortURL}}</title>
+</head>
+<body>
+<h1>Link preview</h1>
+<dl>
+<dt>Short link</dt><dd>{{.ShortURL}}</dd>
+{{if .Title}}<dt>Title</dt><dd>{{.Title}}</dd>{{end}}
+<dt>Destination</dt><dd>{{if eq .Status "active"}}<a href="{{.OriginalURL}}" rel="noopener noreferrer nofollow">{{.OriginalURL}}</a>{{else}}{{.OriginalURL}}{{end}}</dd>
+<dt>Created</dt><dd>{{.CreatedAt.Format "2006-01-02 15:04 MST"}}</dd>
+<dt>Status</dt><dd>{{.Status}}</dd>
+{{with .ActiveFrom}}<dt>Active from</dt><dd>{{.UTC.Format "2006-01-02 15:04 MST"}}</dd>{{end}}
+{{with .ActiveUntil}}<dt>Active until</dt><dd>{{.UTC.Format "2006-01-02 15:04 MST"}}</dd>{{end}}
+</dl>
+</body>
+</html>
+`))
+
+// HandlerPreview показывает информацию о ссылке вместо перенаправления.
+// Обслуживает GET /{id}/info и GET /{id}+, формат ответа выбирается по Accept.
+// Заголовок - тот, что пользователь указал при создании, страница назначения не загружается.
+func HandlerPreview(svc ServiceURLShortener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := strings.TrimSuffix(mux.Vars(r)["id"], "+")
+
+		link, status, err := svc.GetLinkInfo(ctx, id)
+		if err != nil {
+			if errors.Is(err, models.ErrUnfound) || errors.Is(err, models.ErrInvalidData) {
+				httputils.WriteTextError(w, http.StatusNotFound, "URL not found")
+				return
+			}
+			httputils.WriteTextError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		resp := dto.ShortenedLinkPreviewResponseFromDomain(link, status, svc.GetShortURL(link.ShortCode))
+
+		// Предпросмотр зависит от Accept и может смениться при удалении ссылки
+		w.Header().Set(httputils.HeaderVary, httputils.HeaderAccept)
+		w.Header().Set(httputils.HeaderCacheControl, "no-cache")
+
+		if httputils.NegotiateContentType(r, httputils.MIMETextHTML, httputils.MIMEApplicationJSON) == httputils.MIMEApplicationJSON {
+			httputils.WriteJSONResponse(w, http.StatusOK, resp)
+			return
+		}
+
+		w.Header().Set(httputils.HeaderContentType, httputils.MIMETextHTML+"; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		previewTemplate.Execute(w, resp)
+	}
+}
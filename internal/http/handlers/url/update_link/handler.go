@@ -0,0 +1,63 @@
+package update_link
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/dto"
+	"urlshortener/internal/http/httputils"
+
+	"github.com/gorilla/mux"
+)
+
+type ServiceURLShortener interface {
+	UpdateLink(ctx context.Context, userID int64, shortKey string, update models.ShortenedLinkUpdate) (models.ShortenedLink, error)
+	GetShortURL(shortKey string) string
+}
+
+func HandlerUpdateLink(svc ServiceURLShortener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userID, ok := ctx.Value("user_id").(int64)
+		if !ok || userID == 0 {
+			httputils.WriteJSONError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		var req dto.ShortenedLinkUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httputils.WriteJSONError(w, http.StatusBadRequest, "invalid request format")
+			return
+		}
+
+		update, err := dto.ShortenedLinkUpdateRequestToDomain(req)
+		if err != nil {
+			httputils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		link, err := svc.UpdateLink(ctx, userID, mux.Vars(r)["code"], update)
+		if err != nil {
+			var violation *models.PolicyViolation
+			switch {
+			case errors.As(err, &violation):
+				httputils.WriteJSONResponse(w, http.StatusBadRequest, dto.PolicyErrorResponseFromDomain(violation))
+			case errors.Is(err, models.ErrGone):
+				httputils.WriteJSONError(w, http.StatusGone, "URL has been deleted")
+			case errors.Is(err, models.ErrUnfound):
+				httputils.WriteJSONError(w, http.StatusNotFound, "URL not found")
+			case errors.Is(err, models.ErrInvalidData):
+				httputils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+			default:
+				httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		resp := dto.ShortenedLinkDetailsResponseFromDomain(link, svc.GetShortURL(link.ShortCode))
+		httputils.WriteJSONResponse(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,49 @@
+package create_template
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/dto"
+	"urlshortener/internal/http/httputils"
+)
+
+type ServiceURLShortener interface {
+	CreateUTMTemplate(ctx context.Context, tpl models.UTMTemplate) (models.UTMTemplate, error)
+}
+
+// HandlerCreateUTMTemplate сохраняет именованный UTM-шаблон пользователя
+func HandlerCreateUTMTemplate(svc ServiceURLShortener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userID, ok := ctx.Value("user_id").(int64)
+		if !ok || userID == 0 {
+			httputils.WriteJSONError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		var req dto.UTMTemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httputils.WriteJSONError(w, http.StatusBadRequest, "invalid request format")
+			return
+		}
+
+		tpl, err := svc.CreateUTMTemplate(ctx, dto.UTMTemplateRequestToDomain(req, userID))
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrConflict):
+				httputils.WriteJSONError(w, http.StatusConflict, "UTM template with this name already exists")
+			case errors.Is(err, models.ErrInvalidData):
+				httputils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+			default:
+				httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		httputils.WriteJSONResponse(w, http.StatusCreated, dto.UTMTemplateResponseFromDomain(tpl))
+	}
+}
@@ -0,0 +1,42 @@
+package delete_template
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/httputils"
+
+	"github.com/gorilla/mux"
+)
+
+type ServiceURLShortener interface {
+	DeleteUTMTemplate(ctx context.Context, userID int64, name string) error
+}
+
+// HandlerDeleteUTMTemplate удаляет UTM-шаблон, ссылки с этим шаблоном отвязываются от него
+func HandlerDeleteUTMTemplate(svc ServiceURLShortener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userID, ok := ctx.Value("user_id").(int64)
+		if !ok || userID == 0 {
+			httputils.WriteJSONError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		if err := svc.DeleteUTMTemplate(ctx, userID, mux.Vars(r)["name"]); err != nil {
+			switch {
+			case errors.Is(err, models.ErrUnfound):
+				httputils.WriteJSONError(w, http.StatusNotFound, err.Error())
+			case errors.Is(err, models.ErrInvalidData):
+				httputils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+			default:
+				httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
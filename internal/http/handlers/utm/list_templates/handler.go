@@ -0,0 +1,39 @@
+package list_templates
+
+import (
+	"context"
+	"net/http"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/http/dto"
+	"urlshortener/internal/http/httputils"
+)
+
+type ServiceURLShortener interface {
+	GetUserUTMTemplates(ctx context.Context, userID int64) ([]models.UTMTemplate, error)
+}
+
+// HandlerListUTMTemplates возвращает UTM-шаблоны пользователя, 204 если их нет
+func HandlerListUTMTemplates(svc ServiceURLShortener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userID, ok := ctx.Value("user_id").(int64)
+		if !ok || userID == 0 {
+			httputils.WriteJSONError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		templates, err := svc.GetUserUTMTemplates(ctx, userID)
+		if err != nil {
+			httputils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if len(templates) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		httputils.WriteJSONResponse(w, http.StatusOK, dto.UTMTemplateResponseFromDomains(templates))
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // MIME: https://developer.mozilla.org/en-US/docs/Web/HTTP/Guides/MIME_types/Common_types
@@ -16,11 +19,26 @@ const (
 	HeaderContentLength   = "Content-Length"
 	HeaderUserAgent       = "User-Agent"
 	HeaderLocation        = "Location"
+	HeaderCacheControl    = "Cache-Control"
+	HeaderETag            = "ETag"
+	HeaderIfNoneMatch     = "If-None-Match"
+	HeaderAccept          = "Accept"
+	HeaderVary            = "Vary"
+	HeaderAcceptLanguage  = "Accept-Language"
+	HeaderXForwardedFor   = "X-Forwarded-For"
+	HeaderRetryAfter      = "Retry-After"
+	HeaderAuthorization   = "Authorization"
+	HeaderWWWAuthenticate = "WWW-Authenticate"
+	HeaderIdempotencyKey  = "Idempotency-Key"
+	HeaderIdempotentReply = "Idempotent-Replayed"
+	HeaderSetCookie       = "Set-Cookie"
 
 	MIMEApplicationJSON       = "application/json"
 	MIMETextHTML              = "text/html"
 	MIMETextPlain             = "text/plain"
 	MIMEGZipCompressedArchive = "application/gzip"
+	MIMEImagePNG              = "image/png"
+	MIMEImageSVG              = "image/svg+xml"
 
 	EncodingGzip = "gzip"
 )
@@ -67,17 +85,97 @@ func WriteJSONResponse[T any](w http.ResponseWriter, status int, data T) {
 	json.NewEncoder(w).Encode(data)
 }
 
-// WriteRedirect writes a redirect response
-func WriteRedirect(w http.ResponseWriter, location string, permanent bool) {
-	status := http.StatusTemporaryRedirect
-	if permanent {
-		status = http.StatusPermanentRedirect
+// CacheControlTemporaryRedirect makes clients re-request temporary redirects every time
+const CacheControlTemporaryRedirect = "private, no-store"
+
+// MaxPermanentRedirectAge bounds how long a permanent redirect may be cached: a link can still be
+// retargeted, deleted or quarantined, and clients would keep the old target until the entry expires
+const MaxPermanentRedirectAge = 24 * time.Hour
+
+// RedirectCacheControl returns Cache-Control for a redirect with the given 3xx status.
+// Permanent redirects (301, 308) are cached for maxAge, shared caches included unless the response
+// sets a cookie. A non-positive maxAge makes them as uncacheable as temporary ones
+func RedirectCacheControl(status int, maxAge time.Duration, setsCookie bool) string {
+	permanent := status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect
+	if !permanent || maxAge <= 0 {
+		return CacheControlTemporaryRedirect
+	}
+
+	maxAge = min(maxAge, MaxPermanentRedirectAge)
+	scope := "public"
+	if setsCookie {
+		scope = "private"
 	}
+	return fmt.Sprintf("%s, max-age=%d", scope, int64(maxAge/time.Second))
+}
 
+// WriteRedirect writes a redirect response with the given 3xx status.
+// maxAge is how long a permanent redirect may be cached, see RedirectCacheControl
+func WriteRedirect(w http.ResponseWriter, location string, status int, maxAge time.Duration) {
+	setsCookie := len(w.Header().Values(HeaderSetCookie)) > 0
+	w.Header().Set(HeaderCacheControl, RedirectCacheControl(status, maxAge, setsCookie))
 	w.Header().Set(HeaderLocation, location)
 	w.WriteHeader(status)
 }
 
+// NegotiateContentType picks the offer the client prefers according to the Accept header.
+// Without Accept the first offer wins; if nothing is acceptable an empty string is returned.
+func NegotiateContentType(r *http.Request, offers ...string) string {
+	header := r.Header.Get(HeaderAccept)
+	if header == "" || len(offers) == 0 {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := -1.0, -1
+		for _, part := range strings.Split(header, ",") {
+			mediaRange, partQ := parseMediaRange(part)
+			s := matchMediaRange(mediaRange, offer)
+			if s > specificity {
+				q, specificity = partQ, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// parseMediaRange splits "type/subtype;q=0.5" into the range and its quality
+func parseMediaRange(part string) (string, float64) {
+	params := strings.Split(part, ";")
+	mediaRange := strings.ToLower(strings.TrimSpace(params[0]))
+	q := 1.0
+	for _, param := range params[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if ok && strings.TrimSpace(key) == "q" {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+	}
+	return mediaRange, q
+}
+
+// matchMediaRange returns how specifically mediaRange matches offer, or -1 if it does not
+func matchMediaRange(mediaRange, offer string) int {
+	switch {
+	case mediaRange == offer:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	default:
+		return -1
+	}
+}
+
 // BuildShortURL constructs a short URL from base and ID
 func BuildShortURL(urlroot, id string) string {
 	return fmt.Sprintf("http://%s/%s", urlroot, id)
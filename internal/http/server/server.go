@@ -4,20 +4,44 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 	"urlshortener/internal/config"
+	"urlshortener/internal/http/handlers/abuse/get_link_reports"
+	"urlshortener/internal/http/handlers/abuse/list_reports"
+	"urlshortener/internal/http/handlers/abuse/moderate_link"
+	"urlshortener/internal/http/handlers/abuse/report_link"
+	"urlshortener/internal/http/handlers/middlewares/admin"
 	"urlshortener/internal/http/handlers/middlewares/authorization"
 	"urlshortener/internal/http/handlers/middlewares/compressor"
+	"urlshortener/internal/http/handlers/middlewares/idempotent"
 	"urlshortener/internal/http/handlers/middlewares/logger"
+	"urlshortener/internal/http/handlers/middlewares/ratelimit"
+	"urlshortener/internal/http/handlers/quota/get_usage"
+	"urlshortener/internal/http/handlers/system/cache_stats"
 	"urlshortener/internal/http/handlers/system/ping"
+	"urlshortener/internal/http/handlers/system/storage_stats"
 	"urlshortener/internal/http/handlers/url/create_json"
 	"urlshortener/internal/http/handlers/url/create_json_batch"
 	"urlshortener/internal/http/handlers/url/create_text"
 	"urlshortener/internal/http/handlers/url/delete_batch"
 	"urlshortener/internal/http/handlers/url/find_by_id"
 	"urlshortener/internal/http/handlers/url/get_default"
+	"urlshortener/internal/http/handlers/url/get_link_stats"
+	"urlshortener/internal/http/handlers/url/get_qr_code"
 	"urlshortener/internal/http/handlers/url/list_user_urls"
+	"urlshortener/internal/http/handlers/url/preview"
+	"urlshortener/internal/http/handlers/url/update_link"
+	"urlshortener/internal/http/handlers/utm/create_template"
+	"urlshortener/internal/http/handlers/utm/delete_template"
+	"urlshortener/internal/http/handlers/utm/list_templates"
 	"urlshortener/internal/services/auth"
+	"urlshortener/internal/services/geoip"
+	"urlshortener/internal/services/idempotency"
+	"urlshortener/internal/services/link_cache"
+	"urlshortener/internal/services/qr_code"
+	"urlshortener/internal/services/quota"
+	"urlshortener/internal/services/rate_limit"
 	"urlshortener/internal/services/url_shortener"
 
 	"github.com/gorilla/mux"
@@ -30,10 +54,30 @@ type Server struct {
 	log         *zerolog.Logger
 	authService *auth.Authentication
 	urlService  *url_shortener.URLShortener
+	quotaSvc    *quota.Service
+	qrEncoder   *qr_code.Encoder
+	geoResolver *geoip.Resolver
+	limiter     *rate_limit.Limiter
+	idempotency *idempotency.Service
+	linkCache   *link_cache.Storage
+	dbStats     storage_stats.ServiceStorageStats
 	cfg         config.Config
 }
 
-func NewServer(log *zerolog.Logger, cfg config.Config, svc *url_shortener.URLShortener, auth *auth.Authentication) (*Server, error) {
+// Deps сервисы, которые обслуживает сервер. Все поля, кроме DBStats, обязательны
+type Deps struct {
+	URLService  *url_shortener.URLShortener
+	QuotaSvc    *quota.Service
+	AuthService *auth.Authentication
+	QREncoder   *qr_code.Encoder
+	GeoResolver *geoip.Resolver
+	Limiter     *rate_limit.Limiter
+	Idempotency *idempotency.Service
+	LinkCache   *link_cache.Storage
+	DBStats     storage_stats.ServiceStorageStats // nil - хранилище без счетчиков
+}
+
+func NewServer(log *zerolog.Logger, cfg config.Config, deps Deps) (*Server, error) {
 	/*
 		хз по идее конфиг создается через фабрику где уже есть валидация и
 		стандартные значения, сюда по идее нереально подать пустую cfg
@@ -45,17 +89,45 @@ func NewServer(log *zerolog.Logger, cfg config.Config, svc *url_shortener.URLSho
 	if log == nil {
 		return nil, errors.New("logger cannot be nil")
 	}
-	if svc == nil {
+	if deps.URLService == nil {
 		return nil, errors.New("service cannot be nil")
 	}
+	if deps.QuotaSvc == nil {
+		return nil, errors.New("quota service cannot be nil")
+	}
+	if deps.AuthService == nil {
+		return nil, errors.New("auth service cannot be nil")
+	}
+	if deps.QREncoder == nil {
+		return nil, errors.New("qr encoder cannot be nil")
+	}
+	if deps.GeoResolver == nil {
+		return nil, errors.New("geoip resolver cannot be nil")
+	}
+	if deps.Limiter == nil {
+		return nil, errors.New("rate limiter cannot be nil")
+	}
+	if deps.Idempotency == nil {
+		return nil, errors.New("idempotency service cannot be nil")
+	}
+	if deps.LinkCache == nil {
+		return nil, errors.New("link cache cannot be nil")
+	}
 
 	s :=
 		&Server{
 			router:      mux.NewRouter(),
 			cfg:         cfg,
 			log:         log,
-			authService: auth,
-			urlService:  svc,
+			authService: deps.AuthService,
+			urlService:  deps.URLService,
+			quotaSvc:    deps.QuotaSvc,
+			qrEncoder:   deps.QREncoder,
+			geoResolver: deps.GeoResolver,
+			limiter:     deps.Limiter,
+			idempotency: deps.Idempotency,
+			linkCache:   deps.LinkCache,
+			dbStats:     deps.DBStats,
 		}
 
 	s.httpServer = &http.Server{
@@ -77,19 +149,92 @@ func (s *Server) setupRoutes() {
 	s.router.Use(compressor.MiddlewareCompressing())
 
 	// Public routes (no auth required)
+	redirect := s.limit(rate_limit.ScopeRedirect, s.clientKey())
 	s.router.HandleFunc("/ping", ping.HandlerPing(s.urlService)).Methods("GET")
-	s.router.HandleFunc("/{id}", find_by_id.HandlerGetURLWithID(s.urlService)).Methods("GET") // 307
-	s.router.HandleFunc("/", get_default.HandlerGetDefault()).Methods("GET")                  // 400
+	// /{id}/info зарегистрирован раньше хвоста пути, поэтому хвост "info" не передается даже ссылкам с PassPath
+	s.router.HandleFunc("/{id}/info", preview.HandlerPreview(s.urlService)).Methods("GET")
+	s.router.HandleFunc(`/{id:[^/]+\+}`, preview.HandlerPreview(s.urlService)).Methods("GET")
+	s.router.Handle("/{id}", redirect(find_by_id.HandlerGetURLWithID(s.urlService, s.geoResolver, s.cfg.RedirectStatus, s.cfg.PermanentRedirectMaxAge, s.inactiveFallbacks()))).Methods("GET") // 307 по умолчанию
+	// Хвост пути после кода: /{id}/extra/path. Префикс /api/ зарезервирован за защищенными маршрутами
+	s.router.Handle("/{id}/{path:.+}", redirect(find_by_id.HandlerGetURLWithID(s.urlService, s.geoResolver, s.cfg.RedirectStatus, s.cfg.PermanentRedirectMaxAge, s.inactiveFallbacks()))).
+		Methods("GET").
+		MatcherFunc(notAPIPath)
+	s.router.HandleFunc("/", get_default.HandlerGetDefault()).Methods("GET") // 400
+
+	// Жалобы принимаются без авторизации, поэтому частота ограничена по адресу клиента
+	report := s.limit(rate_limit.ScopeReport, ratelimit.IPKey(s.geoResolver))
+	s.router.Handle("/api/report/{code}", report(report_link.HandlerReportLink(s.urlService, s.geoResolver))).Methods("POST") // 202
+
+	// Модерация доступна только с токеном администратора и выключена, если токен не задан
+	if s.cfg.AdminToken != "" {
+		adminRouter := s.router.PathPrefix("/api/admin").Subrouter()
+		adminRouter.Use(admin.MiddlewareAdmin(s.cfg.AdminToken))
+		adminRouter.HandleFunc("/reports", list_reports.HandlerListReports(s.urlService)).Methods("GET")
+		adminRouter.HandleFunc("/reports/{code}", get_link_reports.HandlerGetLinkReports(s.urlService)).Methods("GET")
+		adminRouter.HandleFunc("/links/{code}/clear", moderate_link.HandlerClearLink(s.urlService)).Methods("POST")
+		adminRouter.HandleFunc("/links/{code}/ban", moderate_link.HandlerBanLink(s.urlService)).Methods("POST")
+		if s.linkCache.Enabled() {
+			adminRouter.HandleFunc("/cache", cache_stats.HandlerCacheStats(s.linkCache)).Methods("GET")
+		}
+		// Счетчики есть только у PostgreSQL
+		if s.dbStats != nil {
+			adminRouter.HandleFunc("/storage", storage_stats.HandlerStorageStats(s.dbStats)).Methods("GET")
+		}
+	}
 
+	// Каждый запрос без куки создает пользователя, поэтому регистрация ограничена отдельно
 	authRouter := s.router.PathPrefix("/").Subrouter()
-	authRouter.Use(authorization.MiddlewareAuth(s.authService))
+	authRouter.Use(authorization.MiddlewareAuth(s.authService, s.limit(rate_limit.ScopeRegister, s.clientKey())))
 
 	// Protected routes (with auth)
-	authRouter.HandleFunc("/api/shorten/batch", create_json_batch.HandlerSetURLJsonBatch(s.urlService, s.cfg.ServerAddress)).Methods("POST") // 201
-	authRouter.HandleFunc("/api/shorten", create_json.HandlerSetURLJson(s.urlService, s.cfg.ServerAddress)).Methods("POST")                  // 201
+	// Ключ идемпотентности проверяется после ограничителя частоты, чтобы отказ 429 не сохранялся как ответ
+	limit := s.limit(rate_limit.ScopeCreate, s.clientKey())
+	create := func(next http.Handler) http.Handler { return limit(s.idempotent(next)) }
+	authRouter.Handle("/api/shorten/batch", create(create_json_batch.HandlerSetURLJsonBatch(s.log, s.urlService, s.cfg.ServerAddress))).Methods("POST") // 201
+	authRouter.Handle("/api/shorten", create(create_json.HandlerSetURLJson(s.log, s.urlService, s.cfg.ServerAddress))).Methods("POST")                  // 201
 	authRouter.HandleFunc("/api/user/urls", list_user_urls.HandlerGetURLJsonBatch(s.urlService, s.cfg.ServerAddress)).Methods("GET")
 	authRouter.HandleFunc("/api/user/urls", delete_batch.HandlerDeleteURLBatch(s.urlService)).Methods("DELETE")
-	authRouter.HandleFunc("/", create_text.HandlerSetURLText(s.urlService, s.cfg.ServerAddress)).Methods("POST") // 201
+	authRouter.HandleFunc("/api/user/urls/{code}", update_link.HandlerUpdateLink(s.urlService)).Methods("PATCH")
+	authRouter.HandleFunc("/api/user/urls/{code}/stats", get_link_stats.HandlerGetLinkStats(s.urlService)).Methods("GET")
+	authRouter.HandleFunc("/api/user/urls/{code}/qr", get_qr_code.HandlerGetQRCode(s.urlService, s.qrEncoder)).Methods("GET")
+	authRouter.HandleFunc("/api/user/quota", get_usage.HandlerGetQuotaUsage(s.quotaSvc)).Methods("GET")
+	authRouter.HandleFunc("/api/user/utm", create_template.HandlerCreateUTMTemplate(s.urlService)).Methods("POST") // 201
+	authRouter.HandleFunc("/api/user/utm", list_templates.HandlerListUTMTemplates(s.urlService)).Methods("GET")
+	authRouter.HandleFunc("/api/user/utm/{name}", delete_template.HandlerDeleteUTMTemplate(s.urlService)).Methods("DELETE")
+	authRouter.Handle("/", create(create_text.HandlerSetURLText(s.urlService, s.cfg.ServerAddress))).Methods("POST") // 201
+}
+
+func (s *Server) inactiveFallbacks() find_by_id.InactiveFallbacks {
+	return find_by_id.InactiveFallbacks{
+		NotYetActiveURL: s.cfg.NotYetActiveURL,
+		ExpiredURL:      s.cfg.ExpiredURL,
+	}
+}
+
+// limit ограничивает частоту запросов области scope, для неограниченных областей обертка не добавляется
+func (s *Server) limit(scope string, key ratelimit.KeyFunc) func(http.Handler) http.Handler {
+	if !s.limiter.Enabled(scope) {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return ratelimit.MiddlewareRateLimit(s.limiter, scope, key, s.log)
+}
+
+// idempotent повторяет сохраненный ответ на запрос с Idempotency-Key, если идемпотентность включена
+func (s *Server) idempotent(next http.Handler) http.Handler {
+	if !s.idempotency.Enabled() {
+		return next
+	}
+	return idempotent.MiddlewareIdempotency(s.idempotency, s.log)(next)
+}
+
+// clientKey различает клиентов по API-ключу, пользователю или адресу
+func (s *Server) clientKey() ratelimit.KeyFunc {
+	return ratelimit.ClientKey(s.cfg.APIKeys, s.geoResolver)
+}
+
+// notAPIPath не дает публичным маршрутам с произвольным хвостом перехватывать /api/...
+func notAPIPath(r *http.Request, _ *mux.RouteMatch) bool {
+	return !strings.HasPrefix(r.URL.Path, "/api/")
 }
 
 func (s *Server) Start(ctx context.Context) error {
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"urlshortener/internal/services/rate_limit"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingStore считает обращения к корзинам и пропускает только первый запрос
+type countingStore struct {
+	takes int
+}
+
+func (s *countingStore) RateLimitTake(ctx context.Context, key string, rate, burst float64) (bool, time.Duration, error) {
+	s.takes++
+	return s.takes == 1, time.Second, nil
+}
+
+func (s *countingStore) RateLimitPrune(ctx context.Context, idle time.Duration) (int64, error) {
+	return 0, nil
+}
+
+type okHandler struct{}
+
+func (okHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestServer_limit(t *testing.T) {
+	tests := []struct {
+		name       string
+		rules      map[string]rate_limit.Rule
+		wantStatus []int
+		wantTakes  int
+	}{
+		{
+			name:       "Нулевой бюджет не ограничивает",
+			rules:      map[string]rate_limit.Rule{rate_limit.ScopeCreate: {PerMinute: 0}},
+			wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+			wantTakes:  0,
+		},
+		{
+			name:       "Область без правила не ограничивается",
+			rules:      map[string]rate_limit.Rule{rate_limit.ScopeRedirect: {PerMinute: 1}},
+			wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+			wantTakes:  0,
+		},
+		{
+			name:       "Ненулевой бюджет ограничивает",
+			rules:      map[string]rate_limit.Rule{rate_limit.ScopeCreate: {PerMinute: 1}},
+			wantStatus: []int{http.StatusOK, http.StatusTooManyRequests},
+			wantTakes:  2,
+		},
+	}
+
+	log := zerolog.Nop()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &countingStore{}
+			s := &Server{log: &log, limiter: rate_limit.NewLimiter(store, tt.rules)}
+
+			var next http.Handler = okHandler{}
+			handler := s.limit(rate_limit.ScopeCreate, func(r *http.Request) string { return "client" })(next)
+			if tt.wantTakes == 0 {
+				// Без бюджета middleware не ставится вовсе
+				assert.Equal(t, next, handler)
+			}
+
+			for i, want := range tt.wantStatus {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/shorten", nil))
+				assert.Equal(t, want, rec.Code, "request %d", i+1)
+			}
+			assert.Equal(t, tt.wantTakes, store.takes)
+		})
+	}
+}
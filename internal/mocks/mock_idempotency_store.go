@@ -0,0 +1,102 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: idempotency.go
+//
+// Generated by this command:
+//
+//	mockgen -source=idempotency.go -destination=../../mocks/mock_idempotency_store.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+	models "urlshortener/internal/domain/models"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockStore is a mock of Store interface.
+type MockStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockStoreMockRecorder is the mock recorder for MockStore.
+type MockStoreMockRecorder struct {
+	mock *MockStore
+}
+
+// NewMockStore creates a new mock instance.
+func NewMockStore(ctrl *gomock.Controller) *MockStore {
+	mock := &MockStore{ctrl: ctrl}
+	mock.recorder = &MockStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStore) EXPECT() *MockStoreMockRecorder {
+	return m.recorder
+}
+
+// IdempotencyBegin mocks base method.
+func (m *MockStore) IdempotencyBegin(ctx context.Context, record models.IdempotencyRecord, staleBefore time.Time) (models.IdempotencyRecord, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IdempotencyBegin", ctx, record, staleBefore)
+	ret0, _ := ret[0].(models.IdempotencyRecord)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// IdempotencyBegin indicates an expected call of IdempotencyBegin.
+func (mr *MockStoreMockRecorder) IdempotencyBegin(ctx, record, staleBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IdempotencyBegin", reflect.TypeOf((*MockStore)(nil).IdempotencyBegin), ctx, record, staleBefore)
+}
+
+// IdempotencyComplete mocks base method.
+func (m *MockStore) IdempotencyComplete(ctx context.Context, userID int64, key, owner string, statusCode int, contentType string, body []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IdempotencyComplete", ctx, userID, key, owner, statusCode, contentType, body)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IdempotencyComplete indicates an expected call of IdempotencyComplete.
+func (mr *MockStoreMockRecorder) IdempotencyComplete(ctx, userID, key, owner, statusCode, contentType, body any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IdempotencyComplete", reflect.TypeOf((*MockStore)(nil).IdempotencyComplete), ctx, userID, key, owner, statusCode, contentType, body)
+}
+
+// IdempotencyPrune mocks base method.
+func (m *MockStore) IdempotencyPrune(ctx context.Context, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IdempotencyPrune", ctx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IdempotencyPrune indicates an expected call of IdempotencyPrune.
+func (mr *MockStoreMockRecorder) IdempotencyPrune(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IdempotencyPrune", reflect.TypeOf((*MockStore)(nil).IdempotencyPrune), ctx, now)
+}
+
+// IdempotencyRelease mocks base method.
+func (m *MockStore) IdempotencyRelease(ctx context.Context, userID int64, key, owner string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IdempotencyRelease", ctx, userID, key, owner)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IdempotencyRelease indicates an expected call of IdempotencyRelease.
+func (mr *MockStoreMockRecorder) IdempotencyRelease(ctx, userID, key, owner any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IdempotencyRelease", reflect.TypeOf((*MockStore)(nil).IdempotencyRelease), ctx, userID, key, owner)
+}
@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: quota.go
+//
+// Generated by this command:
+//
+//	mockgen -source=quota.go -destination=../../mocks/mock_quota_storage.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUsageStorage is a mock of UsageStorage interface.
+type MockUsageStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockUsageStorageMockRecorder
+	isgomock struct{}
+}
+
+// MockUsageStorageMockRecorder is the mock recorder for MockUsageStorage.
+type MockUsageStorageMockRecorder struct {
+	mock *MockUsageStorage
+}
+
+// NewMockUsageStorage creates a new mock instance.
+func NewMockUsageStorage(ctrl *gomock.Controller) *MockUsageStorage {
+	mock := &MockUsageStorage{ctrl: ctrl}
+	mock.recorder = &MockUsageStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUsageStorage) EXPECT() *MockUsageStorageMockRecorder {
+	return m.recorder
+}
+
+// ShortenedLinkCountActiveByUser mocks base method.
+func (m *MockUsageStorage) ShortenedLinkCountActiveByUser(ctx context.Context, userID int64) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShortenedLinkCountActiveByUser", ctx, userID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ShortenedLinkCountActiveByUser indicates an expected call of ShortenedLinkCountActiveByUser.
+func (mr *MockUsageStorageMockRecorder) ShortenedLinkCountActiveByUser(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShortenedLinkCountActiveByUser", reflect.TypeOf((*MockUsageStorage)(nil).ShortenedLinkCountActiveByUser), ctx, userID)
+}
+
+// UserLockForQuota mocks base method.
+func (m *MockUsageStorage) UserLockForQuota(ctx context.Context, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserLockForQuota", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UserLockForQuota indicates an expected call of UserLockForQuota.
+func (mr *MockUsageStorageMockRecorder) UserLockForQuota(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserLockForQuota", reflect.TypeOf((*MockUsageStorage)(nil).UserLockForQuota), ctx, userID)
+}
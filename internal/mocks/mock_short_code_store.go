@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pool.go
+//
+// Generated by this command:
+//
+//	mockgen -source=pool.go -destination=../../mocks/mock_short_code_store.go -package=mocks -mock_names=Store=MockShortCodeStore
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockShortCodeStore is a mock of Store interface.
+type MockShortCodeStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockShortCodeStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockShortCodeStoreMockRecorder is the mock recorder for MockShortCodeStore.
+type MockShortCodeStoreMockRecorder struct {
+	mock *MockShortCodeStore
+}
+
+// NewMockShortCodeStore creates a new mock instance.
+func NewMockShortCodeStore(ctrl *gomock.Controller) *MockShortCodeStore {
+	mock := &MockShortCodeStore{ctrl: ctrl}
+	mock.recorder = &MockShortCodeStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockShortCodeStore) EXPECT() *MockShortCodeStoreMockRecorder {
+	return m.recorder
+}
+
+// ShortCodeReserve mocks base method.
+func (m *MockShortCodeStore) ShortCodeReserve(ctx context.Context, candidates []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShortCodeReserve", ctx, candidates)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ShortCodeReserve indicates an expected call of ShortCodeReserve.
+func (mr *MockShortCodeStoreMockRecorder) ShortCodeReserve(ctx, candidates any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShortCodeReserve", reflect.TypeOf((*MockShortCodeStore)(nil).ShortCodeReserve), ctx, candidates)
+}
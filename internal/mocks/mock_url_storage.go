@@ -41,6 +41,80 @@ func (m *MockURLStorage) EXPECT() *MockURLStorageMockRecorder {
 	return m.recorder
 }
 
+// AbuseReportCountReporters mocks base method.
+func (m *MockURLStorage) AbuseReportCountReporters(ctx context.Context, linkID int64) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AbuseReportCountReporters", ctx, linkID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AbuseReportCountReporters indicates an expected call of AbuseReportCountReporters.
+func (mr *MockURLStorageMockRecorder) AbuseReportCountReporters(ctx, linkID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AbuseReportCountReporters", reflect.TypeOf((*MockURLStorage)(nil).AbuseReportCountReporters), ctx, linkID)
+}
+
+// AbuseReportCreate mocks base method.
+func (m *MockURLStorage) AbuseReportCreate(ctx context.Context, report models.AbuseReport) (models.AbuseReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AbuseReportCreate", ctx, report)
+	ret0, _ := ret[0].(models.AbuseReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AbuseReportCreate indicates an expected call of AbuseReportCreate.
+func (mr *MockURLStorageMockRecorder) AbuseReportCreate(ctx, report any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AbuseReportCreate", reflect.TypeOf((*MockURLStorage)(nil).AbuseReportCreate), ctx, report)
+}
+
+// AbuseReportGetByLink mocks base method.
+func (m *MockURLStorage) AbuseReportGetByLink(ctx context.Context, linkID int64) ([]models.AbuseReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AbuseReportGetByLink", ctx, linkID)
+	ret0, _ := ret[0].([]models.AbuseReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AbuseReportGetByLink indicates an expected call of AbuseReportGetByLink.
+func (mr *MockURLStorageMockRecorder) AbuseReportGetByLink(ctx, linkID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AbuseReportGetByLink", reflect.TypeOf((*MockURLStorage)(nil).AbuseReportGetByLink), ctx, linkID)
+}
+
+// AbuseReportListPending mocks base method.
+func (m *MockURLStorage) AbuseReportListPending(ctx context.Context) ([]models.AbuseReportSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AbuseReportListPending", ctx)
+	ret0, _ := ret[0].([]models.AbuseReportSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AbuseReportListPending indicates an expected call of AbuseReportListPending.
+func (mr *MockURLStorageMockRecorder) AbuseReportListPending(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AbuseReportListPending", reflect.TypeOf((*MockURLStorage)(nil).AbuseReportListPending), ctx)
+}
+
+// AbuseReportResolve mocks base method.
+func (m *MockURLStorage) AbuseReportResolve(ctx context.Context, linkID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AbuseReportResolve", ctx, linkID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AbuseReportResolve indicates an expected call of AbuseReportResolve.
+func (mr *MockURLStorageMockRecorder) AbuseReportResolve(ctx, linkID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AbuseReportResolve", reflect.TypeOf((*MockURLStorage)(nil).AbuseReportResolve), ctx, linkID)
+}
+
 // Ping mocks base method.
 func (m *MockURLStorage) Ping(ctx context.Context) error {
 	m.ctrl.T.Helper()
@@ -71,17 +145,17 @@ func (mr *MockURLStorageMockRecorder) ShortenedLinkBatchCreate(ctx, urls any) *g
 }
 
 // ShortenedLinkBatchDelete mocks base method.
-func (m *MockURLStorage) ShortenedLinkBatchDelete(ctx context.Context, userID int64, shortCode []string) error {
+func (m *MockURLStorage) ShortenedLinkBatchDelete(ctx context.Context, id int64, shortCode []string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ShortenedLinkBatchDelete", ctx, userID, shortCode)
+	ret := m.ctrl.Call(m, "ShortenedLinkBatchDelete", ctx, id, shortCode)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // ShortenedLinkBatchDelete indicates an expected call of ShortenedLinkBatchDelete.
-func (mr *MockURLStorageMockRecorder) ShortenedLinkBatchDelete(ctx, userID, shortCode any) *gomock.Call {
+func (mr *MockURLStorageMockRecorder) ShortenedLinkBatchDelete(ctx, id, shortCode any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShortenedLinkBatchDelete", reflect.TypeOf((*MockURLStorage)(nil).ShortenedLinkBatchDelete), ctx, userID, shortCode)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShortenedLinkBatchDelete", reflect.TypeOf((*MockURLStorage)(nil).ShortenedLinkBatchDelete), ctx, id, shortCode)
 }
 
 // ShortenedLinkBatchExists mocks base method.
@@ -159,6 +233,138 @@ func (mr *MockURLStorageMockRecorder) ShortenedLinkGetByShortKey(ctx, shortKey a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShortenedLinkGetByShortKey", reflect.TypeOf((*MockURLStorage)(nil).ShortenedLinkGetByShortKey), ctx, shortKey)
 }
 
+// ShortenedLinkSetModeration mocks base method.
+func (m *MockURLStorage) ShortenedLinkSetModeration(ctx context.Context, linkID int64, status string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShortenedLinkSetModeration", ctx, linkID, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ShortenedLinkSetModeration indicates an expected call of ShortenedLinkSetModeration.
+func (mr *MockURLStorageMockRecorder) ShortenedLinkSetModeration(ctx, linkID, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShortenedLinkSetModeration", reflect.TypeOf((*MockURLStorage)(nil).ShortenedLinkSetModeration), ctx, linkID, status)
+}
+
+// ShortenedLinkUpdate mocks base method.
+func (m *MockURLStorage) ShortenedLinkUpdate(ctx context.Context, url models.ShortenedLink) (models.ShortenedLink, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShortenedLinkUpdate", ctx, url)
+	ret0, _ := ret[0].(models.ShortenedLink)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ShortenedLinkUpdate indicates an expected call of ShortenedLinkUpdate.
+func (mr *MockURLStorageMockRecorder) ShortenedLinkUpdate(ctx, url any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShortenedLinkUpdate", reflect.TypeOf((*MockURLStorage)(nil).ShortenedLinkUpdate), ctx, url)
+}
+
+// ShortenedLinkVariantAddClicks mocks base method.
+func (m *MockURLStorage) ShortenedLinkVariantAddClicks(ctx context.Context, clicks []models.VariantClicks) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShortenedLinkVariantAddClicks", ctx, clicks)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ShortenedLinkVariantAddClicks indicates an expected call of ShortenedLinkVariantAddClicks.
+func (mr *MockURLStorageMockRecorder) ShortenedLinkVariantAddClicks(ctx, clicks any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShortenedLinkVariantAddClicks", reflect.TypeOf((*MockURLStorage)(nil).ShortenedLinkVariantAddClicks), ctx, clicks)
+}
+
+// ShortenedLinkVariantStats mocks base method.
+func (m *MockURLStorage) ShortenedLinkVariantStats(ctx context.Context, linkID int64) (map[string]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShortenedLinkVariantStats", ctx, linkID)
+	ret0, _ := ret[0].(map[string]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ShortenedLinkVariantStats indicates an expected call of ShortenedLinkVariantStats.
+func (mr *MockURLStorageMockRecorder) ShortenedLinkVariantStats(ctx, linkID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShortenedLinkVariantStats", reflect.TypeOf((*MockURLStorage)(nil).ShortenedLinkVariantStats), ctx, linkID)
+}
+
+// UTMTemplateCreate mocks base method.
+func (m *MockURLStorage) UTMTemplateCreate(ctx context.Context, tpl models.UTMTemplate) (models.UTMTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UTMTemplateCreate", ctx, tpl)
+	ret0, _ := ret[0].(models.UTMTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UTMTemplateCreate indicates an expected call of UTMTemplateCreate.
+func (mr *MockURLStorageMockRecorder) UTMTemplateCreate(ctx, tpl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UTMTemplateCreate", reflect.TypeOf((*MockURLStorage)(nil).UTMTemplateCreate), ctx, tpl)
+}
+
+// UTMTemplateDelete mocks base method.
+func (m *MockURLStorage) UTMTemplateDelete(ctx context.Context, userID int64, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UTMTemplateDelete", ctx, userID, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UTMTemplateDelete indicates an expected call of UTMTemplateDelete.
+func (mr *MockURLStorageMockRecorder) UTMTemplateDelete(ctx, userID, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UTMTemplateDelete", reflect.TypeOf((*MockURLStorage)(nil).UTMTemplateDelete), ctx, userID, name)
+}
+
+// UTMTemplateGetBatchByUser mocks base method.
+func (m *MockURLStorage) UTMTemplateGetBatchByUser(ctx context.Context, userID int64) ([]models.UTMTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UTMTemplateGetBatchByUser", ctx, userID)
+	ret0, _ := ret[0].([]models.UTMTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UTMTemplateGetBatchByUser indicates an expected call of UTMTemplateGetBatchByUser.
+func (mr *MockURLStorageMockRecorder) UTMTemplateGetBatchByUser(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UTMTemplateGetBatchByUser", reflect.TypeOf((*MockURLStorage)(nil).UTMTemplateGetBatchByUser), ctx, userID)
+}
+
+// UTMTemplateGetByID mocks base method.
+func (m *MockURLStorage) UTMTemplateGetByID(ctx context.Context, id int64) (models.UTMTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UTMTemplateGetByID", ctx, id)
+	ret0, _ := ret[0].(models.UTMTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UTMTemplateGetByID indicates an expected call of UTMTemplateGetByID.
+func (mr *MockURLStorageMockRecorder) UTMTemplateGetByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UTMTemplateGetByID", reflect.TypeOf((*MockURLStorage)(nil).UTMTemplateGetByID), ctx, id)
+}
+
+// UTMTemplateGetByName mocks base method.
+func (m *MockURLStorage) UTMTemplateGetByName(ctx context.Context, userID int64, name string) (models.UTMTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UTMTemplateGetByName", ctx, userID, name)
+	ret0, _ := ret[0].(models.UTMTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UTMTemplateGetByName indicates an expected call of UTMTemplateGetByName.
+func (mr *MockURLStorageMockRecorder) UTMTemplateGetByName(ctx, userID, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UTMTemplateGetByName", reflect.TypeOf((*MockURLStorage)(nil).UTMTemplateGetByName), ctx, userID, name)
+}
+
 // WithinTx mocks base method.
 func (m *MockURLStorage) WithinTx(ctx context.Context, fn func(context.Context) error) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"time"
+	"urlshortener/internal/domain/models"
+)
+
+type (
+	AbuseReportDB struct {
+		ID        int64     `db:"id"`
+		LinkID    int64     `db:"url_id"`
+		Reason    string    `db:"reason"`
+		Details   string    `db:"details"`
+		Reporter  string    `db:"reporter"`
+		Resolved  bool      `db:"resolved"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+)
+
+func AbuseReportDBToDomain(r AbuseReportDB) models.AbuseReport {
+	return models.AbuseReport{
+		ID:        r.ID,
+		LinkID:    r.LinkID,
+		Reason:    r.Reason,
+		Details:   r.Details,
+		Reporter:  r.Reporter,
+		Resolved:  r.Resolved,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func AbuseReportDBFromDomain(r models.AbuseReport) AbuseReportDB {
+	return AbuseReportDB{
+		ID:        r.ID,
+		LinkID:    r.LinkID,
+		Reason:    r.Reason,
+		Details:   r.Details,
+		Reporter:  r.Reporter,
+		Resolved:  r.Resolved,
+		CreatedAt: r.CreatedAt,
+	}
+}
@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"time"
+	"urlshortener/internal/domain/models"
+)
+
+type (
+	IdempotencyRecordDB struct {
+		UserID      int64     `db:"user_id"`
+		Key         string    `db:"key"`
+		Fingerprint string    `db:"fingerprint"`
+		Owner       string    `db:"owner"`
+		StatusCode  int       `db:"status_code"`
+		ContentType string    `db:"content_type"`
+		Body        []byte    `db:"body"`
+		CreatedAt   time.Time `db:"created_at"`
+		ExpiresAt   time.Time `db:"expires_at"`
+	}
+)
+
+func IdempotencyRecordDBToDomain(r IdempotencyRecordDB) models.IdempotencyRecord {
+	return models.IdempotencyRecord{
+		UserID:      r.UserID,
+		Key:         r.Key,
+		Fingerprint: r.Fingerprint,
+		Owner:       r.Owner,
+		StatusCode:  r.StatusCode,
+		ContentType: r.ContentType,
+		Body:        r.Body,
+		CreatedAt:   r.CreatedAt,
+		ExpiresAt:   r.ExpiresAt,
+	}
+}
+
+func IdempotencyRecordDBFromDomain(r models.IdempotencyRecord) IdempotencyRecordDB {
+	return IdempotencyRecordDB{
+		UserID:      r.UserID,
+		Key:         r.Key,
+		Fingerprint: r.Fingerprint,
+		Owner:       r.Owner,
+		StatusCode:  r.StatusCode,
+		ContentType: r.ContentType,
+		Body:        r.Body,
+		CreatedAt:   r.CreatedAt,
+		ExpiresAt:   r.ExpiresAt,
+	}
+}
@@ -1,6 +1,10 @@
 package dto
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 	"urlshortener/internal/domain/models"
 )
@@ -8,36 +12,218 @@ import (
 // DTO БД для работы с БД
 type (
 	ShortenedLinkDB struct {
-		ID          int64     `db:"id"`
-		OriginalURL string    `db:"original_url"`
-		ShortCode   string    `db:"short_key"`
-		UserID      int64     `db:"user_id"`
-		DeletedFlag bool      `db:"is_deleted"`
-		CreatedAt   time.Time `db:"created_at"`
-		DeletedAt   time.Time `db:"deleted_at"`
+		ID             int64     `db:"id"`
+		OriginalURL    string    `db:"original_url"`
+		ShortCode      string    `db:"short_key"`
+		UserID         int64     `db:"user_id"`
+		DeletedFlag    bool      `db:"is_deleted"`
+		CreatedAt      time.Time `db:"created_at"`
+		DeletedAt      time.Time `db:"deleted_at"`
+		Title          string    `db:"title"`
+		RedirectStatus int       `db:"redirect_status"`
+		PassQuery      bool      `db:"pass_query"`
+		PassPath       bool      `db:"pass_path"`
+		UTMTemplateID  int64     `db:"utm_template_id"`
+
+		RedirectRules  RedirectRulesDB `db:"redirect_rules"`
+		Variants       LinkVariantsDB  `db:"variants"`
+		StickyVariants bool            `db:"sticky_variants"`
+
+		ActiveFrom  sql.NullTime `db:"active_from"`
+		ActiveUntil sql.NullTime `db:"active_until"`
+
+		Moderation string `db:"moderation"`
+	}
+
+	RedirectRuleDB struct {
+		Type      string `json:"type"`
+		Header    string `json:"header,omitempty"`
+		Value     string `json:"value,omitempty"`
+		TargetURL string `json:"target_url"`
+	}
+
+	// RedirectRulesDB хранится в колонке JSONB
+	RedirectRulesDB []RedirectRuleDB
+
+	LinkVariantDB struct {
+		Name      string `json:"name"`
+		TargetURL string `json:"target_url"`
+		Weight    int    `json:"weight"`
 	}
+
+	// LinkVariantsDB хранится в колонке JSONB, счетчики переходов - в отдельной таблице
+	LinkVariantsDB []LinkVariantDB
 )
 
+// Value сериализует правила в JSON, пустой список хранится как []
+func (r RedirectRulesDB) Value() (driver.Value, error) {
+	return jsonListValue(len(r), r)
+}
+
+// Scan читает правила из JSON
+func (r *RedirectRulesDB) Scan(src interface{}) error {
+	var rules RedirectRulesDB
+	if err := scanJSON(src, &rules); err != nil {
+		return fmt.Errorf("failed to scan redirect rules: %w", err)
+	}
+	if len(rules) == 0 {
+		rules = nil
+	}
+	*r = rules
+	return nil
+}
+
+// Value сериализует варианты в JSON, пустой список хранится как []
+func (v LinkVariantsDB) Value() (driver.Value, error) {
+	return jsonListValue(len(v), v)
+}
+
+// Scan читает варианты из JSON
+func (v *LinkVariantsDB) Scan(src interface{}) error {
+	var variants LinkVariantsDB
+	if err := scanJSON(src, &variants); err != nil {
+		return fmt.Errorf("failed to scan variants: %w", err)
+	}
+	if len(variants) == 0 {
+		variants = nil
+	}
+	*v = variants
+	return nil
+}
+
+func jsonListValue(length int, list interface{}) (driver.Value, error) {
+	if length == 0 {
+		return "[]", nil
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func scanJSON(src interface{}, dest interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("unsupported type %T", src)
+	}
+}
+
+func LinkVariantsDBToDomain(variants LinkVariantsDB) []models.LinkVariant {
+	if len(variants) == 0 {
+		return nil
+	}
+	result := make([]models.LinkVariant, len(variants))
+	for i, variant := range variants {
+		result[i] = models.LinkVariant{
+			Name:      variant.Name,
+			TargetURL: variant.TargetURL,
+			Weight:    variant.Weight,
+		}
+	}
+	return result
+}
+
+func LinkVariantsDBFromDomain(variants []models.LinkVariant) LinkVariantsDB {
+	if len(variants) == 0 {
+		return nil
+	}
+	result := make(LinkVariantsDB, len(variants))
+	for i, variant := range variants {
+		result[i] = LinkVariantDB{
+			Name:      variant.Name,
+			TargetURL: variant.TargetURL,
+			Weight:    variant.Weight,
+		}
+	}
+	return result
+}
+
+func RedirectRulesDBToDomain(rules RedirectRulesDB) []models.RedirectRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	result := make([]models.RedirectRule, len(rules))
+	for i, rule := range rules {
+		result[i] = models.RedirectRule{
+			Type:      rule.Type,
+			Header:    rule.Header,
+			Value:     rule.Value,
+			TargetURL: rule.TargetURL,
+		}
+	}
+	return result
+}
+
+func RedirectRulesDBFromDomain(rules []models.RedirectRule) RedirectRulesDB {
+	if len(rules) == 0 {
+		return nil
+	}
+	result := make(RedirectRulesDB, len(rules))
+	for i, rule := range rules {
+		result[i] = RedirectRuleDB{
+			Type:      rule.Type,
+			Header:    rule.Header,
+			Value:     rule.Value,
+			TargetURL: rule.TargetURL,
+		}
+	}
+	return result
+}
+
 func ShortenedLinkDBToDomain(domain ShortenedLinkDB) models.ShortenedLink {
 	return models.ShortenedLink{
-		ID:          domain.ID,
-		OriginalURL: domain.OriginalURL,
-		ShortCode:   domain.ShortCode,
-		UserID:      domain.UserID,
-		DeletedFlag: domain.DeletedFlag,
-		CreatedAt:   domain.CreatedAt,
-		DeletedAt:   domain.DeletedAt,
+		ID:             domain.ID,
+		OriginalURL:    domain.OriginalURL,
+		ShortCode:      domain.ShortCode,
+		UserID:         domain.UserID,
+		DeletedFlag:    domain.DeletedFlag,
+		CreatedAt:      domain.CreatedAt,
+		DeletedAt:      domain.DeletedAt,
+		Title:          domain.Title,
+		RedirectStatus: domain.RedirectStatus,
+		PassQuery:      domain.PassQuery,
+		PassPath:       domain.PassPath,
+		UTMTemplateID:  domain.UTMTemplateID,
+		RedirectRules:  RedirectRulesDBToDomain(domain.RedirectRules),
+		Variants:       LinkVariantsDBToDomain(domain.Variants),
+		StickyVariants: domain.StickyVariants,
+		ActiveFrom:     domain.ActiveFrom.Time,
+		ActiveUntil:    domain.ActiveUntil.Time,
+		Moderation:     domain.Moderation,
 	}
 }
 
 func ShortenedLinkDBFromDomain(db models.ShortenedLink) ShortenedLinkDB {
 	return ShortenedLinkDB{
-		ID:          db.ID,
-		OriginalURL: db.OriginalURL,
-		ShortCode:   db.ShortCode,
-		UserID:      db.UserID,
-		DeletedFlag: db.DeletedFlag,
-		CreatedAt:   db.CreatedAt,
-		DeletedAt:   db.DeletedAt,
+		ID:             db.ID,
+		OriginalURL:    db.OriginalURL,
+		ShortCode:      db.ShortCode,
+		UserID:         db.UserID,
+		DeletedFlag:    db.DeletedFlag,
+		CreatedAt:      db.CreatedAt,
+		DeletedAt:      db.DeletedAt,
+		Title:          db.Title,
+		RedirectStatus: db.RedirectStatus,
+		PassQuery:      db.PassQuery,
+		PassPath:       db.PassPath,
+		UTMTemplateID:  db.UTMTemplateID,
+		RedirectRules:  RedirectRulesDBFromDomain(db.RedirectRules),
+		Variants:       LinkVariantsDBFromDomain(db.Variants),
+		StickyVariants: db.StickyVariants,
+		ActiveFrom:     NullTime(db.ActiveFrom),
+		ActiveUntil:    NullTime(db.ActiveUntil),
+		Moderation:     db.Moderation,
 	}
 }
+
+// NullTime хранит нулевое время как NULL
+func NullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"time"
+	"urlshortener/internal/domain/models"
+)
+
+type (
+	UTMTemplateDB struct {
+		ID        int64     `db:"id"`
+		UserID    int64     `db:"user_id"`
+		Name      string    `db:"name"`
+		Source    string    `db:"utm_source"`
+		Medium    string    `db:"utm_medium"`
+		Campaign  string    `db:"utm_campaign"`
+		Term      string    `db:"utm_term"`
+		Content   string    `db:"utm_content"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+)
+
+func UTMTemplateDBToDomain(t UTMTemplateDB) models.UTMTemplate {
+	return models.UTMTemplate{
+		ID:        t.ID,
+		UserID:    t.UserID,
+		Name:      t.Name,
+		Source:    t.Source,
+		Medium:    t.Medium,
+		Campaign:  t.Campaign,
+		Term:      t.Term,
+		Content:   t.Content,
+		CreatedAt: t.CreatedAt,
+	}
+}
+
+func UTMTemplateDBFromDomain(t models.UTMTemplate) UTMTemplateDB {
+	return UTMTemplateDB{
+		ID:        t.ID,
+		UserID:    t.UserID,
+		Name:      t.Name,
+		Source:    t.Source,
+		Medium:    t.Medium,
+		Campaign:  t.Campaign,
+		Term:      t.Term,
+		Content:   t.Content,
+		CreatedAt: t.CreatedAt,
+	}
+}
@@ -0,0 +1,200 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"time"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/repository/dto"
+)
+
+func (m *InmemoryStorage) AbuseReportCreate(ctx context.Context, report models.AbuseReport) (models.AbuseReport, error) {
+	if err := ctx.Err(); err != nil {
+		return models.AbuseReport{}, models.ErrInvalidData
+	}
+
+	if report.LinkID <= 0 || report.Reason == "" {
+		return models.AbuseReport{}, models.ErrInvalidData
+	}
+
+	tx, unlock := m.lock(ctx)
+	defer unlock()
+
+	// Как и внешний ключ в Postgres, жалоба возможна только на существующую ссылку
+	if _, exists := m.linkByID(report.LinkID); !exists {
+		return models.AbuseReport{}, models.ErrUnfound
+	}
+
+	txSave(tx, &m.lastAbuseReportID)
+	m.lastAbuseReportID++
+	reportDB := dto.AbuseReportDBFromDomain(report)
+	reportDB.ID = m.lastAbuseReportID
+	reportDB.Resolved = false
+	if reportDB.CreatedAt.IsZero() {
+		reportDB.CreatedAt = time.Now()
+	}
+
+	txSet(tx, m.abuseReports, reportDB.ID, reportDB)
+	return dto.AbuseReportDBToDomain(reportDB), nil
+}
+
+func (m *InmemoryStorage) AbuseReportCountReporters(ctx context.Context, linkID int64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, models.ErrInvalidData
+	}
+
+	if linkID <= 0 {
+		return 0, models.ErrInvalidData
+	}
+
+	unlock := m.rlock(ctx)
+	defer unlock()
+
+	reporters := make(map[string]bool)
+	for _, report := range m.abuseReports {
+		if report.LinkID == linkID && !report.Resolved {
+			reporters[report.Reporter] = true
+		}
+	}
+
+	return len(reporters), nil
+}
+
+func (m *InmemoryStorage) AbuseReportGetByLink(ctx context.Context, linkID int64) ([]models.AbuseReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, models.ErrInvalidData
+	}
+
+	if linkID <= 0 {
+		return nil, models.ErrInvalidData
+	}
+
+	unlock := m.rlock(ctx)
+	defer unlock()
+
+	var reports []models.AbuseReport
+	for _, report := range m.abuseReports {
+		if report.LinkID == linkID {
+			reports = append(reports, dto.AbuseReportDBToDomain(report))
+		}
+	}
+
+	// Порядок как в Postgres: новые первыми
+	sort.Slice(reports, func(i, j int) bool {
+		if !reports[i].CreatedAt.Equal(reports[j].CreatedAt) {
+			return reports[i].CreatedAt.After(reports[j].CreatedAt)
+		}
+		return reports[i].ID > reports[j].ID
+	})
+
+	return reports, nil
+}
+
+func (m *InmemoryStorage) AbuseReportListPending(ctx context.Context) ([]models.AbuseReportSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, models.ErrInvalidData
+	}
+
+	unlock := m.rlock(ctx)
+	defer unlock()
+
+	byLink := make(map[int64]*models.AbuseReportSummary)
+	reporters := make(map[int64]map[string]bool)
+	for _, report := range m.abuseReports {
+		if report.Resolved {
+			continue
+		}
+
+		summary, exists := byLink[report.LinkID]
+		if !exists {
+			link, ok := m.linkByID(report.LinkID)
+			if !ok {
+				continue
+			}
+			summary = &models.AbuseReportSummary{Link: dto.ShortenedLinkDBToDomain(link)}
+			byLink[report.LinkID] = summary
+			reporters[report.LinkID] = make(map[string]bool)
+		}
+
+		summary.Reports++
+		reporters[report.LinkID][report.Reporter] = true
+		if report.CreatedAt.After(summary.LastReportAt) {
+			summary.LastReportAt = report.CreatedAt
+		}
+	}
+
+	summaries := make([]models.AbuseReportSummary, 0, len(byLink))
+	for linkID, summary := range byLink {
+		summary.Reporters = len(reporters[linkID])
+		summaries = append(summaries, *summary)
+	}
+
+	// Порядок как в Postgres: карантин, число отправителей, свежесть последней жалобы
+	sort.Slice(summaries, func(i, j int) bool {
+		qi := summaries[i].Link.Moderation == models.ModerationQuarantined
+		qj := summaries[j].Link.Moderation == models.ModerationQuarantined
+		if qi != qj {
+			return qi
+		}
+		if summaries[i].Reporters != summaries[j].Reporters {
+			return summaries[i].Reporters > summaries[j].Reporters
+		}
+		return summaries[i].LastReportAt.After(summaries[j].LastReportAt)
+	})
+
+	return summaries, nil
+}
+
+func (m *InmemoryStorage) AbuseReportResolve(ctx context.Context, linkID int64) error {
+	if err := ctx.Err(); err != nil {
+		return models.ErrInvalidData
+	}
+
+	if linkID <= 0 {
+		return models.ErrInvalidData
+	}
+
+	tx, unlock := m.lock(ctx)
+	defer unlock()
+
+	for id, report := range m.abuseReports {
+		if report.LinkID == linkID && !report.Resolved {
+			report.Resolved = true
+			txSet(tx, m.abuseReports, id, report)
+		}
+	}
+
+	return nil
+}
+
+func (m *InmemoryStorage) ShortenedLinkSetModeration(ctx context.Context, linkID int64, status string) error {
+	if err := ctx.Err(); err != nil {
+		return models.ErrInvalidData
+	}
+
+	if linkID <= 0 {
+		return models.ErrInvalidData
+	}
+
+	tx, unlock := m.lock(ctx)
+	defer unlock()
+
+	link, exists := m.linkByID(linkID)
+	if !exists {
+		return models.ErrUnfound
+	}
+
+	link.Moderation = status
+	txSet(tx, m.data, link.ShortCode, link)
+	return nil
+}
+
+// linkByID ищет ссылку по id перебором, вызывается под блокировкой
+func (m *InmemoryStorage) linkByID(id int64) (dto.ShortenedLinkDB, bool) {
+	for _, link := range m.data {
+		if link.ID == id {
+			return link, true
+		}
+	}
+	return dto.ShortenedLinkDB{}, false
+}
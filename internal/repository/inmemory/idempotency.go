@@ -0,0 +1,109 @@
+package inmemory
+
+import (
+	"context"
+	"time"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/repository/dto"
+)
+
+// idempotencyKeyID ключи идемпотентности у каждого пользователя свои
+type idempotencyKeyID struct {
+	userID int64
+	key    string
+}
+
+func (m *InmemoryStorage) IdempotencyBegin(ctx context.Context, record models.IdempotencyRecord, staleBefore time.Time) (models.IdempotencyRecord, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return models.IdempotencyRecord{}, false, models.ErrInvalidData
+	}
+
+	if record.UserID <= 0 || record.Key == "" || record.Fingerprint == "" || record.Owner == "" {
+		return models.IdempotencyRecord{}, false, models.ErrInvalidData
+	}
+
+	tx, unlock := m.lock(ctx)
+	defer unlock()
+
+	id := idempotencyKeyID{userID: record.UserID, key: record.Key}
+	if existing, exists := m.idempotencyKeys[id]; exists {
+		expired := !existing.ExpiresAt.After(record.CreatedAt)
+		abandoned := existing.StatusCode == 0 && !existing.CreatedAt.After(staleBefore)
+		if !expired && !abandoned {
+			return dto.IdempotencyRecordDBToDomain(existing), false, nil
+		}
+	}
+
+	recordDB := dto.IdempotencyRecordDBFromDomain(record)
+	recordDB.StatusCode = 0
+	recordDB.ContentType = ""
+	recordDB.Body = nil
+	txSet(tx, m.idempotencyKeys, id, recordDB)
+
+	return dto.IdempotencyRecordDBToDomain(recordDB), true, nil
+}
+
+func (m *InmemoryStorage) IdempotencyComplete(ctx context.Context, userID int64, key, owner string, statusCode int, contentType string, body []byte) error {
+	if err := ctx.Err(); err != nil {
+		return models.ErrInvalidData
+	}
+
+	if userID <= 0 || key == "" || owner == "" || statusCode <= 0 {
+		return models.ErrInvalidData
+	}
+
+	tx, unlock := m.lock(ctx)
+	defer unlock()
+
+	id := idempotencyKeyID{userID: userID, key: key}
+	recordDB, exists := m.idempotencyKeys[id]
+	if !exists || recordDB.Owner != owner || recordDB.StatusCode != 0 {
+		return models.ErrUnfound
+	}
+
+	recordDB.StatusCode = statusCode
+	recordDB.ContentType = contentType
+	recordDB.Body = append([]byte(nil), body...)
+	txSet(tx, m.idempotencyKeys, id, recordDB)
+
+	return nil
+}
+
+func (m *InmemoryStorage) IdempotencyRelease(ctx context.Context, userID int64, key, owner string) error {
+	if err := ctx.Err(); err != nil {
+		return models.ErrInvalidData
+	}
+
+	if userID <= 0 || key == "" || owner == "" {
+		return models.ErrInvalidData
+	}
+
+	tx, unlock := m.lock(ctx)
+	defer unlock()
+
+	id := idempotencyKeyID{userID: userID, key: key}
+	if recordDB, exists := m.idempotencyKeys[id]; exists && recordDB.Owner == owner && recordDB.StatusCode == 0 {
+		txDelete(tx, m.idempotencyKeys, id)
+	}
+
+	return nil
+}
+
+func (m *InmemoryStorage) IdempotencyPrune(ctx context.Context, now time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, models.ErrInvalidData
+	}
+
+	tx, unlock := m.lock(ctx)
+	defer unlock()
+
+	var pruned int64
+	for id, recordDB := range m.idempotencyKeys {
+		if !recordDB.ExpiresAt.After(now) {
+			txDelete(tx, m.idempotencyKeys, id)
+			pruned++
+		}
+	}
+
+	return pruned, nil
+}
@@ -0,0 +1,57 @@
+package inmemory
+
+import (
+	"context"
+	"testing"
+	"time"
+	"urlshortener/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInmemoryStorage_Idempotency_StaleTakeover(t *testing.T) {
+	ctx := context.Background()
+	storage := NewStorage()
+	start := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	first := models.IdempotencyRecord{
+		UserID:      1,
+		Key:         "retry-1",
+		Fingerprint: "fingerprint",
+		Owner:       "first",
+		CreatedAt:   start,
+		ExpiresAt:   start.Add(time.Hour),
+	}
+	_, created, err := storage.IdempotencyBegin(ctx, first, start.Add(-time.Minute))
+	require.NoError(t, err)
+	require.True(t, created)
+
+	// Первый запрос завис, ключ занимают заново как брошенный
+	second := first
+	second.Owner = "second"
+	second.CreatedAt = start.Add(2 * time.Minute)
+	second.ExpiresAt = second.CreatedAt.Add(time.Hour)
+	_, created, err = storage.IdempotencyBegin(ctx, second, second.CreatedAt.Add(-time.Minute))
+	require.NoError(t, err)
+	require.True(t, created)
+
+	// Запоздавший первый запрос не трогает запись второго
+	err = storage.IdempotencyComplete(ctx, 1, "retry-1", "first", 500, "", nil)
+	assert.ErrorIs(t, err, models.ErrUnfound)
+	require.NoError(t, storage.IdempotencyRelease(ctx, 1, "retry-1", "first"))
+
+	existing, created, err := storage.IdempotencyBegin(ctx, first, start.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "second", existing.Owner)
+	assert.False(t, existing.Completed())
+
+	require.NoError(t, storage.IdempotencyComplete(ctx, 1, "retry-1", "second", 201, "application/json", []byte(`{}`)))
+
+	existing, created, err = storage.IdempotencyBegin(ctx, first, start.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, 201, existing.StatusCode)
+	assert.Equal(t, []byte(`{}`), existing.Body)
+}
@@ -22,8 +22,18 @@ type InmemoryStorage struct {
 	createdAtIndex map[string][]string
 	deletedAtIndex map[string][]string
 
-	lastURLID  int64
-	lastUserID int64
+	utmTemplates  map[int64]dto.UTMTemplateDB
+	variantClicks map[int64]map[string]int64 // id ссылки -> вариант -> переходы
+	abuseReports  map[int64]dto.AbuseReportDB
+
+	idempotencyKeys map[idempotencyKeyID]dto.IdempotencyRecordDB
+	reservedCodes   map[string]bool // коды, выданные пулу, но, возможно, еще не занятые ссылками
+
+	lastURLID         int64
+	lastUserID        int64
+	lastUTMTemplateID int64
+	lastAbuseReportID int64
+	nextCodeID        int64 // счетчик последовательных кодов
 }
 
 func NewStorage() *InmemoryStorage {
@@ -35,6 +45,11 @@ func NewStorage() *InmemoryStorage {
 		deletedAtIndex:   make(map[string][]string),
 		userURLsIndex:    make(map[int64][]string),
 		urlsIsDeleted:    make(map[string]bool),
+		utmTemplates:     make(map[int64]dto.UTMTemplateDB),
+		variantClicks:    make(map[int64]map[string]int64),
+		abuseReports:     make(map[int64]dto.AbuseReportDB),
+		idempotencyKeys:  make(map[idempotencyKeyID]dto.IdempotencyRecordDB),
+		reservedCodes:    make(map[string]bool),
 		lastURLID:        0,
 		lastUserID:       0,
 	}
@@ -49,9 +64,17 @@ func (m *InmemoryStorage) Close() error {
 	clear(m.originalURLIndex)
 	clear(m.createdAtIndex)
 	clear(m.userURLsIndex)
+	clear(m.utmTemplates)
+	clear(m.variantClicks)
+	clear(m.abuseReports)
+	clear(m.idempotencyKeys)
+	clear(m.reservedCodes)
 
 	m.lastURLID = 0
 	m.lastUserID = 0
+	m.lastUTMTemplateID = 0
+	m.lastAbuseReportID = 0
+	m.nextCodeID = 0
 
 	return nil
 }
@@ -66,8 +89,8 @@ func (m *InmemoryStorage) ShortenedLinkCreate(ctx context.Context, url models.Sh
 		return models.ShortenedLink{}, models.ErrInvalidData
 	}
 
-	m.rwmu.Lock()
-	defer m.rwmu.Unlock()
+	tx, unlock := m.lock(ctx)
+	defer unlock()
 
 	if existingURL, exists := m.data[url.ShortCode]; exists {
 		if existingURL.OriginalURL == url.OriginalURL {
@@ -82,20 +105,25 @@ func (m *InmemoryStorage) ShortenedLinkCreate(ctx context.Context, url models.Sh
 		}
 	}
 
+	txSave(tx, &m.lastURLID)
 	m.lastURLID++
 	urlDB := dto.ShortenedLinkDBFromDomain(url)
 	urlDB.ID = m.lastURLID
+	// Как и в Postgres, ссылка на несуществующий UTM-шаблон не сохраняется
+	if _, ok := m.utmTemplates[urlDB.UTMTemplateID]; !ok {
+		urlDB.UTMTemplateID = 0
+	}
 	if urlDB.CreatedAt.IsZero() {
 		urlDB.CreatedAt = time.Now()
 	}
 
 	// Добавляем во все индексы
-	m.data[urlDB.ShortCode] = urlDB
-	m.originalURLIndex[urlDB.OriginalURL] = urlDB.ShortCode
+	txSet(tx, m.data, urlDB.ShortCode, urlDB)
+	txSet(tx, m.originalURLIndex, urlDB.OriginalURL, urlDB.ShortCode)
 
 	dateKey := urlDB.CreatedAt.Format("2006-01-02")
-	m.createdAtIndex[dateKey] = append(m.createdAtIndex[dateKey], urlDB.ShortCode)
-	m.userURLsIndex[urlDB.UserID] = append(m.userURLsIndex[urlDB.UserID], urlDB.ShortCode)
+	txSet(tx, m.createdAtIndex, dateKey, append(m.createdAtIndex[dateKey], urlDB.ShortCode))
+	txSet(tx, m.userURLsIndex, urlDB.UserID, append(m.userURLsIndex[urlDB.UserID], urlDB.ShortCode))
 
 	return dto.ShortenedLinkDBToDomain(urlDB), nil
 }
@@ -109,19 +137,16 @@ func (m *InmemoryStorage) ShortenedLinkGetByShortKey(ctx context.Context, shortK
 		return models.ShortenedLink{}, models.ErrInvalidData
 	}
 
-	m.rwmu.RLock()
-	defer m.rwmu.RUnlock()
+	unlock := m.rlock(ctx)
+	defer unlock()
 
+	// Как и в Postgres, возвращаем в том числе удаленные ссылки,
+	// флаг удаления проверяет сервисный слой
 	url, exists := m.data[shortKey]
 	if !exists {
 		return models.ShortenedLink{}, models.ErrUnfound
 	}
 
-	// Проверяем, не удален ли URL
-	if url.DeletedFlag {
-		return models.ShortenedLink{}, models.ErrGone
-	}
-
 	return dto.ShortenedLinkDBToDomain(url), nil
 }
 
@@ -134,8 +159,8 @@ func (m *InmemoryStorage) ShortenedLinkGetByOriginalURL(ctx context.Context, ori
 		return models.ShortenedLink{}, models.ErrInvalidData
 	}
 
-	m.rwmu.RLock()
-	defer m.rwmu.RUnlock()
+	unlock := m.rlock(ctx)
+	defer unlock()
 
 	shortCode, exists := m.originalURLIndex[originalURL]
 	if !exists {
@@ -158,50 +183,147 @@ func (m *InmemoryStorage) ShortenedLinkBatchCreate(ctx context.Context, urls []m
 		return nil, models.ErrInvalidData
 	}
 
-	m.rwmu.Lock()
-	defer m.rwmu.Unlock()
-
-	result := make([]models.ShortenedLink, 0, len(urls))
-	for _, url := range urls {
-		if url.ShortCode == "" || url.OriginalURL == "" || url.UserID <= 0 {
-			return nil, models.ErrInvalidData
-		}
+	// Как и в Postgres, пакет сохраняется целиком или не сохраняется вовсе
+	var result []models.ShortenedLink
+	err := m.WithinTx(ctx, func(ctx context.Context) error {
+		tx, unlock := m.lock(ctx)
+		defer unlock()
 
-		// Проверяем конфликты
-		if existingURL, exists := m.data[url.ShortCode]; exists {
-			if existingURL.OriginalURL != url.OriginalURL {
-				return nil, models.ErrConflict
+		result = make([]models.ShortenedLink, 0, len(urls))
+		for _, url := range urls {
+			if url.ShortCode == "" || url.OriginalURL == "" || url.UserID <= 0 {
+				return models.ErrInvalidData
 			}
-			result = append(result, dto.ShortenedLinkDBToDomain(existingURL))
-			continue
-		}
 
-		if shortCode, exists := m.originalURLIndex[url.OriginalURL]; exists {
-			if existingURL, exists := m.data[shortCode]; exists {
+			// Проверяем конфликты
+			if existingURL, exists := m.data[url.ShortCode]; exists {
+				if existingURL.OriginalURL != url.OriginalURL {
+					return models.ErrConflict
+				}
 				result = append(result, dto.ShortenedLinkDBToDomain(existingURL))
 				continue
 			}
+
+			if shortCode, exists := m.originalURLIndex[url.OriginalURL]; exists {
+				if existingURL, exists := m.data[shortCode]; exists {
+					result = append(result, dto.ShortenedLinkDBToDomain(existingURL))
+					continue
+				}
+			}
+
+			txSave(tx, &m.lastURLID)
+			m.lastURLID++
+			urlDB := dto.ShortenedLinkDBFromDomain(url)
+			urlDB.ID = m.lastURLID
+			if _, ok := m.utmTemplates[urlDB.UTMTemplateID]; !ok {
+				urlDB.UTMTemplateID = 0
+			}
+			if urlDB.CreatedAt.IsZero() {
+				urlDB.CreatedAt = time.Now()
+			}
+
+			// Добавляем во все индексы
+			txSet(tx, m.data, urlDB.ShortCode, urlDB)
+			txSet(tx, m.originalURLIndex, urlDB.OriginalURL, urlDB.ShortCode)
+
+			dateKey := urlDB.CreatedAt.Format("2006-01-02")
+			txSet(tx, m.createdAtIndex, dateKey, append(m.createdAtIndex[dateKey], urlDB.ShortCode))
+			txSet(tx, m.userURLsIndex, urlDB.UserID, append(m.userURLsIndex[urlDB.UserID], urlDB.ShortCode))
+
+			result = append(result, dto.ShortenedLinkDBToDomain(urlDB))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (m *InmemoryStorage) ShortenedLinkUpdate(ctx context.Context, url models.ShortenedLink) (models.ShortenedLink, error) {
+	if err := ctx.Err(); err != nil {
+		return models.ShortenedLink{}, models.ErrInvalidData
+	}
+
+	if url.ShortCode == "" || url.UserID <= 0 {
+		return models.ShortenedLink{}, models.ErrInvalidData
+	}
+
+	tx, unlock := m.lock(ctx)
+	defer unlock()
+
+	existing, exists := m.data[url.ShortCode]
+	if !exists || existing.UserID != url.UserID || existing.DeletedFlag {
+		return models.ShortenedLink{}, models.ErrUnfound
+	}
+
+	// Меняем только изменяемые настройки, как и UPDATE в Postgres
+	existing.Title = url.Title
+	existing.RedirectStatus = url.RedirectStatus
+	existing.PassQuery = url.PassQuery
+	existing.PassPath = url.PassPath
+	existing.RedirectRules = dto.RedirectRulesDBFromDomain(url.RedirectRules)
+	existing.Variants = dto.LinkVariantsDBFromDomain(url.Variants)
+	existing.StickyVariants = url.StickyVariants
+	existing.ActiveFrom = dto.NullTime(url.ActiveFrom)
+	existing.ActiveUntil = dto.NullTime(url.ActiveUntil)
+	existing.UTMTemplateID = 0
+	if tpl, ok := m.utmTemplates[url.UTMTemplateID]; ok && tpl.UserID == url.UserID {
+		existing.UTMTemplateID = url.UTMTemplateID
+	}
+	txSet(tx, m.data, url.ShortCode, existing)
+
+	return dto.ShortenedLinkDBToDomain(existing), nil
+}
+
+// ShortenedLinkVariantAddClicks добавляет накопленные переходы к счетчикам вариантов
+func (m *InmemoryStorage) ShortenedLinkVariantAddClicks(ctx context.Context, clicks []models.VariantClicks) error {
+	if err := ctx.Err(); err != nil {
+		return models.ErrInvalidData
+	}
+
+	for _, c := range clicks {
+		if c.LinkID <= 0 || c.Variant == "" || c.Clicks <= 0 {
+			return models.ErrInvalidData
 		}
+	}
+
+	tx, unlock := m.lock(ctx)
+	defer unlock()
 
-		m.lastURLID++
-		urlDB := dto.ShortenedLinkDBFromDomain(url)
-		urlDB.ID = m.lastURLID
-		if urlDB.CreatedAt.IsZero() {
-			urlDB.CreatedAt = time.Now()
+	for _, c := range clicks {
+		counts, exists := m.variantClicks[c.LinkID]
+		if !exists {
+			counts = make(map[string]int64)
+			txSet(tx, m.variantClicks, c.LinkID, counts)
 		}
+		txSet(tx, counts, c.Variant, counts[c.Variant]+c.Clicks)
+	}
 
-		// Добавляем во все индексы
-		m.data[urlDB.ShortCode] = urlDB
-		m.originalURLIndex[urlDB.OriginalURL] = urlDB.ShortCode
+	return nil
+}
 
-		dateKey := urlDB.CreatedAt.Format("2006-01-02")
-		m.createdAtIndex[dateKey] = append(m.createdAtIndex[dateKey], urlDB.ShortCode)
-		m.userURLsIndex[urlDB.UserID] = append(m.userURLsIndex[urlDB.UserID], urlDB.ShortCode)
+// ShortenedLinkVariantStats возвращает переходы по вариантам ссылки
+func (m *InmemoryStorage) ShortenedLinkVariantStats(ctx context.Context, linkID int64) (map[string]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, models.ErrInvalidData
+	}
 
-		result = append(result, dto.ShortenedLinkDBToDomain(urlDB))
+	if linkID <= 0 {
+		return nil, models.ErrInvalidData
 	}
 
-	return result, nil
+	unlock := m.rlock(ctx)
+	defer unlock()
+
+	stats := make(map[string]int64, len(m.variantClicks[linkID]))
+	for variant, clicks := range m.variantClicks[linkID] {
+		stats[variant] = clicks
+	}
+
+	return stats, nil
 }
 
 func (m *InmemoryStorage) ShortenedLinkBatchExists(ctx context.Context, originalURLs []string) ([]models.ShortenedLink, error) {
@@ -213,8 +335,8 @@ func (m *InmemoryStorage) ShortenedLinkBatchExists(ctx context.Context, original
 		return nil, models.ErrInvalidData
 	}
 
-	m.rwmu.RLock()
-	defer m.rwmu.RUnlock()
+	unlock := m.rlock(ctx)
+	defer unlock()
 
 	result := make([]models.ShortenedLink, 0, len(originalURLs))
 	for _, originalURL := range originalURLs {
@@ -241,8 +363,8 @@ func (m *InmemoryStorage) ShortenedLinkGetBatchByUser(ctx context.Context, userI
 		return nil, models.ErrInvalidData
 	}
 
-	m.rwmu.RLock()
-	defer m.rwmu.RUnlock()
+	unlock := m.rlock(ctx)
+	defer unlock()
 
 	shortKeys, exists := m.userURLsIndex[userID]
 	if !exists {
@@ -273,8 +395,8 @@ func (m *InmemoryStorage) List(ctx context.Context, limit, offset int) ([]models
 		return nil, models.ErrInvalidData
 	}
 
-	m.rwmu.RLock()
-	defer m.rwmu.RUnlock()
+	unlock := m.rlock(ctx)
+	defer unlock()
 
 	// Собираем все URL
 	allURLs := make([]models.ShortenedLink, 0, len(m.data))
@@ -310,8 +432,8 @@ func (m *InmemoryStorage) ShortenedLinkBatchDelete(ctx context.Context, id int64
 		return models.ErrInvalidData
 	}
 
-	m.rwmu.Lock()
-	defer m.rwmu.Unlock()
+	tx, unlock := m.lock(ctx)
+	defer unlock()
 
 	deletedAt := time.Now().UTC()
 
@@ -336,14 +458,14 @@ func (m *InmemoryStorage) ShortenedLinkBatchDelete(ctx context.Context, id int64
 		url.DeletedAt = deletedAt
 
 		// Обновляем запись в хранилище
-		m.data[shortCode] = url
+		txSet(tx, m.data, shortCode, url)
 
 		// Добавляем в индекс удаленных URL
 		deletedDateKey := deletedAt.Format("2006-01-02")
-		m.deletedAtIndex[deletedDateKey] = append(m.deletedAtIndex[deletedDateKey], shortCode)
+		txSet(tx, m.deletedAtIndex, deletedDateKey, append(m.deletedAtIndex[deletedDateKey], shortCode))
 
 		// Обновляем флаг в отдельном индексе для быстрой проверки
-		m.urlsIsDeleted[shortCode] = true
+		txSet(tx, m.urlsIsDeleted, shortCode, true)
 	}
 
 	return nil
@@ -358,8 +480,8 @@ func (m *InmemoryStorage) Exists(ctx context.Context, originalURL string) (model
 		return models.ShortenedLink{}, models.ErrInvalidData
 	}
 
-	m.rwmu.RLock()
-	defer m.rwmu.RUnlock()
+	unlock := m.rlock(ctx)
+	defer unlock()
 
 	shortCode, exists := m.originalURLIndex[originalURL]
 	if !exists {
@@ -380,9 +502,10 @@ func (m *InmemoryStorage) UserCreate(ctx context.Context, user models.User) (mod
 		return models.User{}, models.ErrInvalidData
 	}
 
-	m.rwmu.Lock()
-	defer m.rwmu.Unlock()
+	tx, unlock := m.lock(ctx)
+	defer unlock()
 
+	txSave(tx, &m.lastUserID)
 	m.lastUserID++
 	userDB := dto.UserDBFromDomain(user)
 	userDB.ID = m.lastUserID
@@ -390,7 +513,7 @@ func (m *InmemoryStorage) UserCreate(ctx context.Context, user models.User) (mod
 		userDB.CreatedAt = time.Now()
 	}
 
-	m.users[userDB.ID] = userDB
+	txSet(tx, m.users, userDB.ID, userDB)
 	return dto.UserDBToDomain(userDB), nil
 }
 
@@ -403,8 +526,8 @@ func (m *InmemoryStorage) UserGetByID(ctx context.Context, id int64) (models.Use
 		return models.User{}, models.ErrInvalidData
 	}
 
-	m.rwmu.RLock()
-	defer m.rwmu.RUnlock()
+	unlock := m.rlock(ctx)
+	defer unlock()
 
 	user, exists := m.users[id]
 	if !exists {
@@ -420,18 +543,14 @@ func (m *InmemoryStorage) Ping(ctx context.Context) error {
 	return nil
 }
 
-func (m *InmemoryStorage) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
-	return fn(ctx)
-}
-
 // Временный метод для отладки
 func (m *InmemoryStorage) GetAll(ctx context.Context) ([]models.ShortenedLink, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, models.ErrInvalidData
 	}
 
-	m.rwmu.RLock()
-	defer m.rwmu.RUnlock()
+	unlock := m.rlock(ctx)
+	defer unlock()
 
 	result := make([]models.ShortenedLink, 0, len(m.data))
 	for _, url := range m.data {
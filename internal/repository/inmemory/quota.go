@@ -0,0 +1,41 @@
+package inmemory
+
+import (
+	"context"
+	"urlshortener/internal/domain/models"
+)
+
+// UserLockForQuota в памяти ничего не блокирует: WithinTx и так держит хранилище до своего завершения
+func (m *InmemoryStorage) UserLockForQuota(ctx context.Context, userID int64) error {
+	if err := ctx.Err(); err != nil {
+		return models.ErrInvalidData
+	}
+
+	if userID <= 0 {
+		return models.ErrInvalidData
+	}
+
+	return nil
+}
+
+func (m *InmemoryStorage) ShortenedLinkCountActiveByUser(ctx context.Context, userID int64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, models.ErrInvalidData
+	}
+
+	if userID <= 0 {
+		return 0, models.ErrInvalidData
+	}
+
+	unlock := m.rlock(ctx)
+	defer unlock()
+
+	count := 0
+	for _, shortKey := range m.userURLsIndex[userID] {
+		if url, exists := m.data[shortKey]; exists && !url.DeletedFlag {
+			count++
+		}
+	}
+
+	return count, nil
+}
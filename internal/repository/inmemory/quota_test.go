@@ -0,0 +1,53 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"urlshortener/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInmemoryStorage_WithinTx_QuotaRace(t *testing.T) {
+	const (
+		limit    = 5
+		creators = 50
+	)
+
+	storage := NewStorage()
+	user, err := storage.UserCreate(context.Background(), models.User{})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := range creators {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Как withQuota: проверка и создание в одной транзакции
+			err := storage.WithinTx(context.Background(), func(ctx context.Context) error {
+				if err := storage.UserLockForQuota(ctx, user.ID); err != nil {
+					return err
+				}
+				used, err := storage.ShortenedLinkCountActiveByUser(ctx, user.ID)
+				if err != nil || used >= limit {
+					return err
+				}
+				_, err = storage.ShortenedLinkCreate(ctx, models.ShortenedLink{
+					ShortCode:   fmt.Sprintf("code%d", i),
+					OriginalURL: fmt.Sprintf("http://example.com/%d", i),
+					UserID:      user.ID,
+				})
+				return err
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	used, err := storage.ShortenedLinkCountActiveByUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, limit, used)
+}
@@ -0,0 +1,51 @@
+package inmemory
+
+import (
+	"context"
+	"urlshortener/internal/domain/models"
+)
+
+func (m *InmemoryStorage) ShortCodeReserve(ctx context.Context, candidates []string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, models.ErrInvalidData
+	}
+
+	if len(candidates) == 0 {
+		return nil, models.ErrInvalidData
+	}
+
+	tx, unlock := m.lock(ctx)
+	defer unlock()
+
+	reserved := make([]string, 0, len(candidates))
+	for _, code := range candidates {
+		if _, exists := m.data[code]; exists {
+			continue
+		}
+		if m.reservedCodes[code] {
+			continue
+		}
+		txSet(tx, m.reservedCodes, code, true)
+		reserved = append(reserved, code)
+	}
+
+	return reserved, nil
+}
+
+func (m *InmemoryStorage) ShortCodeAllocateIDs(ctx context.Context, n int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, models.ErrInvalidData
+	}
+
+	if n <= 0 {
+		return 0, models.ErrInvalidData
+	}
+
+	tx, unlock := m.lock(ctx)
+	defer unlock()
+
+	txSave(tx, &m.nextCodeID)
+	first := m.nextCodeID
+	m.nextCodeID += int64(n)
+	return first, nil
+}
@@ -0,0 +1,120 @@
+package inmemory
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type txKey struct{}
+
+// memTx журнал отмены транзакции: каждое изменение хранилища внутри WithinTx запоминает,
+// как вернуть прежнее значение
+type memTx struct {
+	storage *InmemoryStorage
+	undo    []func()
+	done    atomic.Bool // транзакция завершена, ее контекст больше не освобождает от блокировки
+}
+
+// WithinTx выполняет fn в транзакции. Транзакция держит блокировку хранилища на запись до своего
+// завершения, поэтому параллельные вызовы ждут ее и не видят незафиксированных изменений,
+// а вызовы с ее контекстом видят их сразу. При ошибке или панике все изменения fn откатываются.
+// Вложенный вызов работает как точка сохранения: откатывает только свои изменения.
+// Контекст транзакции нельзя передавать в другие горутины, пока она не завершилась
+func (m *InmemoryStorage) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	tx := m.tx(ctx)
+	if tx == nil {
+		m.rwmu.Lock()
+		tx = &memTx{storage: m}
+		ctx = context.WithValue(ctx, txKey{}, tx)
+		defer func() {
+			tx.done.Store(true)
+			m.rwmu.Unlock()
+		}()
+	}
+
+	mark := len(tx.undo)
+	defer func() {
+		if p := recover(); p != nil {
+			tx.rollback(mark)
+			panic(p)
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		tx.rollback(mark)
+		return err
+	}
+	return nil
+}
+
+// tx возвращает незавершенную транзакцию этого хранилища из контекста или nil
+func (m *InmemoryStorage) tx(ctx context.Context) *memTx {
+	tx, ok := ctx.Value(txKey{}).(*memTx)
+	if !ok || tx.storage != m || tx.done.Load() {
+		return nil
+	}
+	return tx
+}
+
+// lock берет блокировку на запись и возвращает транзакцию ctx для журнала изменений.
+// Внутри транзакции блокировка уже взята WithinTx
+func (m *InmemoryStorage) lock(ctx context.Context) (*memTx, func()) {
+	if tx := m.tx(ctx); tx != nil {
+		return tx, func() {}
+	}
+	m.rwmu.Lock()
+	return nil, m.rwmu.Unlock
+}
+
+// rlock берет блокировку на чтение, внутри транзакции блокировка уже взята WithinTx
+func (m *InmemoryStorage) rlock(ctx context.Context) func() {
+	if m.tx(ctx) != nil {
+		return func() {}
+	}
+	m.rwmu.RLock()
+	return m.rwmu.RUnlock
+}
+
+// rollback отменяет изменения, сделанные после отметки mark, в обратном порядке
+func (tx *memTx) rollback(mark int) {
+	for i := len(tx.undo) - 1; i >= mark; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = tx.undo[:mark]
+}
+
+// txSet записывает значение, запоминая в журнале tx прежнее. Вызывается под блокировкой
+func txSet[K comparable, V any](tx *memTx, dst map[K]V, key K, value V) {
+	if tx != nil {
+		old, existed := dst[key]
+		tx.undo = append(tx.undo, func() {
+			if existed {
+				dst[key] = old
+			} else {
+				delete(dst, key)
+			}
+		})
+	}
+	dst[key] = value
+}
+
+// txDelete удаляет значение, запоминая его в журнале tx. Вызывается под блокировкой
+func txDelete[K comparable, V any](tx *memTx, dst map[K]V, key K) {
+	old, existed := dst[key]
+	if !existed {
+		return
+	}
+	if tx != nil {
+		tx.undo = append(tx.undo, func() { dst[key] = old })
+	}
+	delete(dst, key)
+}
+
+// txSave запоминает текущее значение поля перед его изменением. Вызывается под блокировкой
+func txSave[T any](tx *memTx, field *T) {
+	if tx == nil {
+		return
+	}
+	old := *field
+	tx.undo = append(tx.undo, func() { *field = old })
+}
@@ -0,0 +1,403 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/repository/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTxTest = errors.New("test error")
+
+// storageState копия данных и индексов хранилища для сравнения до и после транзакции
+type storageState struct {
+	data             map[string]dto.ShortenedLinkDB
+	users            map[int64]dto.UserDB
+	originalURLIndex map[string]string
+	userURLsIndex    map[int64][]string
+	createdAtIndex   map[string][]string
+	lastURLID        int64
+	lastUserID       int64
+}
+
+func snapshot(m *InmemoryStorage) storageState {
+	m.rwmu.RLock()
+	defer m.rwmu.RUnlock()
+
+	return storageState{
+		data:             maps.Clone(m.data),
+		users:            maps.Clone(m.users),
+		originalURLIndex: maps.Clone(m.originalURLIndex),
+		userURLsIndex:    maps.Clone(m.userURLsIndex),
+		createdAtIndex:   maps.Clone(m.createdAtIndex),
+		lastURLID:        m.lastURLID,
+		lastUserID:       m.lastUserID,
+	}
+}
+
+// newTxTestStorage хранилище с одним пользователем и одной ссылкой
+func newTxTestStorage(t *testing.T) (*InmemoryStorage, models.User) {
+	t.Helper()
+
+	storage := NewStorage()
+	user, err := storage.UserCreate(context.Background(), models.User{})
+	require.NoError(t, err)
+	_, err = storage.ShortenedLinkCreate(context.Background(), models.ShortenedLink{
+		ShortCode:   "old",
+		OriginalURL: "http://old.url",
+		UserID:      user.ID,
+	})
+	require.NoError(t, err)
+
+	return storage, user
+}
+
+func TestInmemoryStorage_WithinTx(t *testing.T) {
+	tests := []struct {
+		name       string
+		fn         func(ctx context.Context, storage *InmemoryStorage, user models.User) error
+		wantErr    error
+		wantPanic  bool
+		wantCommit bool
+	}{
+		{
+			name: "Ошибка откатывает пользователей, ссылки и индексы",
+			fn: func(ctx context.Context, storage *InmemoryStorage, user models.User) error {
+				newUser, err := storage.UserCreate(ctx, models.User{})
+				if err != nil {
+					return err
+				}
+				_, err = storage.ShortenedLinkCreate(ctx, models.ShortenedLink{
+					ShortCode:   "new",
+					OriginalURL: "http://new.url",
+					UserID:      newUser.ID,
+				})
+				if err != nil {
+					return err
+				}
+				return errTxTest
+			},
+			wantErr: errTxTest,
+		},
+		{
+			name: "Регистрация с ошибкой после создания пользователя",
+			fn: func(ctx context.Context, storage *InmemoryStorage, user models.User) error {
+				if _, err := storage.UserCreate(ctx, models.User{}); err != nil {
+					return err
+				}
+				// Как при ошибке выпуска токена в auth.Register
+				return errTxTest
+			},
+			wantErr: errTxTest,
+		},
+		{
+			name: "Ошибка откатывает изменение и удаление ссылок",
+			fn: func(ctx context.Context, storage *InmemoryStorage, user models.User) error {
+				link, err := storage.ShortenedLinkGetByShortKey(ctx, "old")
+				if err != nil {
+					return err
+				}
+				link.Title = "changed"
+				if _, err := storage.ShortenedLinkUpdate(ctx, link); err != nil {
+					return err
+				}
+				if err := storage.ShortenedLinkBatchDelete(ctx, user.ID, []string{"old"}); err != nil {
+					return err
+				}
+				return errTxTest
+			},
+			wantErr: errTxTest,
+		},
+		{
+			name: "Паника откатывает изменения",
+			fn: func(ctx context.Context, storage *InmemoryStorage, user models.User) error {
+				if _, err := storage.UserCreate(ctx, models.User{}); err != nil {
+					return err
+				}
+				panic(errTxTest)
+			},
+			wantPanic: true,
+		},
+		{
+			name: "Без ошибки изменения сохраняются",
+			fn: func(ctx context.Context, storage *InmemoryStorage, user models.User) error {
+				_, err := storage.ShortenedLinkCreate(ctx, models.ShortenedLink{
+					ShortCode:   "new",
+					OriginalURL: "http://new.url",
+					UserID:      user.ID,
+				})
+				return err
+			},
+			wantCommit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, user := newTxTestStorage(t)
+			before := snapshot(storage)
+
+			run := func() error {
+				return storage.WithinTx(context.Background(), func(ctx context.Context) error {
+					return tt.fn(ctx, storage, user)
+				})
+			}
+
+			if tt.wantPanic {
+				assert.Panics(t, func() { _ = run() })
+			} else {
+				err := run()
+				if tt.wantErr != nil {
+					assert.ErrorIs(t, err, tt.wantErr)
+				} else {
+					assert.NoError(t, err)
+				}
+			}
+
+			if tt.wantCommit {
+				assert.NotEqual(t, before, snapshot(storage))
+				return
+			}
+			assert.Equal(t, before, snapshot(storage))
+		})
+	}
+}
+
+func TestInmemoryStorage_WithinTx_ReadsOwnWrites(t *testing.T) {
+	storage, user := newTxTestStorage(t)
+
+	err := storage.WithinTx(context.Background(), func(ctx context.Context) error {
+		_, err := storage.ShortenedLinkCreate(ctx, models.ShortenedLink{
+			ShortCode:   "new",
+			OriginalURL: "http://new.url",
+			UserID:      user.ID,
+		})
+		require.NoError(t, err)
+
+		link, err := storage.ShortenedLinkGetByOriginalURL(ctx, "http://new.url")
+		require.NoError(t, err)
+		assert.Equal(t, "new", link.ShortCode)
+
+		links, err := storage.ShortenedLinkGetBatchByUser(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Len(t, links, 2)
+
+		return errTxTest
+	})
+	assert.ErrorIs(t, err, errTxTest)
+
+	_, err = storage.ShortenedLinkGetByShortKey(context.Background(), "new")
+	assert.ErrorIs(t, err, models.ErrUnfound)
+}
+
+func TestInmemoryStorage_WithinTx_Nested(t *testing.T) {
+	tests := []struct {
+		name        string
+		innerErr    error
+		outerErr    error
+		wantCodes   []string
+		wantUnfound []string
+	}{
+		{
+			name:        "Ошибка вложенной транзакции откатывает только ее изменения",
+			innerErr:    errTxTest,
+			wantCodes:   []string{"old", "outer"},
+			wantUnfound: []string{"inner"},
+		},
+		{
+			name:      "Вложенная транзакция фиксируется вместе с внешней",
+			wantCodes: []string{"old", "outer", "inner"},
+		},
+		{
+			name:        "Ошибка внешней транзакции откатывает и вложенную",
+			outerErr:    errTxTest,
+			wantCodes:   []string{"old"},
+			wantUnfound: []string{"outer", "inner"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, user := newTxTestStorage(t)
+
+			create := func(ctx context.Context, code string) error {
+				_, err := storage.ShortenedLinkCreate(ctx, models.ShortenedLink{
+					ShortCode:   code,
+					OriginalURL: "http://" + code + ".url",
+					UserID:      user.ID,
+				})
+				return err
+			}
+
+			err := storage.WithinTx(context.Background(), func(ctx context.Context) error {
+				if err := create(ctx, "outer"); err != nil {
+					return err
+				}
+
+				innerErr := storage.WithinTx(ctx, func(ctx context.Context) error {
+					if err := create(ctx, "inner"); err != nil {
+						return err
+					}
+					return tt.innerErr
+				})
+				assert.ErrorIs(t, innerErr, tt.innerErr)
+
+				// Внешняя транзакция продолжается и после отката вложенной
+				if _, err := storage.ShortenedLinkGetByShortKey(ctx, "outer"); err != nil {
+					return err
+				}
+				return tt.outerErr
+			})
+			if tt.outerErr != nil {
+				assert.ErrorIs(t, err, tt.outerErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			ctx := context.Background()
+			for _, code := range tt.wantCodes {
+				_, err := storage.ShortenedLinkGetByShortKey(ctx, code)
+				assert.NoError(t, err, code)
+			}
+			for _, code := range tt.wantUnfound {
+				_, err := storage.ShortenedLinkGetByShortKey(ctx, code)
+				assert.ErrorIs(t, err, models.ErrUnfound, code)
+				_, err = storage.ShortenedLinkGetByOriginalURL(ctx, "http://"+code+".url")
+				assert.ErrorIs(t, err, models.ErrUnfound, code)
+			}
+
+			links, err := storage.ShortenedLinkGetBatchByUser(ctx, user.ID)
+			require.NoError(t, err)
+			assert.Len(t, links, len(tt.wantCodes))
+		})
+	}
+}
+
+func TestInmemoryStorage_ShortenedLinkBatchCreate_Atomic(t *testing.T) {
+	tests := []struct {
+		name    string
+		batch   func(user models.User) []models.ShortenedLink
+		wantErr error
+	}{
+		{
+			name: "Конфликт кода в середине пакета",
+			batch: func(user models.User) []models.ShortenedLink {
+				return []models.ShortenedLink{
+					{ShortCode: "a", OriginalURL: "http://a.url", UserID: user.ID},
+					{ShortCode: "b", OriginalURL: "http://b.url", UserID: user.ID},
+					{ShortCode: "old", OriginalURL: "http://other.url", UserID: user.ID},
+					{ShortCode: "c", OriginalURL: "http://c.url", UserID: user.ID},
+				}
+			},
+			wantErr: models.ErrConflict,
+		},
+		{
+			name: "Некорректная ссылка в конце пакета",
+			batch: func(user models.User) []models.ShortenedLink {
+				return []models.ShortenedLink{
+					{ShortCode: "a", OriginalURL: "http://a.url", UserID: user.ID},
+					{ShortCode: "b", OriginalURL: "", UserID: user.ID},
+				}
+			},
+			wantErr: models.ErrInvalidData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, user := newTxTestStorage(t)
+			before := snapshot(storage)
+
+			_, err := storage.ShortenedLinkBatchCreate(context.Background(), tt.batch(user))
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, before, snapshot(storage))
+
+			// Внутри транзакции ошибка пакета тоже не оставляет его части
+			err = storage.WithinTx(context.Background(), func(ctx context.Context) error {
+				_, err := storage.ShortenedLinkBatchCreate(ctx, tt.batch(user))
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, before.data, maps.Clone(storage.data))
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, before, snapshot(storage))
+		})
+	}
+}
+
+func TestInmemoryStorage_WithinTx_Isolation(t *testing.T) {
+	tests := []struct {
+		name    string
+		txErr   error
+		wantErr error
+	}{
+		{
+			name:    "Читатель не видит незафиксированную ссылку и после отката",
+			txErr:   errTxTest,
+			wantErr: models.ErrUnfound,
+		},
+		{
+			name: "Читатель видит ссылку после фиксации",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, user := newTxTestStorage(t)
+
+			written := make(chan struct{})
+			release := make(chan struct{})
+			txDone := make(chan error, 1)
+			go func() {
+				txDone <- storage.WithinTx(context.Background(), func(ctx context.Context) error {
+					_, err := storage.ShortenedLinkCreate(ctx, models.ShortenedLink{
+						ShortCode:   "new",
+						OriginalURL: "http://new.url",
+						UserID:      user.ID,
+					})
+					if err != nil {
+						return err
+					}
+					close(written)
+					<-release
+					return tt.txErr
+				})
+			}()
+			<-written
+
+			type readResult struct {
+				link models.ShortenedLink
+				err  error
+			}
+			read := make(chan readResult, 1)
+			go func() {
+				link, err := storage.ShortenedLinkGetByShortKey(context.Background(), "new")
+				read <- readResult{link: link, err: err}
+			}()
+
+			// Пока транзакция не завершилась, читатель ждет ее
+			select {
+			case got := <-read:
+				t.Fatalf("read finished before commit: %+v, %v", got.link, got.err)
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			close(release)
+			assert.ErrorIs(t, <-txDone, tt.txErr)
+
+			got := <-read
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, got.err, tt.wantErr)
+				return
+			}
+			require.NoError(t, got.err)
+			assert.Equal(t, "http://new.url", got.link.OriginalURL)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"time"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/repository/dto"
+)
+
+func (m *InmemoryStorage) UTMTemplateCreate(ctx context.Context, tpl models.UTMTemplate) (models.UTMTemplate, error) {
+	if err := ctx.Err(); err != nil {
+		return models.UTMTemplate{}, models.ErrInvalidData
+	}
+
+	if tpl.UserID <= 0 || tpl.Name == "" {
+		return models.UTMTemplate{}, models.ErrInvalidData
+	}
+
+	tx, unlock := m.lock(ctx)
+	defer unlock()
+
+	if _, exists := m.utmTemplateByName(tpl.UserID, tpl.Name); exists {
+		return models.UTMTemplate{}, models.ErrConflict
+	}
+
+	txSave(tx, &m.lastUTMTemplateID)
+	m.lastUTMTemplateID++
+	tplDB := dto.UTMTemplateDBFromDomain(tpl)
+	tplDB.ID = m.lastUTMTemplateID
+	if tplDB.CreatedAt.IsZero() {
+		tplDB.CreatedAt = time.Now()
+	}
+
+	txSet(tx, m.utmTemplates, tplDB.ID, tplDB)
+	return dto.UTMTemplateDBToDomain(tplDB), nil
+}
+
+func (m *InmemoryStorage) UTMTemplateGetByName(ctx context.Context, userID int64, name string) (models.UTMTemplate, error) {
+	if err := ctx.Err(); err != nil {
+		return models.UTMTemplate{}, models.ErrInvalidData
+	}
+
+	if userID <= 0 || name == "" {
+		return models.UTMTemplate{}, models.ErrInvalidData
+	}
+
+	unlock := m.rlock(ctx)
+	defer unlock()
+
+	tpl, exists := m.utmTemplateByName(userID, name)
+	if !exists {
+		return models.UTMTemplate{}, models.ErrUnfound
+	}
+	return dto.UTMTemplateDBToDomain(tpl), nil
+}
+
+func (m *InmemoryStorage) UTMTemplateGetByID(ctx context.Context, id int64) (models.UTMTemplate, error) {
+	if err := ctx.Err(); err != nil {
+		return models.UTMTemplate{}, models.ErrInvalidData
+	}
+
+	if id <= 0 {
+		return models.UTMTemplate{}, models.ErrInvalidData
+	}
+
+	unlock := m.rlock(ctx)
+	defer unlock()
+
+	tpl, exists := m.utmTemplates[id]
+	if !exists {
+		return models.UTMTemplate{}, models.ErrUnfound
+	}
+	return dto.UTMTemplateDBToDomain(tpl), nil
+}
+
+func (m *InmemoryStorage) UTMTemplateGetBatchByUser(ctx context.Context, userID int64) ([]models.UTMTemplate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, models.ErrInvalidData
+	}
+
+	if userID <= 0 {
+		return nil, models.ErrInvalidData
+	}
+
+	unlock := m.rlock(ctx)
+	defer unlock()
+
+	result := make([]models.UTMTemplate, 0)
+	for _, tpl := range m.utmTemplates {
+		if tpl.UserID == userID {
+			result = append(result, dto.UTMTemplateDBToDomain(tpl))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+func (m *InmemoryStorage) UTMTemplateDelete(ctx context.Context, userID int64, name string) error {
+	if err := ctx.Err(); err != nil {
+		return models.ErrInvalidData
+	}
+
+	if userID <= 0 || name == "" {
+		return models.ErrInvalidData
+	}
+
+	tx, unlock := m.lock(ctx)
+	defer unlock()
+
+	tpl, exists := m.utmTemplateByName(userID, name)
+	if !exists {
+		return models.ErrUnfound
+	}
+	txDelete(tx, m.utmTemplates, tpl.ID)
+
+	// Аналог ON DELETE SET NULL: отвязываем шаблон от ссылок пользователя
+	for _, shortKey := range m.userURLsIndex[userID] {
+		if url, exists := m.data[shortKey]; exists && url.UTMTemplateID == tpl.ID {
+			url.UTMTemplateID = 0
+			txSet(tx, m.data, shortKey, url)
+		}
+	}
+
+	return nil
+}
+
+// utmTemplateByName ищет шаблон пользователя по имени, вызывается под блокировкой
+func (m *InmemoryStorage) utmTemplateByName(userID int64, name string) (dto.UTMTemplateDB, bool) {
+	for _, tpl := range m.utmTemplates {
+		if tpl.UserID == userID && tpl.Name == name {
+			return tpl, true
+		}
+	}
+	return dto.UTMTemplateDB{}, false
+}
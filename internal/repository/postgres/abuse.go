@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/repository/dto"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// abuseReportColumns - порядок колонок abuse_reports, который ожидает scanAbuseReport
+const abuseReportColumns = "id, url_id, reason, details, reporter, resolved, created_at"
+
+// AbuseReportCreate сохраняет жалобу на ссылку
+func (p *PostgresStorage) AbuseReportCreate(ctx context.Context, report models.AbuseReport) (models.AbuseReport, error) {
+	if report.LinkID <= 0 || report.Reason == "" {
+		return models.AbuseReport{}, models.ErrInvalidData
+	}
+
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return models.AbuseReport{}, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	reportDB := dto.AbuseReportDBFromDomain(report)
+	result, err := scanAbuseReport(querier.QueryRow(ctx, `
+		INSERT INTO abuse_reports (url_id, reason, details, reporter, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+abuseReportColumns,
+		reportDB.LinkID, reportDB.Reason, reportDB.Details, reportDB.Reporter, reportDB.CreatedAt,
+	))
+	if err != nil {
+		return models.AbuseReport{}, fmt.Errorf("failed to create abuse report: %w", err)
+	}
+
+	return dto.AbuseReportDBToDomain(result), nil
+}
+
+// AbuseReportCountReporters возвращает число разных отправителей нерассмотренных жалоб на ссылку
+func (p *PostgresStorage) AbuseReportCountReporters(ctx context.Context, linkID int64) (int, error) {
+	if linkID <= 0 {
+		return 0, models.ErrInvalidData
+	}
+
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	var count int
+	err = querier.QueryRow(ctx,
+		"SELECT COUNT(DISTINCT reporter) FROM abuse_reports WHERE url_id = $1 AND resolved = false",
+		linkID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reporters: %w", err)
+	}
+
+	return count, nil
+}
+
+// AbuseReportGetByLink возвращает все жалобы на ссылку, новые первыми
+func (p *PostgresStorage) AbuseReportGetByLink(ctx context.Context, linkID int64) ([]models.AbuseReport, error) {
+	if linkID <= 0 {
+		return nil, models.ErrInvalidData
+	}
+
+	querier, err := p.GetReadQuerier(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	rows, err := querier.Query(ctx,
+		"SELECT "+abuseReportColumns+" FROM abuse_reports WHERE url_id = $1 ORDER BY created_at DESC, id DESC",
+		linkID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get abuse reports: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []models.AbuseReport
+	for rows.Next() {
+		reportDB, err := scanAbuseReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan abuse report: %w", err)
+		}
+		reports = append(reports, dto.AbuseReportDBToDomain(reportDB))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return reports, nil
+}
+
+// AbuseReportListPending возвращает ссылки с нерассмотренными жалобами:
+// сначала помещенные в карантин, затем по числу отправителей
+func (p *PostgresStorage) AbuseReportListPending(ctx context.Context) ([]models.AbuseReportSummary, error) {
+	querier, err := p.GetReadQuerier(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	rows, err := querier.Query(ctx, `
+		WITH pending AS (
+			SELECT url_id, COUNT(*) AS reports, COUNT(DISTINCT reporter) AS reporters, MAX(created_at) AS last_report_at
+			FROM abuse_reports
+			WHERE resolved = false
+			GROUP BY url_id
+		)
+		SELECT `+shortenedLinkColumns+`, pending.reports, pending.reporters, pending.last_report_at
+		FROM urls JOIN pending ON pending.url_id = urls.id
+		ORDER BY moderation = 'quarantined' DESC, pending.reporters DESC, pending.last_report_at DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending reports: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []models.AbuseReportSummary
+	for rows.Next() {
+		var summary models.AbuseReportSummary
+		linkDB, err := scanShortLink(rows, &summary.Reports, &summary.Reporters, &summary.LastReportAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pending report: %w", err)
+		}
+		summary.Link = dto.ShortenedLinkDBToDomain(linkDB)
+		summaries = append(summaries, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return summaries, nil
+}
+
+// AbuseReportResolve отмечает все жалобы на ссылку рассмотренными
+func (p *PostgresStorage) AbuseReportResolve(ctx context.Context, linkID int64) error {
+	if linkID <= 0 {
+		return models.ErrInvalidData
+	}
+
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	_, err = querier.Exec(ctx,
+		"UPDATE abuse_reports SET resolved = true WHERE url_id = $1 AND resolved = false",
+		linkID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to resolve abuse reports: %w", err)
+	}
+
+	return nil
+}
+
+// ShortenedLinkSetModeration меняет состояние модерации ссылки
+func (p *PostgresStorage) ShortenedLinkSetModeration(ctx context.Context, linkID int64, status string) error {
+	if linkID <= 0 {
+		return models.ErrInvalidData
+	}
+
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	var shortKey string
+	err = querier.QueryRow(ctx, "UPDATE urls SET moderation = $2 WHERE id = $1 RETURNING short_key", linkID, status).Scan(&shortKey)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrUnfound
+		}
+		return fmt.Errorf("failed to set moderation: %w", err)
+	}
+
+	return p.notifyLinkChanges(ctx, querier, []string{shortKey})
+}
+
+func scanAbuseReport(row rowScanner) (dto.AbuseReportDB, error) {
+	var reportDB dto.AbuseReportDB
+	err := row.Scan(
+		&reportDB.ID,
+		&reportDB.LinkID,
+		&reportDB.Reason,
+		&reportDB.Details,
+		&reportDB.Reporter,
+		&reportDB.Resolved,
+		&reportDB.CreatedAt,
+	)
+	return reportDB, err
+}
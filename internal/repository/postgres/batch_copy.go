@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/repository/dto"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// batchCopyThreshold с какого размера пакет загружается через COPY. Меньшие пакеты
+	// дешевле вставить по одной ссылке, чем создавать временную таблицу
+	batchCopyThreshold = 100
+
+	// batchCopyTable временная таблица для пакета, живет до конца транзакции
+	batchCopyTable = "urls_batch"
+)
+
+// batchCopyColumns колонки временной таблицы: номер ссылки в пакете и колонки shortenedLinkInsertColumns
+var batchCopyColumns = []string{
+	"ord", "short_key", "original_url", "user_id", "created_at", "title", "redirect_status", "pass_query", "pass_path", "utm_template_id",
+	"redirect_rules", "variants", "sticky_variants", "active_from", "active_until", "moderation",
+}
+
+// shortenedLinkCopyInsert загружает пакет через COPY во временную таблицу и переносит его в urls одним
+// INSERT ... SELECT, поэтому число обменов с сервером не зависит от размера пакета. Результат
+// сопоставляется с пакетом по номеру строки. Как и при вставке по одной, из повторов адреса в пакете
+// создается первый, для уже сокращенного адреса возвращается существующая ссылка, а удаленная ссылка
+// с тем же адресом - ошибка
+func (p *PostgresStorage) shortenedLinkCopyInsert(ctx context.Context, querier Querier, urls []models.ShortenedLink) ([]models.ShortenedLink, []string, error) {
+	_, err := querier.Exec(ctx, `
+		CREATE TEMP TABLE `+batchCopyTable+` (
+			ord INTEGER NOT NULL,
+			short_key TEXT NOT NULL,
+			original_url TEXT NOT NULL,
+			user_id BIGINT,
+			created_at TIMESTAMP NOT NULL,
+			title TEXT NOT NULL,
+			redirect_status SMALLINT NOT NULL,
+			pass_query BOOLEAN NOT NULL,
+			pass_path BOOLEAN NOT NULL,
+			utm_template_id BIGINT NOT NULL,
+			redirect_rules JSONB NOT NULL,
+			variants JSONB NOT NULL,
+			sticky_variants BOOLEAN NOT NULL,
+			active_from TIMESTAMPTZ,
+			active_until TIMESTAMPTZ,
+			moderation TEXT NOT NULL
+		) ON COMMIT DROP`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create batch table: %w", err)
+	}
+
+	rows := make([][]any, len(urls))
+	for i, url := range urls {
+		rows[i] = append([]any{i}, shortLinkInsertArgs(dto.ShortenedLinkDBFromDomain(url))...)
+	}
+	if _, err := querier.CopyFrom(ctx, pgx.Identifier{batchCopyTable}, batchCopyColumns, pgx.CopyFromRows(rows)); err != nil {
+		return nil, nil, fmt.Errorf("failed to copy URL batch: %w", err)
+	}
+
+	// Вставленные строки не видны запросу, который их вставил, поэтому результат собирается
+	// из RETURNING и ссылок, существовавших до вставки. Адрес уникален, так что на строку пакета
+	// приходится не больше одной ссылки
+	linkRows, err := querier.Query(ctx, `
+		WITH inserted AS (
+			INSERT INTO urls (`+shortenedLinkInsertColumns+`)
+			SELECT short_key, original_url, user_id, created_at, title, redirect_status, pass_query, pass_path,
+				(SELECT id FROM utm_templates WHERE id = b.utm_template_id), redirect_rules,
+				variants, sticky_variants, active_from, active_until, moderation
+			FROM (
+				SELECT DISTINCT ON (original_url) * FROM `+batchCopyTable+` ORDER BY original_url, ord
+			) AS b
+			ORDER BY ord
+			ON CONFLICT (original_url) DO NOTHING
+			RETURNING `+shortenedLinkColumns+`
+		), links AS (
+			SELECT *, true AS created FROM inserted
+			UNION ALL
+			SELECT `+shortenedLinkColumns+`, false FROM urls
+			WHERE is_deleted = false AND original_url IN (SELECT original_url FROM `+batchCopyTable+`)
+		)
+		SELECT links.*, b.ord FROM `+batchCopyTable+` AS b
+		JOIN links ON links.original_url = b.original_url`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to insert URL batch: %w", err)
+	}
+	defer linkRows.Close()
+
+	result := make([]models.ShortenedLink, len(urls))
+	found := make([]bool, len(urls))
+	var created []string
+	seen := make(map[string]bool)
+	for linkRows.Next() {
+		var (
+			isCreated bool
+			ord       int
+		)
+		dbURL, err := scanShortLink(linkRows, &isCreated, &ord)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan URL: %w", err)
+		}
+		result[ord] = dto.ShortenedLinkDBToDomain(dbURL)
+		found[ord] = true
+
+		// Повтор адреса в пакете получает ту же созданную ссылку, уведомлять о ней второй раз не нужно
+		if isCreated && !seen[dbURL.ShortCode] {
+			created = append(created, dbURL.ShortCode)
+		}
+		seen[dbURL.ShortCode] = true
+	}
+	if err := linkRows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to insert URL batch: %w", err)
+	}
+
+	if err := fillConcurrentLinks(ctx, querier, urls, result, found); err != nil {
+		return nil, nil, err
+	}
+
+	// Повторный пакет в той же транзакции создаст таблицу заново
+	if _, err := querier.Exec(ctx, "DROP TABLE "+batchCopyTable); err != nil {
+		return nil, nil, fmt.Errorf("failed to drop batch table: %w", err)
+	}
+	return result, created, nil
+}
+
+// fillConcurrentLinks находит ссылки на адреса, которые параллельная транзакция вставила после начала
+// запроса пакета: вставка их пропустила по конфликту, а снимок запроса их еще не видел. В READ COMMITTED
+// новый запрос их видит. В SERIALIZABLE и REPEATABLE READ PostgreSQL сам прерывает такую вставку
+// ошибкой сериализации, и транзакция повторяется. Адрес, занятый удаленной ссылкой, остается ошибкой
+func fillConcurrentLinks(ctx context.Context, querier Querier, urls []models.ShortenedLink, result []models.ShortenedLink, found []bool) error {
+	var missing []string
+	for i, ok := range found {
+		if !ok {
+			missing = append(missing, urls[i].OriginalURL)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	rows, err := querier.Query(ctx,
+		"SELECT "+shortenedLinkColumns+" FROM urls WHERE original_url = ANY($1) AND is_deleted = false",
+		missing,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get existing URLs: %w", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]models.ShortenedLink, len(missing))
+	for rows.Next() {
+		dbURL, err := scanShortLink(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan URL: %w", err)
+		}
+		existing[dbURL.OriginalURL] = dto.ShortenedLinkDBToDomain(dbURL)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get existing URLs: %w", err)
+	}
+
+	for i, ok := range found {
+		if ok {
+			continue
+		}
+		link, exists := existing[urls[i].OriginalURL]
+		if !exists {
+			// Адрес занят удаленной ссылкой, как и при вставке по одной
+			return fmt.Errorf("failed to get existing URL: %w", pgx.ErrNoRows)
+		}
+		result[i] = link
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	"urlshortener/internal/domain/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorage_ShortenedLinkCopyInsert(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	user, err := storage.UserCreate(ctx, models.User{CreatedAt: time.Now().UTC()})
+	require.NoError(t, err)
+
+	link := func(code, url string) models.ShortenedLink {
+		return models.ShortenedLink{ShortCode: code, OriginalURL: url, UserID: user.ID, CreatedAt: time.Now().UTC()}
+	}
+
+	tests := []struct {
+		name        string
+		setup       func(t *testing.T)
+		batch       []models.ShortenedLink
+		wantCodes   []string
+		wantCreated []string
+		wantErr     error
+	}{
+		{
+			name: "Повторы адреса в пакете получают первую ссылку",
+			batch: []models.ShortenedLink{
+				link("dup1", "https://dup.example/a"),
+				link("dup2", "https://dup.example/b"),
+				link("dup3", "https://dup.example/a"),
+			},
+			wantCodes:   []string{"dup1", "dup2", "dup1"},
+			wantCreated: []string{"dup1", "dup2"},
+		},
+		{
+			name: "Для уже сокращенного адреса возвращается существующая ссылка",
+			setup: func(t *testing.T) {
+				_, err := storage.ShortenedLinkCreate(ctx, link("old1", "https://old.example/"))
+				require.NoError(t, err)
+			},
+			batch: []models.ShortenedLink{
+				link("new1", "https://new.example/"),
+				link("new2", "https://old.example/"),
+			},
+			wantCodes:   []string{"new1", "old1"},
+			wantCreated: []string{"new1"},
+		},
+		{
+			name: "Адрес удаленной ссылки - ошибка",
+			setup: func(t *testing.T) {
+				_, err := storage.ShortenedLinkCreate(ctx, link("del1", "https://deleted.example/"))
+				require.NoError(t, err)
+				require.NoError(t, storage.ShortenedLinkBatchDelete(ctx, user.ID, []string{"del1"}))
+			},
+			batch: []models.ShortenedLink{
+				link("del2", "https://deleted.example/"),
+			},
+			wantErr: pgx.ErrNoRows,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+
+			var (
+				result  []models.ShortenedLink
+				created []string
+			)
+			err := storage.WithinTx(ctx, func(txCtx context.Context) error {
+				querier, err := storage.GetQuerier(txCtx)
+				if err != nil {
+					return err
+				}
+				result, created, err = storage.shortenedLinkCopyInsert(txCtx, querier, tt.batch)
+				return err
+			})
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			codes := make([]string, len(result))
+			for i, link := range result {
+				codes[i] = link.ShortCode
+				assert.Equal(t, tt.batch[i].OriginalURL, link.OriginalURL)
+			}
+			assert.Equal(t, tt.wantCodes, codes)
+			assert.ElementsMatch(t, tt.wantCreated, created)
+		})
+	}
+}
+
+func TestFillConcurrentLinks(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	user, err := storage.UserCreate(ctx, models.User{CreatedAt: time.Now().UTC()})
+	require.NoError(t, err)
+
+	// Ссылка, которую вставила бы параллельная транзакция: запрос пакета ее не нашел
+	concurrent, err := storage.ShortenedLinkCreate(ctx, models.ShortenedLink{
+		ShortCode: "conc1", OriginalURL: "https://concurrent.example/", UserID: user.ID, CreatedAt: time.Now().UTC(),
+	})
+	require.NoError(t, err)
+
+	urls := []models.ShortenedLink{
+		{ShortCode: "own1", OriginalURL: "https://own.example/"},
+		{ShortCode: "conc2", OriginalURL: "https://concurrent.example/"},
+	}
+	result := []models.ShortenedLink{{ShortCode: "own1", OriginalURL: "https://own.example/"}, {}}
+	found := []bool{true, false}
+
+	require.NoError(t, fillConcurrentLinks(ctx, storage.pool, urls, result, found))
+	assert.Equal(t, concurrent.ShortCode, result[1].ShortCode)
+	assert.Equal(t, "own1", result[0].ShortCode)
+
+	t.Run("Адрес без активной ссылки", func(t *testing.T) {
+		urls := []models.ShortenedLink{{OriginalURL: fmt.Sprintf("https://missing.example/%d", time.Now().UnixNano())}}
+		err := fillConcurrentLinks(ctx, storage.pool, urls, make([]models.ShortenedLink, 1), []bool{false})
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/repository/dto"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// IdempotencyBegin занимает ключ одним UPSERT: существующая запись перезаписывается,
+// только если она истекла или брошена, поэтому из параллельных запросов ключ получает один.
+// Остальные читают действующую запись
+func (p *PostgresStorage) IdempotencyBegin(ctx context.Context, record models.IdempotencyRecord, staleBefore time.Time) (models.IdempotencyRecord, bool, error) {
+	if record.UserID <= 0 || record.Key == "" || record.Fingerprint == "" || record.Owner == "" {
+		return models.IdempotencyRecord{}, false, models.ErrInvalidData
+	}
+
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return models.IdempotencyRecord{}, false, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	recordDB := dto.IdempotencyRecordDBFromDomain(record)
+	var created bool
+	err = querier.QueryRow(ctx, `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint, owner, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			owner = EXCLUDED.owner,
+			status_code = 0,
+			content_type = '',
+			body = NULL,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+			OR (idempotency_keys.status_code = 0 AND idempotency_keys.created_at <= $7)
+		RETURNING true`,
+		recordDB.UserID, recordDB.Key, recordDB.Fingerprint, recordDB.Owner, recordDB.CreatedAt, recordDB.ExpiresAt, staleBefore,
+	).Scan(&created)
+	if err == nil {
+		return record, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return models.IdempotencyRecord{}, false, fmt.Errorf("failed to insert idempotency key: %w", err)
+	}
+
+	existing := dto.IdempotencyRecordDB{UserID: record.UserID, Key: record.Key}
+	err = querier.QueryRow(ctx, `
+		SELECT fingerprint, status_code, content_type, body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2`,
+		record.UserID, record.Key,
+	).Scan(&existing.Fingerprint, &existing.StatusCode, &existing.ContentType, &existing.Body, &existing.CreatedAt, &existing.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.IdempotencyRecord{}, false, models.ErrUnfound
+	}
+	if err != nil {
+		return models.IdempotencyRecord{}, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return dto.IdempotencyRecordDBToDomain(existing), false, nil
+}
+
+// IdempotencyComplete и IdempotencyRelease сверяют владельца: если ключ заняли заново как брошенный,
+// запоздавший первый запрос не трогает запись нового владельца
+func (p *PostgresStorage) IdempotencyComplete(ctx context.Context, userID int64, key, owner string, statusCode int, contentType string, body []byte) error {
+	if userID <= 0 || key == "" || owner == "" || statusCode <= 0 {
+		return models.ErrInvalidData
+	}
+
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	result, err := querier.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, body = $5
+		WHERE user_id = $1 AND key = $2 AND owner = $6 AND status_code = 0`,
+		userID, key, statusCode, contentType, body, owner,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	updated := result.RowsAffected()
+	if updated == 0 {
+		return models.ErrUnfound
+	}
+	return nil
+}
+
+func (p *PostgresStorage) IdempotencyRelease(ctx context.Context, userID int64, key, owner string) error {
+	if userID <= 0 || key == "" || owner == "" {
+		return models.ErrInvalidData
+	}
+
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	_, err = querier.Exec(ctx,
+		"DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND owner = $3 AND status_code = 0",
+		userID, key, owner,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresStorage) IdempotencyPrune(ctx context.Context, now time.Time) (int64, error) {
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	result, err := querier.Exec(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune idempotency keys: %w", err)
+	}
+
+	pruned := result.RowsAffected()
+	return pruned, nil
+}
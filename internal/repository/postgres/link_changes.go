@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// linkChangesChannel канал уведомлений о созданных, измененных и удаленных ссылках
+	linkChangesChannel = "link_changes"
+	// maxNotifyPayload запас до предела PostgreSQL в 8000 байт на уведомление
+	maxNotifyPayload = 7000
+	// listenHeartbeat как часто проверять соединение слушателя, пока уведомлений нет
+	listenHeartbeat = 30 * time.Second
+)
+
+// notifyLinkChanges публикует коды измененных ссылок, по одному коду на строку.
+// Внутри транзакции PostgreSQL доставит уведомления только после ее фиксации
+func (p *PostgresStorage) notifyLinkChanges(ctx context.Context, querier Querier, codes []string) error {
+	for _, payload := range linkChangePayloads(codes) {
+		if _, err := querier.Exec(ctx, "SELECT pg_notify($1, $2)", linkChangesChannel, payload); err != nil {
+			return fmt.Errorf("failed to notify link changes: %w", err)
+		}
+	}
+	return nil
+}
+
+// linkChangePayloads делит коды на уведомления, помещающиеся в предел размера
+func linkChangePayloads(codes []string) []string {
+	var (
+		payloads []string
+		current  strings.Builder
+	)
+	for _, code := range codes {
+		if code == "" {
+			continue
+		}
+		if current.Len() > 0 && current.Len()+1+len(code) > maxNotifyPayload {
+			payloads = append(payloads, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteByte('\n')
+		}
+		current.WriteString(code)
+	}
+	if current.Len() > 0 {
+		payloads = append(payloads, current.String())
+	}
+	return payloads
+}
+
+// ShortenedLinkListenChanges слушает изменения ссылок на отдельном соединении вне пула.
+// ready вызывается, когда подписка оформлена, onChange - на каждое уведомление.
+// Возвращает ошибку при разрыве соединения, nil - после отмены ctx
+func (p *PostgresStorage) ShortenedLinkListenChanges(ctx context.Context, ready func(), onChange func(codes []string)) error {
+	conn, err := pgx.ConnectConfig(ctx, p.pool.Config().ConnConfig.Copy())
+	if err != nil {
+		return fmt.Errorf("failed to connect listener: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+linkChangesChannel); err != nil {
+		return fmt.Errorf("failed to listen for link changes: %w", err)
+	}
+	ready()
+
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, listenHeartbeat)
+		notification, err := conn.WaitForNotification(waitCtx)
+		cancel()
+
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err == nil:
+			onChange(strings.Split(notification.Payload, "\n"))
+		case pgconn.Timeout(err) || errors.Is(err, context.DeadlineExceeded):
+			// Уведомлений давно не было, молчащий разрыв соединения обнаружит только запрос
+			pingCtx, cancel := context.WithTimeout(ctx, storagePingTimeout)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil && ctx.Err() == nil {
+				return fmt.Errorf("link changes listener connection lost: %w", err)
+			}
+		default:
+			return fmt.Errorf("failed to wait for link changes: %w", err)
+		}
+	}
+}
@@ -2,83 +2,197 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 	"urlshortener/internal/domain/models"
 	"urlshortener/internal/repository/dto"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
 )
 
 const (
 	storageMaxOpenConnections     = 5
-	storageMaxIdleConnections     = 2
 	storageConnectionsMaxIdleTime = 2 * time.Minute
 	storageConnectionsLifetime    = 30 * time.Minute
 	storagePingTimeout            = 5 * time.Second
 )
 
+// shortenedLinkColumns - порядок колонок urls, который ожидает scanShortLink
+const shortenedLinkColumns = "id, short_key, original_url, user_id, is_deleted, created_at, title, redirect_status, pass_query, pass_path, " +
+	"COALESCE(utm_template_id, 0), redirect_rules, variants, sticky_variants, active_from, active_until, moderation"
+
+// shortenedLinkInsertColumns - колонки, заполняемые при создании ссылки, в порядке shortLinkInsertArgs.
+// Несуществующий UTM-шаблон (например, при восстановлении из файла) превращается в NULL
+const (
+	shortenedLinkInsertColumns = "short_key, original_url, user_id, created_at, title, redirect_status, pass_query, pass_path, utm_template_id, redirect_rules, " +
+		"variants, sticky_variants, active_from, active_until, moderation"
+	shortenedLinkInsertValues = "$1, $2, $3, $4, $5, $6, $7, $8, (SELECT id FROM utm_templates WHERE id = $9), $10, $11, $12, $13, $14, $15"
+)
+
 type PostgresStorage struct {
-	db  *sql.DB
-	txm TxManager
-	que Querier
+	pool     *pgxpool.Pool
+	replicas *replicaSet
+	txm      TxManager
 }
 
 type TxManager interface {
-	// Если opts == nil, применяется по умолчанию уровень ReadCommitted
-	WithTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error
+	// Если opts == nil, применяется по умолчанию уровень Serializable с повтором конфликтов по DefaultRetryPolicy
+	WithTx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context) error) error
 	GetQuerier(ctx context.Context) (Querier, error)
+	// Retries сколько раз транзакции повторялись после конфликтов
+	Retries() uint64
 }
 
 // Querier предоставляет единый интерфейс для SQL-запросов,
 // автоматически работающий как с транзакциями, так и с обычными соединениями.
 // Избавляет от ручных проверок контекста транзакций.
+// Ему удовлетворяют и *pgxpool.Pool, и pgx.Tx
 type Querier interface {
-	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
-	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
-	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRow(ctx context.Context, query string, args ...any) pgx.Row
+	Query(ctx context.Context, query string, args ...any) (pgx.Rows, error)
+	Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// PoolConfig настройки пула соединений. Нулевые поля берутся из параметров DSN
+// (pool_max_conns, pool_min_conns, pool_max_conn_lifetime, pool_max_conn_idle_time,
+// default_query_exec_mode), а если их там нет - из значений по умолчанию хранилища
+type PoolConfig struct {
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+	QueryExecMode   string // cache_statement, cache_describe, describe_exec, exec или simple_protocol
 }
 
-func NewStorage(ctx context.Context, dsn string) (*PostgresStorage, error) {
-	db, err := sql.Open("pgx", dsn)
+func NewStorage(ctx context.Context, log *zerolog.Logger, dsn string, cfg PoolConfig, replicaCfg ReplicaConfig) (*PostgresStorage, error) {
+	poolConfig, err := newPoolConfig(dsn, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	initConnectionPools(db)
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
 
 	ctxPing, cancel := context.WithTimeout(ctx, storagePingTimeout)
 	defer cancel()
 
-	if err := db.PingContext(ctxPing); err != nil {
-		_ = db.Close()
+	if err := pool.Ping(ctxPing); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	replicas, err := newReplicaSet(ctx, log, pool, replicaCfg, cfg)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
 	return &PostgresStorage{
-		db:  db,
-		txm: NewSQLTxManager(db),
+		pool:     pool,
+		replicas: replicas,
+		txm:      NewPoolTxManager(pool, log),
 	}, nil
 }
 
-func initConnectionPools(db *sql.DB) {
-	db.SetMaxOpenConns(storageMaxOpenConnections)
-	db.SetMaxIdleConns(storageMaxIdleConnections)
-	db.SetConnMaxIdleTime(storageConnectionsMaxIdleTime)
-	db.SetConnMaxLifetime(storageConnectionsLifetime)
+// newPoolConfig разбирает DSN и накладывает на него настройки пула
+func newPoolConfig(dsn string, cfg PoolConfig) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database dsn: %w", err)
+	}
+
+	switch {
+	case cfg.MaxConns > 0:
+		poolConfig.MaxConns = cfg.MaxConns
+	case !dsnHasParam(dsn, "pool_max_conns"):
+		poolConfig.MaxConns = storageMaxOpenConnections
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = cfg.MinConns
+	}
+	if poolConfig.MinConns > poolConfig.MaxConns {
+		poolConfig.MinConns = poolConfig.MaxConns
+	}
+	switch {
+	case cfg.MaxConnLifetime > 0:
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	case !dsnHasParam(dsn, "pool_max_conn_lifetime"):
+		poolConfig.MaxConnLifetime = storageConnectionsLifetime
+	}
+	switch {
+	case cfg.MaxConnIdleTime > 0:
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	case !dsnHasParam(dsn, "pool_max_conn_idle_time"):
+		poolConfig.MaxConnIdleTime = storageConnectionsMaxIdleTime
+	}
+
+	if cfg.QueryExecMode != "" {
+		mode, ok := queryExecModes[cfg.QueryExecMode]
+		if !ok {
+			return nil, fmt.Errorf("unknown query exec mode %q", cfg.QueryExecMode)
+		}
+		poolConfig.ConnConfig.DefaultQueryExecMode = mode
+	}
+	return poolConfig, nil
+}
+
+// queryExecModes режимы выполнения запросов pgx по их имени в DSN
+var queryExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
 }
 
-// Выполняет функцию в транзакции, если opts == nil, применяется по умолчанию уровень ReadCommitted
+// dsnHasParam сообщает, задан ли параметр в DSN вида URL или key=value
+func dsnHasParam(dsn, name string) bool {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		return err == nil && u.Query().Has(name)
+	}
+	for _, field := range strings.Fields(dsn) {
+		if key, _, ok := strings.Cut(field, "="); ok && strings.TrimSpace(key) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Выполняет функцию в транзакции уровня Serializable, конфликты с другими транзакциями повторяются.
+// Другие настройки можно передать через WithTxOptions
 func (p *PostgresStorage) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
-	return p.txm.WithTx(ctx, nil, fn)
+	return p.txm.WithTx(ctx, txOptionsFromContext(ctx), fn)
+}
+
+// WithinTxOptions выполняет функцию в транзакции с заданными уровнем изоляции и повторами.
+// Если opts == nil, действуют настройки WithinTx
+func (p *PostgresStorage) WithinTxOptions(ctx context.Context, opts *TxOptions, fn func(ctx context.Context) error) error {
+	if opts == nil {
+		opts = txOptionsFromContext(ctx)
+	}
+	return p.txm.WithTx(ctx, opts, fn)
+}
+
+// TxRetries сколько раз транзакции повторялись после конфликтов с момента запуска
+func (p *PostgresStorage) TxRetries() uint64 {
+	return p.txm.Retries()
 }
 
 // Возвращает унифицированный интерфейс для выполнения SQL-запросов.
 // Автоматически определяет контекст транзакции и возвращает соответствующий Querier:
-// - TxQuerier если выполняется транзакция
-// - SQLQuerier если транзакции нет
+// - pgx.Tx если выполняется транзакция
+// - пул соединений если транзакции нет
 func (p *PostgresStorage) GetQuerier(ctx context.Context) (Querier, error) {
 	return p.txm.GetQuerier(ctx)
 }
@@ -91,7 +205,7 @@ func (p *PostgresStorage) UserCreate(ctx context.Context, user models.User) (mod
 		return models.User{}, fmt.Errorf("failed to get querier: %w", err)
 	}
 
-	err = querier.QueryRowContext(ctx,
+	err = querier.QueryRow(ctx,
 		`INSERT INTO users(created_at) VALUES ($1) RETURNING id`,
 		userDB.CreatedAt).Scan(&userDB.ID)
 
@@ -118,12 +232,12 @@ func (p *PostgresStorage) UserGetByID(ctx context.Context, id int64) (models.Use
 		return models.User{}, fmt.Errorf("failed to get querier: %w", err)
 	}
 
-	err = querier.QueryRowContext(ctx,
+	err = querier.QueryRow(ctx,
 		`SELECT id, created_at FROM users WHERE id = $1`, id).
 		Scan(&userDB.ID, &userDB.CreatedAt)
 
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return models.User{}, models.ErrUnfound
 		}
 		return models.User{}, fmt.Errorf("failed to get user: %w", err)
@@ -137,14 +251,14 @@ func (p *PostgresStorage) ShortenedLinkGetBatchByUser(ctx context.Context, id in
 		return nil, models.ErrInvalidData
 	}
 
-	querier, err := p.GetQuerier(ctx)
+	querier, err := p.GetReadQuerier(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get querier: %w", err)
 	}
 
 	// Для списка пользователя возвращаем только НЕудаленные ссылки
-	rows, err := querier.QueryContext(ctx,
-		`SELECT id, short_key, original_url, user_id, is_deleted, created_at 
+	rows, err := querier.Query(ctx,
+		`SELECT `+shortenedLinkColumns+`
          FROM urls 
          WHERE user_id = $1 AND is_deleted = false
          ORDER BY created_at`, id)
@@ -163,24 +277,22 @@ func (p *PostgresStorage) ShortenedLinkCreate(ctx context.Context, url models.Sh
 	}
 
 	dbURL := dto.ShortenedLinkDBFromDomain(url)
-	var dbResult dto.ShortenedLinkDB
 
 	// Пытаемся вставить запись, при конфликте НИЧЕГО не делаем
-	err = querier.QueryRowContext(ctx, `
-        INSERT INTO urls (short_key, original_url, user_id, created_at)
-        VALUES ($1, $2, $3, $4)
+	dbResult, err := scanShortLink(querier.QueryRow(ctx, `
+        INSERT INTO urls (`+shortenedLinkInsertColumns+`)
+        VALUES (`+shortenedLinkInsertValues+`)
         ON CONFLICT (original_url) DO NOTHING
-        RETURNING id, short_key, original_url, user_id, created_at`,
-		dbURL.ShortCode, dbURL.OriginalURL, dbURL.UserID, dbURL.CreatedAt,
-	).Scan(&dbResult.ID, &dbResult.ShortCode, &dbResult.OriginalURL, &dbResult.UserID, &dbResult.CreatedAt)
+        RETURNING `+shortenedLinkColumns,
+		shortLinkInsertArgs(dbURL)...,
+	))
 
-	if errors.Is(err, sql.ErrNoRows) {
+	if errors.Is(err, pgx.ErrNoRows) {
 		// Конфликт - запись уже существует, возвращаем существующую
-		var existing dto.ShortenedLinkDB
-		err := querier.QueryRowContext(ctx,
-			"SELECT id, short_key, original_url, user_id, created_at FROM urls WHERE original_url = $1 AND is_deleted = false",
+		existing, err := scanShortLink(querier.QueryRow(ctx,
+			"SELECT "+shortenedLinkColumns+" FROM urls WHERE original_url = $1 AND is_deleted = false",
 			url.OriginalURL,
-		).Scan(&existing.ID, &existing.ShortCode, &existing.OriginalURL, &existing.UserID, &existing.CreatedAt)
+		))
 
 		if err != nil {
 			return models.ShortenedLink{}, fmt.Errorf("failed to get existing URL: %w", err)
@@ -192,6 +304,11 @@ func (p *PostgresStorage) ShortenedLinkCreate(ctx context.Context, url models.Sh
 		return models.ShortenedLink{}, fmt.Errorf("database error: %w", err)
 	}
 
+	// Другие экземпляры могли запомнить, что такого кода нет
+	if err := p.notifyLinkChanges(ctx, querier, []string{dbResult.ShortCode}); err != nil {
+		return models.ShortenedLink{}, err
+	}
+
 	return dto.ShortenedLinkDBToDomain(dbResult), nil
 }
 
@@ -200,21 +317,19 @@ func (p *PostgresStorage) ShortenedLinkGetByShortKey(ctx context.Context, shortK
 		return models.ShortenedLink{}, models.ErrInvalidData
 	}
 
-	var result dto.ShortenedLinkDB
-
-	querier, err := p.GetQuerier(ctx)
+	querier, err := p.GetReadQuerier(ctx)
 	if err != nil {
 		return models.ShortenedLink{}, fmt.Errorf("failed to get querier: %w", err)
 	}
 
 	// ВАЖНО: получаем ВСЕ записи (включая удаленные), чтобы можно было проверить флаг is_deleted
-	err = querier.QueryRowContext(ctx,
-		"SELECT id, short_key, original_url, user_id, is_deleted, created_at FROM urls WHERE short_key = $1",
+	result, err := scanShortLink(querier.QueryRow(ctx,
+		"SELECT "+shortenedLinkColumns+" FROM urls WHERE short_key = $1",
 		shortKey,
-	).Scan(&result.ID, &result.ShortCode, &result.OriginalURL, &result.UserID, &result.DeletedFlag, &result.CreatedAt)
+	))
 
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return models.ShortenedLink{}, models.ErrUnfound
 		}
 		return models.ShortenedLink{}, fmt.Errorf("failed to get URL: %w", err)
@@ -229,21 +344,19 @@ func (p *PostgresStorage) ShortenedLinkGetByOriginalURL(ctx context.Context, ori
 		return models.ShortenedLink{}, models.ErrInvalidData
 	}
 
-	var result dto.ShortenedLinkDB
-
 	querier, err := p.GetQuerier(ctx)
 	if err != nil {
 		return models.ShortenedLink{}, fmt.Errorf("failed to get querier: %w", err)
 	}
 
 	// Для поиска по оригинальному URL тоже получаем все записи
-	err = querier.QueryRowContext(ctx,
-		"SELECT id, short_key, original_url, user_id, is_deleted, created_at FROM urls WHERE original_url = $1",
+	result, err := scanShortLink(querier.QueryRow(ctx,
+		"SELECT "+shortenedLinkColumns+" FROM urls WHERE original_url = $1",
 		originalURL,
-	).Scan(&result.ID, &result.ShortCode, &result.OriginalURL, &result.UserID, &result.DeletedFlag, &result.CreatedAt)
+	))
 
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return models.ShortenedLink{}, models.ErrUnfound
 		}
 		return models.ShortenedLink{}, fmt.Errorf("failed to get URL: %w", err)
@@ -252,57 +365,111 @@ func (p *PostgresStorage) ShortenedLinkGetByOriginalURL(ctx context.Context, ori
 	return dto.ShortenedLinkDBToDomain(result), nil
 }
 
+// ShortenedLinkBatchCreate сохраняет ссылки одной транзакцией. Для адресов, которые уже сокращены,
+// возвращаются существующие ссылки, порядок результата совпадает с порядком urls.
+// Большие пакеты загружаются через COPY, см. shortenedLinkCopyInsert
 func (p *PostgresStorage) ShortenedLinkBatchCreate(ctx context.Context, urls []models.ShortenedLink) ([]models.ShortenedLink, error) {
 	var result []models.ShortenedLink
 
-	err := p.txm.WithTx(ctx, nil, func(txCtx context.Context) error {
+	err := p.txm.WithTx(ctx, txOptionsFromContext(ctx), func(txCtx context.Context) error {
 		querier, err := p.GetQuerier(txCtx)
 		if err != nil {
 			return fmt.Errorf("failed to get querier: %w", err)
 		}
 
-		for _, url := range urls {
-			if err := txCtx.Err(); err != nil {
-				return fmt.Errorf("operation canceled: %w", err)
-			}
+		var created []string
+		if len(urls) >= batchCopyThreshold {
+			result, created, err = p.shortenedLinkCopyInsert(txCtx, querier, urls)
+		} else {
+			result, created, err = p.shortenedLinkInsertEach(txCtx, querier, urls)
+		}
+		if err != nil {
+			return err
+		}
+		return p.notifyLinkChanges(txCtx, querier, created)
+	})
 
-			var dbURL dto.ShortenedLinkDB
-			err := querier.QueryRowContext(txCtx, `
-				INSERT INTO urls (short_key, original_url, user_id, created_at)
-				VALUES ($1, $2, $3, $4)
-				ON CONFLICT (original_url) DO NOTHING
-				RETURNING id, short_key, original_url, user_id, created_at`,
-				url.ShortCode, url.OriginalURL, url.UserID, url.CreatedAt,
-			).Scan(&dbURL.ID, &dbURL.ShortCode, &dbURL.OriginalURL, &dbURL.UserID, &dbURL.CreatedAt)
-
-			if errors.Is(err, sql.ErrNoRows) {
-				var existing dto.ShortenedLinkDB
-				err := querier.QueryRowContext(txCtx,
-					"SELECT id, short_key, original_url, user_id, created_at FROM urls WHERE original_url = $1 AND is_deleted = false",
-					url.OriginalURL,
-				).Scan(&existing.ID, &existing.ShortCode, &existing.OriginalURL, &existing.UserID, &existing.CreatedAt)
-
-				if err != nil {
-					return fmt.Errorf("failed to get existing URL: %w", err)
-				}
-				result = append(result, dto.ShortenedLinkDBToDomain(existing))
-				continue
-			}
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// shortenedLinkInsertEach вставляет ссылки по одной, возвращает результат и коды созданных ссылок
+func (p *PostgresStorage) shortenedLinkInsertEach(ctx context.Context, querier Querier, urls []models.ShortenedLink) ([]models.ShortenedLink, []string, error) {
+	result := make([]models.ShortenedLink, 0, len(urls))
+	var created []string
+
+	for _, url := range urls {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, fmt.Errorf("operation canceled: %w", err)
+		}
+
+		dbURL, err := scanShortLink(querier.QueryRow(ctx, `
+			INSERT INTO urls (`+shortenedLinkInsertColumns+`)
+			VALUES (`+shortenedLinkInsertValues+`)
+			ON CONFLICT (original_url) DO NOTHING
+			RETURNING `+shortenedLinkColumns,
+			shortLinkInsertArgs(dto.ShortenedLinkDBFromDomain(url))...,
+		))
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			existing, err := scanShortLink(querier.QueryRow(ctx,
+				"SELECT "+shortenedLinkColumns+" FROM urls WHERE original_url = $1 AND is_deleted = false",
+				url.OriginalURL,
+			))
 
 			if err != nil {
-				return fmt.Errorf("failed to insert URL: %w", err)
+				return nil, nil, fmt.Errorf("failed to get existing URL: %w", err)
 			}
+			result = append(result, dto.ShortenedLinkDBToDomain(existing))
+			continue
+		}
 
-			result = append(result, dto.ShortenedLinkDBToDomain(dbURL))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to insert URL: %w", err)
 		}
-		return nil
-	})
 
+		result = append(result, dto.ShortenedLinkDBToDomain(dbURL))
+		created = append(created, dbURL.ShortCode)
+	}
+	return result, created, nil
+}
+
+// ShortenedLinkUpdate обновляет изменяемые настройки активной ссылки владельца
+func (p *PostgresStorage) ShortenedLinkUpdate(ctx context.Context, url models.ShortenedLink) (models.ShortenedLink, error) {
+	if url.ShortCode == "" || url.UserID <= 0 {
+		return models.ShortenedLink{}, models.ErrInvalidData
+	}
+
+	querier, err := p.GetQuerier(ctx)
 	if err != nil {
-		return nil, err
+		return models.ShortenedLink{}, fmt.Errorf("failed to get querier: %w", err)
 	}
 
-	return result, nil
+	dbURL := dto.ShortenedLinkDBFromDomain(url)
+	result, err := scanShortLink(querier.QueryRow(ctx, `
+		UPDATE urls SET title = $1, redirect_status = $2, pass_query = $3, pass_path = $4,
+			utm_template_id = (SELECT id FROM utm_templates WHERE id = $5 AND user_id = $7), redirect_rules = $8,
+			variants = $9, sticky_variants = $10, active_from = $11, active_until = $12
+		WHERE short_key = $6 AND user_id = $7 AND is_deleted = false
+		RETURNING `+shortenedLinkColumns,
+		dbURL.Title, dbURL.RedirectStatus, dbURL.PassQuery, dbURL.PassPath, dbURL.UTMTemplateID, dbURL.ShortCode, dbURL.UserID,
+		dbURL.RedirectRules, dbURL.Variants, dbURL.StickyVariants, dbURL.ActiveFrom, dbURL.ActiveUntil,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ShortenedLink{}, models.ErrUnfound
+		}
+		return models.ShortenedLink{}, fmt.Errorf("failed to update URL: %w", err)
+	}
+
+	if err := p.notifyLinkChanges(ctx, querier, []string{result.ShortCode}); err != nil {
+		return models.ShortenedLink{}, err
+	}
+
+	return dto.ShortenedLinkDBToDomain(result), nil
 }
 
 // List остается без изменений
@@ -311,13 +478,13 @@ func (p *PostgresStorage) List(ctx context.Context, limit, offset int) ([]models
 		return nil, models.ErrInvalidData
 	}
 
-	querier, err := p.GetQuerier(ctx)
+	querier, err := p.GetReadQuerier(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get querier: %w", err)
 	}
 
-	rows, err := querier.QueryContext(ctx,
-		"SELECT id, short_key, original_url, user_id, created_at FROM urls WHERE is_deleted = false ORDER BY created_at DESC LIMIT $1 OFFSET $2",
+	rows, err := querier.Query(ctx,
+		"SELECT "+shortenedLinkColumns+" FROM urls WHERE is_deleted = false ORDER BY created_at DESC LIMIT $1 OFFSET $2",
 		limit, offset,
 	)
 	if err != nil {
@@ -329,20 +496,18 @@ func (p *PostgresStorage) List(ctx context.Context, limit, offset int) ([]models
 }
 
 func (p *PostgresStorage) Exists(ctx context.Context, originalURL string) (models.ShortenedLink, error) {
-	var dbURL dto.ShortenedLinkDB
-
 	querier, err := p.GetQuerier(ctx)
 	if err != nil {
 		return models.ShortenedLink{}, fmt.Errorf("failed to get querier: %w", err)
 	}
 
-	err = querier.QueryRowContext(ctx,
-		"SELECT id, short_key, original_url, user_id, is_deleted, created_at FROM urls WHERE original_url = $1",
+	dbURL, err := scanShortLink(querier.QueryRow(ctx,
+		"SELECT "+shortenedLinkColumns+" FROM urls WHERE original_url = $1",
 		originalURL,
-	).Scan(&dbURL.ID, &dbURL.ShortCode, &dbURL.OriginalURL, &dbURL.UserID, &dbURL.DeletedFlag, &dbURL.CreatedAt)
+	))
 
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return models.ShortenedLink{}, nil
 		}
 		return models.ShortenedLink{}, fmt.Errorf("failed to check URL existence: %w", err)
@@ -362,8 +527,8 @@ func (p *PostgresStorage) ShortenedLinkBatchExists(ctx context.Context, original
 	}
 
 	// Для проверки существования проверяем только активные ссылки
-	rows, err := querier.QueryContext(ctx,
-		"SELECT id, short_key, original_url, user_id, is_deleted, created_at FROM urls WHERE original_url = ANY($1) AND is_deleted = false",
+	rows, err := querier.Query(ctx,
+		"SELECT "+shortenedLinkColumns+" FROM urls WHERE original_url = ANY($1) AND is_deleted = false",
 		originalURLs,
 	)
 	if err != nil {
@@ -371,39 +536,24 @@ func (p *PostgresStorage) ShortenedLinkBatchExists(ctx context.Context, original
 	}
 	defer rows.Close()
 
-	var result []models.ShortenedLink
-	for rows.Next() {
-		if err := ctx.Err(); err != nil {
-			return nil, fmt.Errorf("operation canceled: %w", err)
-		}
-
-		var dbURL dto.ShortenedLinkDB
-		if err := rows.Scan(&dbURL.ID, &dbURL.ShortCode, &dbURL.OriginalURL, &dbURL.UserID, &dbURL.DeletedFlag, &dbURL.CreatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan URL row: %w", err)
-		}
-		result = append(result, dto.ShortenedLinkDBToDomain(dbURL))
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows iteration error: %w", err)
-	}
-
-	return result, nil
+	return p.scanShortLinks(ctx, rows)
 }
 
 func (p *PostgresStorage) Ping(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, storagePingTimeout)
 	defer cancel()
 
-	return p.db.PingContext(ctx)
+	return p.pool.Ping(ctx)
 }
 
 func (p *PostgresStorage) Close() error {
-	return p.db.Close()
+	p.replicas.close()
+	p.pool.Close()
+	return nil
 }
 
 // scanShortLinks - утилитарный метод для сканирования результатов запроса URL
-func (p *PostgresStorage) scanShortLinks(ctx context.Context, rows *sql.Rows) ([]models.ShortenedLink, error) {
+func (p *PostgresStorage) scanShortLinks(ctx context.Context, rows pgx.Rows) ([]models.ShortenedLink, error) {
 	var shortLinks []models.ShortenedLink
 
 	for rows.Next() {
@@ -411,15 +561,8 @@ func (p *PostgresStorage) scanShortLinks(ctx context.Context, rows *sql.Rows) ([
 			return nil, fmt.Errorf("operation canceled: %w", err)
 		}
 
-		var linkDB dto.ShortenedLinkDB
-		if err := rows.Scan(
-			&linkDB.ID,
-			&linkDB.ShortCode,
-			&linkDB.OriginalURL,
-			&linkDB.UserID,
-			&linkDB.DeletedFlag,
-			&linkDB.CreatedAt,
-		); err != nil {
+		linkDB, err := scanShortLink(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan link: %w", err)
 		}
 		shortLinks = append(shortLinks, dto.ShortenedLinkDBToDomain(linkDB))
@@ -432,6 +575,59 @@ func (p *PostgresStorage) scanShortLinks(ctx context.Context, rows *sql.Rows) ([
 	return shortLinks, nil
 }
 
+// rowScanner - общий интерфейс pgx.Row и pgx.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanShortLink сканирует строку, выбранную в порядке shortenedLinkColumns.
+// extra - приемники для колонок, выбранных после колонок ссылки
+func scanShortLink(row rowScanner, extra ...interface{}) (dto.ShortenedLinkDB, error) {
+	var linkDB dto.ShortenedLinkDB
+	dest := []interface{}{
+		&linkDB.ID,
+		&linkDB.ShortCode,
+		&linkDB.OriginalURL,
+		&linkDB.UserID,
+		&linkDB.DeletedFlag,
+		&linkDB.CreatedAt,
+		&linkDB.Title,
+		&linkDB.RedirectStatus,
+		&linkDB.PassQuery,
+		&linkDB.PassPath,
+		&linkDB.UTMTemplateID,
+		&linkDB.RedirectRules,
+		&linkDB.Variants,
+		&linkDB.StickyVariants,
+		&linkDB.ActiveFrom,
+		&linkDB.ActiveUntil,
+		&linkDB.Moderation,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return linkDB, err
+}
+
+// shortLinkInsertArgs возвращает аргументы в порядке shortenedLinkInsertColumns
+func shortLinkInsertArgs(link dto.ShortenedLinkDB) []interface{} {
+	return []interface{}{
+		link.ShortCode,
+		link.OriginalURL,
+		link.UserID,
+		link.CreatedAt,
+		link.Title,
+		link.RedirectStatus,
+		link.PassQuery,
+		link.PassPath,
+		link.UTMTemplateID,
+		link.RedirectRules,
+		link.Variants,
+		link.StickyVariants,
+		link.ActiveFrom,
+		link.ActiveUntil,
+		link.Moderation,
+	}
+}
+
 func (p *PostgresStorage) ShortenedLinkBatchDelete(ctx context.Context, id int64, shortCode []string) error {
 	if id <= 0 || len(shortCode) == 0 {
 		return models.ErrInvalidData
@@ -442,16 +638,98 @@ func (p *PostgresStorage) ShortenedLinkBatchDelete(ctx context.Context, id int64
 		return fmt.Errorf("failed to get querier: %w", err)
 	}
 
-	query := "UPDATE urls SET is_deleted = true, deleted_at = $1 WHERE user_id = $2 AND short_key = ANY($3::text[]) AND is_deleted = false"
-	result, err := querier.ExecContext(ctx, query, time.Now().UTC(), id, shortCode)
+	query := "UPDATE urls SET is_deleted = true, deleted_at = $1 WHERE user_id = $2 AND short_key = ANY($3::text[]) AND is_deleted = false RETURNING short_key"
+	rows, err := querier.Query(ctx, query, time.Now().UTC(), id, shortCode)
 	if err != nil {
 		return fmt.Errorf("failed to batch delete URLs: %w", err)
 	}
+	defer rows.Close()
 
-	_, err = result.RowsAffected()
+	deleted := make([]string, 0, len(shortCode))
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return fmt.Errorf("failed to scan deleted URL: %w", err)
+		}
+		deleted = append(deleted, code)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate deleted URLs: %w", err)
+	}
+
+	return p.notifyLinkChanges(ctx, querier, deleted)
+}
+
+// ShortenedLinkVariantAddClicks добавляет накопленные переходы к счетчикам вариантов одним запросом.
+// Переходы по ссылкам, которых уже нет в базе, отбрасываются
+func (p *PostgresStorage) ShortenedLinkVariantAddClicks(ctx context.Context, clicks []models.VariantClicks) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	linkIDs := make([]int64, len(clicks))
+	variants := make([]string, len(clicks))
+	counts := make([]int64, len(clicks))
+	for i, c := range clicks {
+		if c.LinkID <= 0 || c.Variant == "" || c.Clicks <= 0 {
+			return models.ErrInvalidData
+		}
+		linkIDs[i], variants[i], counts[i] = c.LinkID, c.Variant, c.Clicks
+	}
+
+	querier, err := p.GetQuerier(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	_, err = querier.Exec(ctx, `
+		INSERT INTO url_variant_clicks (url_id, variant, clicks)
+		SELECT c.url_id, c.variant, c.clicks
+		FROM unnest($1::bigint[], $2::text[], $3::bigint[]) AS c(url_id, variant, clicks)
+		JOIN urls ON urls.id = c.url_id
+		ON CONFLICT (url_id, variant) DO UPDATE SET clicks = url_variant_clicks.clicks + EXCLUDED.clicks`,
+		linkIDs, variants, counts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record variant clicks: %w", err)
 	}
 
 	return nil
 }
+
+// ShortenedLinkVariantStats возвращает переходы по вариантам ссылки
+func (p *PostgresStorage) ShortenedLinkVariantStats(ctx context.Context, linkID int64) (map[string]int64, error) {
+	if linkID <= 0 {
+		return nil, models.ErrInvalidData
+	}
+
+	querier, err := p.GetReadQuerier(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	rows, err := querier.Query(ctx,
+		"SELECT variant, clicks FROM url_variant_clicks WHERE url_id = $1", linkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query variant stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make(map[string]int64)
+	for rows.Next() {
+		var (
+			variant string
+			clicks  int64
+		)
+		if err := rows.Scan(&variant, &clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan variant stats: %w", err)
+		}
+		stats[variant] = clicks
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return stats, nil
+}
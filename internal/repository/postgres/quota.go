@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"urlshortener/internal/domain/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// UserLockForQuota блокирует строку пользователя до конца текущей транзакции
+func (p *PostgresStorage) UserLockForQuota(ctx context.Context, userID int64) error {
+	if userID <= 0 {
+		return models.ErrInvalidData
+	}
+
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	var id int64
+	err = querier.QueryRow(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrUnfound
+		}
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	return nil
+}
+
+// ShortenedLinkCountActiveByUser возвращает число неудаленных ссылок пользователя
+func (p *PostgresStorage) ShortenedLinkCountActiveByUser(ctx context.Context, userID int64) (int, error) {
+	if userID <= 0 {
+		return 0, models.ErrInvalidData
+	}
+
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	var count int
+	err = querier.QueryRow(ctx,
+		"SELECT COUNT(*) FROM urls WHERE user_id = $1 AND is_deleted = false",
+		userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count user links: %w", err)
+	}
+
+	return count, nil
+}
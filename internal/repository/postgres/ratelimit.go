@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// rateLimitRefill - запас токенов корзины к текущему моменту с учетом пополнения, но не больше емкости
+const rateLimitRefill = "LEAST($2::float8, rate_limits.tokens + EXTRACT(EPOCH FROM now() - rate_limits.updated_at) * $3::float8)"
+
+// RateLimitTake списывает токен из общей для всех экземпляров корзины.
+// Пополнение и списание выполняются одним UPSERT, поэтому параллельные запросы не теряют списаний.
+// Колонка allowed хранит результат последнего списания, по остатку его не отличить
+func (p *PostgresStorage) RateLimitTake(ctx context.Context, key string, rate, burst float64) (bool, time.Duration, error) {
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	var (
+		tokens  float64
+		allowed bool
+	)
+	err = querier.QueryRow(ctx, `
+		INSERT INTO rate_limits (key, tokens, allowed, updated_at)
+		VALUES ($1, $2::float8 - 1, true, now())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = CASE WHEN `+rateLimitRefill+` >= 1 THEN `+rateLimitRefill+` - 1 ELSE `+rateLimitRefill+` END,
+			allowed = `+rateLimitRefill+` >= 1,
+			updated_at = now()
+		RETURNING tokens, allowed`,
+		key, burst, rate,
+	).Scan(&tokens, &allowed)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	if allowed {
+		return true, 0, nil
+	}
+	if rate <= 0 {
+		return false, time.Minute, nil
+	}
+	return false, time.Duration((1 - tokens) / rate * float64(time.Second)), nil
+}
+
+// RateLimitPrune удаляет корзины, не использованные дольше idle
+func (p *PostgresStorage) RateLimitPrune(ctx context.Context, idle time.Duration) (int64, error) {
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	result, err := querier.Exec(ctx,
+		"DELETE FROM rate_limits WHERE updated_at < now() - make_interval(secs => $1)",
+		idle.Seconds(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune rate limits: %w", err)
+	}
+
+	pruned := result.RowsAffected()
+	return pruned, nil
+}
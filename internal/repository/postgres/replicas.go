@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// DefaultReplicaCheckInterval как часто проверять реплики, если интервал не задан
+const DefaultReplicaCheckInterval = 5 * time.Second
+
+// ReplicaConfig реплики для чтения. Без DSN все запросы идут в основную базу
+type ReplicaConfig struct {
+	DSNs          []string
+	CheckInterval time.Duration // как часто проверять доступность реплик
+	MaxLag        time.Duration // реплика, отставшая больше, не получает чтений, 0 - не проверять
+}
+
+// replicaLagQuery отставание реплики в секундах. Реплика, применившая весь полученный журнал,
+// не отстает, даже если в основной базе давно не было записей. На основной базе функции
+// восстановления возвращают NULL, и отставание тоже 0
+const replicaLagQuery = `SELECT CASE
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END::float8`
+
+// replica пул соединений с одной репликой
+type replica struct {
+	host    string // для логов, без пароля из DSN
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// replicaSet раздает чтения по доступным репликам по кругу, без доступных - основной базе
+type replicaSet struct {
+	primary  *pgxpool.Pool
+	replicas []*replica
+	interval time.Duration
+	maxLag   time.Duration
+	next     atomic.Uint64
+}
+
+// newReplicaSet открывает пулы реплик с настройками основного пула и сразу проверяет их.
+// Недоступная реплика не мешает запуску, проверки вернут ее, когда она поднимется
+func newReplicaSet(ctx context.Context, log *zerolog.Logger, primary *pgxpool.Pool, cfg ReplicaConfig, poolCfg PoolConfig) (*replicaSet, error) {
+	set := &replicaSet{
+		primary:  primary,
+		interval: cfg.CheckInterval,
+		maxLag:   cfg.MaxLag,
+	}
+	if set.interval <= 0 {
+		set.interval = DefaultReplicaCheckInterval
+	}
+
+	for _, dsn := range cfg.DSNs {
+		poolConfig, err := newPoolConfig(dsn, poolCfg)
+		if err != nil {
+			set.close()
+			return nil, fmt.Errorf("invalid replica: %w", err)
+		}
+		pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+		if err != nil {
+			set.close()
+			return nil, fmt.Errorf("failed to open replica %s: %w", poolConfig.ConnConfig.Host, err)
+		}
+		set.replicas = append(set.replicas, &replica{host: poolConfig.ConnConfig.Host, pool: pool})
+	}
+
+	set.check(ctx, nil)
+	for _, r := range set.replicas {
+		if !r.healthy.Load() {
+			log.
+				Warn().
+				Str("replica", r.host).
+				Msg("PostgreSQL replica is unavailable or lagging at startup, its reads go elsewhere until it catches up")
+		}
+	}
+	return set, nil
+}
+
+// reader возвращает пул для чтения вне транзакции
+func (s *replicaSet) reader() Querier {
+	n := len(s.replicas)
+	if n == 0 {
+		return s.primary
+	}
+	start := int(s.next.Add(1) % uint64(n))
+	for i := range n {
+		if r := s.replicas[(start+i)%n]; r.healthy.Load() {
+			return r.pool
+		}
+	}
+	return s.primary
+}
+
+// run проверяет реплики каждые interval до отмены ctx
+func (s *replicaSet) run(ctx context.Context, log *zerolog.Logger) {
+	if len(s.replicas) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check(ctx, log)
+		}
+	}
+}
+
+// check проверяет реплики параллельно и логирует смену их доступности
+func (s *replicaSet) check(ctx context.Context, log *zerolog.Logger) {
+	var wg sync.WaitGroup
+	for _, r := range s.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			pingCtx, cancel := context.WithTimeout(ctx, storagePingTimeout)
+			err := s.checkReplica(pingCtx, r)
+			cancel()
+			if err != nil && ctx.Err() != nil {
+				return
+			}
+
+			healthy := err == nil
+			if r.healthy.Swap(healthy) == healthy || log == nil {
+				return
+			}
+			if healthy {
+				log.
+					Info().
+					Str("replica", r.host).
+					Msg("PostgreSQL replica is back, routing reads to it")
+			} else {
+				log.
+					Warn().
+					Err(err).
+					Str("replica", r.host).
+					Msg("PostgreSQL replica is unavailable or lagging, routing its reads elsewhere")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// checkReplica запрашивает отставание реплики, запрос заодно проверяет ее доступность
+func (s *replicaSet) checkReplica(ctx context.Context, r *replica) error {
+	var seconds float64
+	if err := r.pool.QueryRow(ctx, replicaLagQuery).Scan(&seconds); err != nil {
+		return err
+	}
+	if lag := time.Duration(seconds * float64(time.Second)); s.maxLag > 0 && lag > s.maxLag {
+		return fmt.Errorf("replication lag %s exceeds %s", lag.Round(time.Millisecond), s.maxLag)
+	}
+	return nil
+}
+
+func (s *replicaSet) close() {
+	for _, r := range s.replicas {
+		r.pool.Close()
+	}
+}
+
+// RunReplicaChecks следит за доступностью реплик до отмены ctx. Без реплик сразу возвращается
+func (p *PostgresStorage) RunReplicaChecks(ctx context.Context, log *zerolog.Logger) {
+	p.replicas.run(ctx, log)
+}
+
+// WithPrimary направляет чтения с этим контекстом в основную базу. Нужен тем, кто надолго
+// запоминает прочитанное, например кэшу: версия с отстающей реплики прожила бы в нем весь TTL
+func (p *PostgresStorage) WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keyPrimary, true)
+}
+
+// GetReadQuerier возвращает Querier для запросов только на чтение: внутри транзакции - ее саму,
+// иначе - доступную реплику или основную базу. Реплика может отставать, поэтому чтения,
+// которые должны увидеть только что записанное, используют GetQuerier или WithPrimary
+func (p *PostgresStorage) GetReadQuerier(ctx context.Context) (Querier, error) {
+	if _, ok := ctx.Value(keyTxValue).(pgx.Tx); ok {
+		return p.txm.GetQuerier(ctx)
+	}
+	if primary, _ := ctx.Value(keyPrimary).(bool); primary {
+		return p.pool, nil
+	}
+	return p.replicas.reader(), nil
+}
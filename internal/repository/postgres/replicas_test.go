@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorage_GetReadQuerier(t *testing.T) {
+	primary := &pgxpool.Pool{}
+	replicaPool := &pgxpool.Pool{}
+
+	healthy := &replica{host: "replica", pool: replicaPool}
+	healthy.healthy.Store(true)
+	unhealthy := &replica{host: "lagging", pool: &pgxpool.Pool{}}
+
+	tests := []struct {
+		name     string
+		replicas []*replica
+		ctx      context.Context
+		want     *pgxpool.Pool
+	}{
+		{
+			name:     "Чтение с доступной реплики",
+			replicas: []*replica{healthy},
+			ctx:      context.Background(),
+			want:     replicaPool,
+		},
+		{
+			name:     "Недоступная или отставшая реплика пропускается",
+			replicas: []*replica{unhealthy, healthy},
+			ctx:      context.Background(),
+			want:     replicaPool,
+		},
+		{
+			name:     "Без доступных реплик - основная база",
+			replicas: []*replica{unhealthy},
+			ctx:      context.Background(),
+			want:     primary,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &PostgresStorage{
+				pool:     primary,
+				replicas: &replicaSet{primary: primary, replicas: tt.replicas},
+			}
+
+			querier, err := storage.GetReadQuerier(tt.ctx)
+			require.NoError(t, err)
+			assert.Same(t, tt.want, querier)
+
+			// WithPrimary всегда ведет в основную базу
+			querier, err = storage.GetReadQuerier(storage.WithPrimary(tt.ctx))
+			require.NoError(t, err)
+			assert.Same(t, primary, querier)
+		})
+	}
+}
+
+func TestReplicaSet_checkReplica(t *testing.T) {
+	storage := newTestStorage(t)
+
+	// Основная база в роли реплики не отстает: функции восстановления на ней возвращают NULL
+	set := &replicaSet{maxLag: time.Nanosecond}
+	assert.NoError(t, set.checkReplica(context.Background(), &replica{host: "primary", pool: storage.pool}))
+}
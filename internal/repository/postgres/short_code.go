@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"urlshortener/internal/domain/models"
+)
+
+// ShortCodeReserve резервирует свободные коды одним INSERT: первичный ключ short_codes не дает
+// выдать код дважды даже нескольким экземплярам, коды существующих ссылок пропускаются
+func (p *PostgresStorage) ShortCodeReserve(ctx context.Context, candidates []string) ([]string, error) {
+	if len(candidates) == 0 {
+		return nil, models.ErrInvalidData
+	}
+
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	rows, err := querier.Query(ctx, `
+		INSERT INTO short_codes (code)
+		SELECT candidate FROM unnest($1::text[]) AS candidate
+		WHERE NOT EXISTS (SELECT 1 FROM urls WHERE urls.short_key = candidate)
+		ON CONFLICT (code) DO NOTHING
+		RETURNING code`,
+		candidates,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve short codes: %w", err)
+	}
+	defer rows.Close()
+
+	reserved := make([]string, 0, len(candidates))
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, fmt.Errorf("failed to scan short code: %w", err)
+		}
+		reserved = append(reserved, code)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate short codes: %w", err)
+	}
+
+	return reserved, nil
+}
+
+// ShortCodeAllocateIDs выдает блок номеров для последовательных кодов. Строка счетчика
+// блокируется на время UPDATE, поэтому блоки разных экземпляров не пересекаются
+func (p *PostgresStorage) ShortCodeAllocateIDs(ctx context.Context, n int) (int64, error) {
+	if n <= 0 {
+		return 0, models.ErrInvalidData
+	}
+
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	var first int64
+	err = querier.QueryRow(ctx, `
+		INSERT INTO short_code_counter (id, next_id) VALUES (1, $1)
+		ON CONFLICT (id) DO UPDATE SET next_id = short_code_counter.next_id + $1
+		RETURNING next_id - $1`,
+		n,
+	).Scan(&first)
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate code ids: %w", err)
+	}
+
+	return first, nil
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+// testDSNEnv база для тестов хранилища. Без нее тесты, которым нужен PostgreSQL, пропускаются
+const testDSNEnv = "TEST_DATABASE_DSN"
+
+// newTestStorage создает хранилище в отдельной схеме со свежей структурой из migrations/init.sql.
+// Схема удаляется после теста
+func newTestStorage(t *testing.T) *PostgresStorage {
+	t.Helper()
+
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close(context.Background()) })
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	_, err = conn.Exec(ctx, "CREATE SCHEMA "+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+	})
+
+	migration, err := os.ReadFile("../../../migrations/init.sql")
+	require.NoError(t, err)
+	_, err = conn.Exec(ctx, "SET search_path TO "+schema+";\n"+string(migration))
+	require.NoError(t, err)
+
+	log := zerolog.Nop()
+	storage, err := NewStorage(ctx, &log, withSearchPath(dsn, schema), PoolConfig{}, ReplicaConfig{})
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+
+	return storage
+}
+
+// withSearchPath добавляет в DSN схему по умолчанию для всех соединений пула
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		return dsn + sep + "search_path=" + schema
+	}
+	return dsn + " search_path=" + schema
+}
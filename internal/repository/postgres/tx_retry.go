@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Коды ошибок PostgreSQL, после которых транзакцию можно просто повторить
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// RetryPolicy повторы транзакции после конфликта сериализации или взаимоблокировки.
+// Пауза перед повтором удваивается от BaseDelay до MaxDelay, из нее берется случайная доля
+// не меньше половины, чтобы столкнувшиеся транзакции не повторялись одновременно
+type RetryPolicy struct {
+	MaxAttempts int           // попыток всего, 1 - без повторов, 0 - DefaultRetryPolicy
+	BaseDelay   time.Duration // пауза перед первым повтором
+	MaxDelay    time.Duration // предел паузы, 0 - пауза не растет
+}
+
+var (
+	// DefaultRetryPolicy применяется, если настройки транзакции не заданы
+	DefaultRetryPolicy = RetryPolicy{MaxAttempts: 5, BaseDelay: 10 * time.Millisecond, MaxDelay: 500 * time.Millisecond}
+	// NoRetry выполняет транзакцию один раз
+	NoRetry = RetryPolicy{MaxAttempts: 1}
+)
+
+// delay пауза перед повтором после attempt неудачных попыток
+func (r RetryPolicy) delay(attempt int) time.Duration {
+	d := r.BaseDelay
+	for i := 1; i < attempt && d < r.MaxDelay; i++ {
+		d *= 2
+	}
+	if r.MaxDelay > 0 && d > r.MaxDelay {
+		d = r.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// isRetryable сообщает, откатил ли сервер транзакцию из-за конкуренции с другими
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected
+}
@@ -0,0 +1,182 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "Конфликт сериализации",
+			err:  &pgconn.PgError{Code: pgSerializationFailure},
+			want: true,
+		},
+		{
+			name: "Взаимоблокировка",
+			err:  &pgconn.PgError{Code: pgDeadlockDetected},
+			want: true,
+		},
+		{
+			name: "Ошибка функции, обернутая при откате",
+			err:  fmt.Errorf("failed to insert URL: %w", &pgconn.PgError{Code: pgSerializationFailure}),
+			want: true,
+		},
+		{
+			name: "Ошибка фиксации",
+			err:  fmt.Errorf("failed to commit transaction: %w", &pgconn.PgError{Code: pgSerializationFailure}),
+			want: true,
+		},
+		{
+			name: "Ошибка отката вместе с конфликтом",
+			err:  fmt.Errorf("rollback error: %w, original error: %w", errors.New("conn closed"), &pgconn.PgError{Code: pgDeadlockDetected}),
+			want: true,
+		},
+		{
+			name: "Нарушение уникальности",
+			err:  &pgconn.PgError{Code: "23505"},
+			want: false,
+		},
+		{
+			name: "Не ошибка PostgreSQL",
+			err:  pgx.ErrNoRows,
+			want: false,
+		},
+		{
+			name: "Нет ошибки",
+			err:  nil,
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isRetryable(tt.err))
+		})
+	}
+}
+
+func TestRetryPolicy_delay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 10 * time.Millisecond, MaxDelay: 100 * time.Millisecond}
+
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		full    time.Duration // пауза до случайной доли
+	}{
+		{
+			name:    "Первый повтор",
+			policy:  policy,
+			attempt: 1,
+			full:    10 * time.Millisecond,
+		},
+		{
+			name:    "Пауза удваивается",
+			policy:  policy,
+			attempt: 3,
+			full:    40 * time.Millisecond,
+		},
+		{
+			name:    "Пауза не больше предела",
+			policy:  policy,
+			attempt: 5,
+			full:    100 * time.Millisecond,
+		},
+		{
+			name:    "Предел не переполняется на поздних попытках",
+			policy:  policy,
+			attempt: 100,
+			full:    100 * time.Millisecond,
+		},
+		{
+			name:    "Без предела пауза не растет",
+			policy:  RetryPolicy{BaseDelay: time.Millisecond},
+			attempt: 4,
+			full:    time.Millisecond,
+		},
+		{
+			name:    "Без паузы",
+			policy:  RetryPolicy{MaxAttempts: 3},
+			attempt: 2,
+			full:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Доля случайная, поэтому границы проверяются на многих значениях
+			for range 1000 {
+				d := tt.policy.delay(tt.attempt)
+				assert.GreaterOrEqual(t, d, tt.full/2)
+				assert.LessOrEqual(t, d, tt.full)
+			}
+		})
+	}
+}
+
+// optsTxManager запоминает настройки, с которыми хранилище начало транзакцию
+type optsTxManager struct {
+	TxManager
+	opts *TxOptions
+}
+
+func (tm *optsTxManager) WithTx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context) error) error {
+	tm.opts = opts
+	return nil
+}
+
+func TestPostgresStorage_WithinTxOptions(t *testing.T) {
+	readCommitted := TxOptions{TxOptions: pgx.TxOptions{IsoLevel: pgx.ReadCommitted}, Retry: NoRetry}
+	serializable := TxOptions{TxOptions: pgx.TxOptions{IsoLevel: pgx.Serializable}, Retry: DefaultRetryPolicy}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		opts *TxOptions
+		want *TxOptions
+	}{
+		{
+			name: "По умолчанию",
+			ctx:  context.Background(),
+			want: nil,
+		},
+		{
+			name: "Настройки из контекста",
+			ctx:  WithTxOptions(context.Background(), readCommitted),
+			want: &readCommitted,
+		},
+		{
+			name: "Явные настройки важнее контекста",
+			ctx:  WithTxOptions(context.Background(), readCommitted),
+			opts: &serializable,
+			want: &serializable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txm := &optsTxManager{}
+			storage := &PostgresStorage{txm: txm}
+
+			assert.NoError(t, storage.WithinTxOptions(tt.ctx, tt.opts, func(ctx context.Context) error { return nil }))
+			assert.Equal(t, tt.want, txm.opts)
+
+			if tt.opts == nil {
+				assert.NoError(t, storage.WithinTx(tt.ctx, func(ctx context.Context) error { return nil }))
+				assert.Equal(t, tt.want, txm.opts)
+			}
+		})
+	}
+}
@@ -2,42 +2,122 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
 )
 
 type keyTxType int
 
 const (
 	keyTxValue keyTxType = iota
+	keyTxOptions
+	keyPrimary
 )
 
 var (
 	ErrNoTransaction = errors.New("no transaction in context")
 )
 
-// SQLTxManager реализация для SQL базы данных
-type SQLTxManager struct {
-	db *sql.DB
+// TxOptions настройки транзакции
+type TxOptions struct {
+	pgx.TxOptions
+	Retry RetryPolicy
+}
+
+// WithTxOptions задает настройки транзакций, которые хранилище начнет с этим контекстом:
+// WithinTx и методы, выполняющие несколько запросов одной транзакцией. Так вызывающий код,
+// которому известно только URLStorage, может, например, отключить повторы
+func WithTxOptions(ctx context.Context, opts TxOptions) context.Context {
+	return context.WithValue(ctx, keyTxOptions, opts)
 }
 
-func NewSQLTxManager(db *sql.DB) *SQLTxManager {
-	return &SQLTxManager{
-		db: db,
+// txOptionsFromContext настройки из WithTxOptions, nil - настройки по умолчанию
+func txOptionsFromContext(ctx context.Context) *TxOptions {
+	opts, ok := ctx.Value(keyTxOptions).(TxOptions)
+	if !ok {
+		return nil
 	}
+	return &opts
+}
+
+// PoolTxManager реализация для пула pgx
+type PoolTxManager struct {
+	pool    *pgxpool.Pool
+	log     *zerolog.Logger
+	retries atomic.Uint64
+}
+
+func NewPoolTxManager(pool *pgxpool.Pool, log *zerolog.Logger) *PoolTxManager {
+	return &PoolTxManager{
+		pool: pool,
+		log:  log,
+	}
+}
+
+// Retries сколько раз транзакции повторялись с момента запуска
+func (tm *PoolTxManager) Retries() uint64 {
+	return tm.retries.Load()
 }
 
-// Если opts == nil, применяется по умолчанию уровень ReadCommitted
-func (tm *SQLTxManager) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error {
+// WithTx выполняет fn в транзакции. Если opts == nil, применяется уровень Serializable
+// и DefaultRetryPolicy. После конфликта сериализации или взаимоблокировки fn выполняется заново
+// в новой транзакции, поэтому не должна копить результат между попытками.
+// Внутри уже начатой транзакции fn выполняется в точке сохранения: ошибка fn откатывает только
+// ее изменения, а opts не применяются - уровень изоляции и повторы задает внешняя транзакция
+func (tm *PoolTxManager) WithTx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context) error) error {
+	if outer, err := tm.getTx(ctx); err == nil {
+		return tm.runTx(ctx, outer.Begin, fn)
+	}
+
 	if opts == nil {
-		opts = &sql.TxOptions{
-			Isolation: sql.LevelSerializable,
-			ReadOnly:  false,
+		opts = &TxOptions{
+			TxOptions: pgx.TxOptions{
+				IsoLevel:   pgx.Serializable,
+				AccessMode: pgx.ReadWrite,
+			},
 		}
 	}
+	retry := opts.Retry
+	if retry.MaxAttempts <= 0 {
+		retry = DefaultRetryPolicy
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := tm.runTx(ctx, func(ctx context.Context) (pgx.Tx, error) {
+			return tm.pool.BeginTx(ctx, opts.TxOptions)
+		}, fn)
+		if err == nil || attempt >= retry.MaxAttempts || !isRetryable(err) {
+			return err
+		}
+
+		tm.retries.Add(1)
+		delay := retry.delay(attempt)
+		tm.log.
+			Warn().
+			Err(err).
+			Int("attempt", attempt).
+			Dur("delay", delay).
+			Msg("Retrying transaction after conflict")
 
-	tx, err := tm.db.BeginTx(ctx, opts)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// runTx выполняет одну попытку транзакции или точки сохранения, начатой begin
+func (tm *PoolTxManager) runTx(ctx context.Context, begin func(ctx context.Context) (pgx.Tx, error), fn func(ctx context.Context) error) error {
+	tx, err := begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -46,42 +126,43 @@ func (tm *SQLTxManager) WithTx(ctx context.Context, opts *sql.TxOptions, fn func
 
 	defer func() {
 		if p := recover(); p != nil {
-			_ = tx.Rollback()
+			_ = tx.Rollback(context.WithoutCancel(ctx))
 			panic(p)
 		}
 	}()
 
 	err = fn(ctx)
 	if err != nil {
-		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+		// Откат должен пройти и после отмены запроса, иначе соединение вернется в пул с открытой транзакцией
+		if rollbackErr := tx.Rollback(context.WithoutCancel(ctx)); rollbackErr != nil {
 			return fmt.Errorf("rollback error: %w, original error: %w", rollbackErr, err)
 		}
 		return err
 	}
 
-	if err := tx.Commit(); err != nil {
+	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
-func (tm *SQLTxManager) GetQuerier(ctx context.Context) (Querier, error) {
+func (tm *PoolTxManager) GetQuerier(ctx context.Context) (Querier, error) {
 	tx, err := tm.getTx(ctx)
 	if err != nil {
 		if errors.Is(err, ErrNoTransaction) {
-			// Возвращаем Querier для основного соединения
-			return &SQLQuerier{db: tm.db}, nil
+			// Возвращаем пул: каждый запрос берет свободное соединение
+			return tm.pool, nil
 		}
 		return nil, fmt.Errorf("failed to get transaction: %w", err)
 	}
-	// Возвращаем Querier для транзакции
-	return &TxQuerier{tx: tx}, nil
+	// Возвращаем транзакцию
+	return tx, nil
 }
 
 // GetTx извлекает транзакцию из контекста
-func (tm *SQLTxManager) getTx(ctx context.Context) (*sql.Tx, error) {
-	tx, ok := ctx.Value(keyTxValue).(*sql.Tx)
+func (tm *PoolTxManager) getTx(ctx context.Context) (pgx.Tx, error) {
+	tx, ok := ctx.Value(keyTxValue).(pgx.Tx)
 	if !ok {
 		return nil, ErrNoTransaction
 	}
@@ -0,0 +1,182 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/repository/dto"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// utmTemplateColumns - порядок колонок utm_templates, который ожидает scanUTMTemplate
+const utmTemplateColumns = "id, user_id, name, utm_source, utm_medium, utm_campaign, utm_term, utm_content, created_at"
+
+// UTMTemplateCreate сохраняет шаблон, при совпадении имени у пользователя возвращает ErrConflict
+func (p *PostgresStorage) UTMTemplateCreate(ctx context.Context, tpl models.UTMTemplate) (models.UTMTemplate, error) {
+	if tpl.UserID <= 0 || tpl.Name == "" {
+		return models.UTMTemplate{}, models.ErrInvalidData
+	}
+
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return models.UTMTemplate{}, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	tplDB := dto.UTMTemplateDBFromDomain(tpl)
+	result, err := scanUTMTemplate(querier.QueryRow(ctx, `
+		INSERT INTO utm_templates (user_id, name, utm_source, utm_medium, utm_campaign, utm_term, utm_content, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, name) DO NOTHING
+		RETURNING `+utmTemplateColumns,
+		tplDB.UserID, tplDB.Name, tplDB.Source, tplDB.Medium, tplDB.Campaign, tplDB.Term, tplDB.Content, tplDB.CreatedAt,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.UTMTemplate{}, models.ErrConflict
+		}
+		return models.UTMTemplate{}, fmt.Errorf("failed to create UTM template: %w", err)
+	}
+
+	return dto.UTMTemplateDBToDomain(result), nil
+}
+
+func (p *PostgresStorage) UTMTemplateGetByName(ctx context.Context, userID int64, name string) (models.UTMTemplate, error) {
+	if userID <= 0 || name == "" {
+		return models.UTMTemplate{}, models.ErrInvalidData
+	}
+
+	return p.utmTemplateGet(ctx,
+		"SELECT "+utmTemplateColumns+" FROM utm_templates WHERE user_id = $1 AND name = $2",
+		userID, name,
+	)
+}
+
+func (p *PostgresStorage) UTMTemplateGetByID(ctx context.Context, id int64) (models.UTMTemplate, error) {
+	if id <= 0 {
+		return models.UTMTemplate{}, models.ErrInvalidData
+	}
+
+	return p.utmTemplateGet(ctx,
+		"SELECT "+utmTemplateColumns+" FROM utm_templates WHERE id = $1",
+		id,
+	)
+}
+
+func (p *PostgresStorage) UTMTemplateGetBatchByUser(ctx context.Context, userID int64) ([]models.UTMTemplate, error) {
+	if userID <= 0 {
+		return nil, models.ErrInvalidData
+	}
+
+	querier, err := p.GetReadQuerier(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	rows, err := querier.Query(ctx,
+		"SELECT "+utmTemplateColumns+" FROM utm_templates WHERE user_id = $1 ORDER BY name",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query UTM templates: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []models.UTMTemplate
+	for rows.Next() {
+		tplDB, err := scanUTMTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan UTM template: %w", err)
+		}
+		templates = append(templates, dto.UTMTemplateDBToDomain(tplDB))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return templates, nil
+}
+
+// UTMTemplateDelete удаляет шаблон пользователя, привязанные ссылки отвязываются через ON DELETE SET NULL.
+// Коды этих ссылок публикуются как измененные, чтобы кэши перестали применять шаблон
+func (p *PostgresStorage) UTMTemplateDelete(ctx context.Context, userID int64, name string) error {
+	if userID <= 0 || name == "" {
+		return models.ErrInvalidData
+	}
+
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	// Запрос видит ссылки до срабатывания ON DELETE SET NULL, поэтому находит все привязанные
+	rows, err := querier.Query(ctx, `
+		WITH deleted AS (
+			DELETE FROM utm_templates WHERE user_id = $1 AND name = $2 RETURNING id
+		)
+		SELECT urls.short_key FROM deleted
+		LEFT JOIN urls ON urls.utm_template_id = deleted.id`,
+		userID, name,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete UTM template: %w", err)
+	}
+	defer rows.Close()
+
+	found := false
+	var codes []string
+	for rows.Next() {
+		var code *string
+		if err := rows.Scan(&code); err != nil {
+			return fmt.Errorf("failed to scan linked URL: %w", err)
+		}
+		found = true
+		if code != nil {
+			codes = append(codes, *code)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to delete UTM template: %w", err)
+	}
+	if !found {
+		return models.ErrUnfound
+	}
+
+	return p.notifyLinkChanges(ctx, querier, codes)
+}
+
+func (p *PostgresStorage) utmTemplateGet(ctx context.Context, query string, args ...interface{}) (models.UTMTemplate, error) {
+	querier, err := p.GetQuerier(ctx)
+	if err != nil {
+		return models.UTMTemplate{}, fmt.Errorf("failed to get querier: %w", err)
+	}
+
+	result, err := scanUTMTemplate(querier.QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.UTMTemplate{}, models.ErrUnfound
+		}
+		return models.UTMTemplate{}, fmt.Errorf("failed to get UTM template: %w", err)
+	}
+
+	return dto.UTMTemplateDBToDomain(result), nil
+}
+
+// scanUTMTemplate сканирует строку, выбранную в порядке utmTemplateColumns
+func scanUTMTemplate(row rowScanner) (dto.UTMTemplateDB, error) {
+	var tplDB dto.UTMTemplateDB
+	err := row.Scan(
+		&tplDB.ID,
+		&tplDB.UserID,
+		&tplDB.Name,
+		&tplDB.Source,
+		&tplDB.Medium,
+		&tplDB.Campaign,
+		&tplDB.Term,
+		&tplDB.Content,
+		&tplDB.CreatedAt,
+	)
+	return tplDB, err
+}
@@ -0,0 +1,159 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// countryRecord - часть записи GeoIP2/GeoLite2 Country, которая нужна для правил
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// Resolver определяет страну клиента по локальной базе MaxMind (.mmdb).
+// Без базы геотаргетинг выключен и Country всегда возвращает пустую строку
+type Resolver struct {
+	db      *maxminddb.Reader
+	trusted []netip.Prefix
+}
+
+// NewResolver открывает базу по пути dbPath. Пустой путь - геотаргетинг выключен.
+// trustedProxies - адреса или подсети прокси, которым разрешено передавать X-Forwarded-For
+func NewResolver(dbPath string, trustedProxies []string) (*Resolver, error) {
+	trusted, err := parsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Resolver{trusted: trusted}
+	if dbPath == "" {
+		return r, nil
+	}
+
+	db, err := maxminddb.Open(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	r.db = db
+
+	return r, nil
+}
+
+// Enabled сообщает, подключена ли база GeoIP
+func (r *Resolver) Enabled() bool {
+	return r != nil && r.db != nil
+}
+
+// Close закрывает базу GeoIP
+func (r *Resolver) Close() error {
+	if !r.Enabled() {
+		return nil
+	}
+	return r.db.Close()
+}
+
+// ClientIP возвращает адрес клиента. X-Forwarded-For учитывается только если запрос
+// пришел от доверенного прокси: цепочка разбирается справа налево до первого
+// недоверенного адреса
+func (r *Resolver) ClientIP(remoteAddr string, forwardedFor []string) netip.Addr {
+	client := parseAddr(remoteAddr)
+	if !client.IsValid() || !r.isTrusted(client) {
+		return client
+	}
+
+	var hops []string
+	for _, header := range forwardedFor {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := parseAddr(strings.TrimSpace(hops[i]))
+		if !hop.IsValid() {
+			// Испорченную цепочку дальше не разбираем
+			return client
+		}
+		client = hop
+		if !r.isTrusted(hop) {
+			return client
+		}
+	}
+
+	return client
+}
+
+// Country возвращает ISO-код страны адреса в верхнем регистре или пустую строку
+func (r *Resolver) Country(ip netip.Addr) string {
+	if !r.Enabled() || !ip.IsValid() {
+		return ""
+	}
+
+	var record countryRecord
+	if err := r.db.Lookup(net.IP(ip.Unmap().AsSlice()), &record); err != nil {
+		return ""
+	}
+
+	if record.Country.ISOCode != "" {
+		return strings.ToUpper(record.Country.ISOCode)
+	}
+	return strings.ToUpper(record.RegisteredCountry.ISOCode)
+}
+
+func (r *Resolver) isTrusted(ip netip.Addr) bool {
+	if r == nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range r.trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAddr принимает "ip", "ip:port" и "[ipv6]:port"
+func parseAddr(value string) netip.Addr {
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr().Unmap()
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(value, "[]")); err == nil {
+		return addr.Unmap()
+	}
+	return netip.Addr{}
+}
+
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
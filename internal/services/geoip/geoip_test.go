@@ -0,0 +1,154 @@
+package geoip
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver_ClientIP(t *testing.T) {
+	resolver, err := NewResolver("", []string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{
+			name:       "Без прокси",
+			remoteAddr: "203.0.113.7:51234",
+			want:       "203.0.113.7",
+		},
+		{
+			name:         "X-Forwarded-For от недоверенного адреса игнорируется",
+			remoteAddr:   "203.0.113.7:51234",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:         "Доверенный прокси",
+			remoteAddr:   "10.1.2.3:8080",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "Подделанное начало цепочки не учитывается",
+			remoteAddr:   "10.1.2.3:8080",
+			forwardedFor: []string{"1.1.1.1, 198.51.100.1", "192.168.1.1"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "Вся цепочка из доверенных адресов",
+			remoteAddr:   "10.1.2.3:8080",
+			forwardedFor: []string{"10.9.9.9"},
+			want:         "10.9.9.9",
+		},
+		{
+			name:         "Испорченная цепочка",
+			remoteAddr:   "10.1.2.3:8080",
+			forwardedFor: []string{"unknown"},
+			want:         "10.1.2.3",
+		},
+		{
+			name:       "IPv6",
+			remoteAddr: "[2001:db8::1]:443",
+			want:       "2001:db8::1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolver.ClientIP(tt.remoteAddr, tt.forwardedFor)
+			assert.Equal(t, netip.MustParseAddr(tt.want), got)
+		})
+	}
+}
+
+func TestNewResolver(t *testing.T) {
+	t.Run("Некорректный доверенный прокси", func(t *testing.T) {
+		_, err := NewResolver("", []string{"not-an-ip"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Несуществующий файл базы", func(t *testing.T) {
+		_, err := NewResolver("/nonexistent/GeoLite2-Country.mmdb", nil)
+		assert.Error(t, err)
+	})
+}
+
+// testdata/GeoLite2-Country-Test.mmdb содержит три сети:
+// 81.2.69.0/24 - GB, 2001:218::/32 - JP,
+// 198.51.100.0/24 - только registered_country "de" в нижнем регистре
+func TestResolver_Country(t *testing.T) {
+	resolver, err := NewResolver("testdata/GeoLite2-Country-Test.mmdb", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, resolver.Close()) })
+	require.True(t, resolver.Enabled())
+
+	tests := []struct {
+		name string
+		ip   netip.Addr
+		want string
+	}{
+		{
+			name: "IPv4",
+			ip:   netip.MustParseAddr("81.2.69.142"),
+			want: "GB",
+		},
+		{
+			name: "IPv4, отображенный в IPv6",
+			ip:   netip.MustParseAddr("::ffff:81.2.69.142"),
+			want: "GB",
+		},
+		{
+			name: "IPv6",
+			ip:   netip.MustParseAddr("2001:218::1"),
+			want: "JP",
+		},
+		{
+			name: "Только страна регистрации, код приводится к верхнему регистру",
+			ip:   netip.MustParseAddr("198.51.100.7"),
+			want: "DE",
+		},
+		{
+			name: "Адреса нет в базе",
+			ip:   netip.MustParseAddr("8.8.8.8"),
+			want: "",
+		},
+		{
+			name: "Пустой адрес",
+			ip:   netip.Addr{},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, resolver.Country(tt.ip))
+		})
+	}
+}
+
+func TestResolver_Country_NoDatabase(t *testing.T) {
+	// Адрес есть в тестовой базе, но без базы страна не определяется
+	ip := netip.MustParseAddr("81.2.69.142")
+
+	t.Run("Путь к базе не задан", func(t *testing.T) {
+		resolver, err := NewResolver("", []string{"10.0.0.0/8"})
+		require.NoError(t, err)
+		assert.False(t, resolver.Enabled())
+		assert.Equal(t, "", resolver.Country(ip))
+		assert.NoError(t, resolver.Close())
+	})
+
+	t.Run("Resolver не создан", func(t *testing.T) {
+		var resolver *Resolver
+		assert.False(t, resolver.Enabled())
+		assert.Equal(t, "", resolver.Country(ip))
+		assert.NoError(t, resolver.Close())
+	})
+}
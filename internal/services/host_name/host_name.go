@@ -0,0 +1,104 @@
+package host_name
+
+import (
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrInvalidIPv4 хост оканчивается числом, но не является IPv4-адресом. Браузер такой адрес не откроет
+var ErrInvalidIPv4 = errors.New("invalid IPv4 address")
+
+// Имена хостов переводятся в punycode без требований STD3: подчеркивания в реальных именах встречаются
+var profile = idna.New(idna.MapForLookup(), idna.StrictDomainName(false), idna.BidiRule())
+
+// ToASCII приводит имя хоста к нижнему регистру и переводит IDN в punycode
+func ToASCII(host string) (string, error) {
+	return profile.ToASCII(host)
+}
+
+// ParseIPv4 разбирает IPv4-адрес так же, как браузеры по стандарту WHATWG URL: от одной до четырех
+// частей, каждая десятичная, восьмеричная (с ведущим 0) или шестнадцатеричная (с 0x), последняя часть
+// занимает все оставшиеся байты. Так 2130706433, 0x7f.1 и 127.1 - это 127.0.0.1.
+// ok == false - хост не оканчивается числом и считается именем. Хост уже должен быть в ASCII
+func ParseIPv4(host string) (addr netip.Addr, ok bool, err error) {
+	parts := strings.Split(host, ".")
+	if len(parts) > 1 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	if !endsInNumber(parts[len(parts)-1]) {
+		return netip.Addr{}, false, nil
+	}
+	if len(parts) > 4 {
+		return netip.Addr{}, true, ErrInvalidIPv4
+	}
+
+	numbers := make([]uint64, len(parts))
+	for i, part := range parts {
+		n, valid := parseIPv4Number(part)
+		if !valid {
+			return netip.Addr{}, true, ErrInvalidIPv4
+		}
+		numbers[i] = n
+	}
+
+	var ipv4 uint64
+	for i, n := range numbers[:len(numbers)-1] {
+		if n > 255 {
+			return netip.Addr{}, true, ErrInvalidIPv4
+		}
+		ipv4 |= n << (8 * (3 - i))
+	}
+	last := numbers[len(numbers)-1]
+	if last >= 1<<(8*(5-len(numbers))) {
+		return netip.Addr{}, true, ErrInvalidIPv4
+	}
+	ipv4 |= last
+
+	return netip.AddrFrom4([4]byte{byte(ipv4 >> 24), byte(ipv4 >> 16), byte(ipv4 >> 8), byte(ipv4)}), true, nil
+}
+
+// endsInNumber последняя часть хоста - десятичное число или число с префиксом 0x
+func endsInNumber(part string) bool {
+	if part == "" {
+		return false
+	}
+	if strings.Trim(part, "0123456789") == "" {
+		return true
+	}
+	_, ok := parseIPv4Number(part)
+	return ok && (strings.HasPrefix(part, "0x") || strings.HasPrefix(part, "0X"))
+}
+
+// parseIPv4Number разбирает часть IPv4-адреса в одной из трех систем счисления
+func parseIPv4Number(part string) (uint64, bool) {
+	if part == "" {
+		return 0, false
+	}
+
+	base := 10
+	switch {
+	case strings.HasPrefix(part, "0x") || strings.HasPrefix(part, "0X"):
+		part, base = part[2:], 16
+	case len(part) > 1 && part[0] == '0':
+		part, base = part[1:], 8
+	}
+	// "0x" без цифр - это ноль
+	if part == "" {
+		return 0, true
+	}
+
+	n, err := strconv.ParseUint(part, base, 64)
+	if err != nil {
+		// Переполнение - все равно число, но слишком большое для адреса
+		var numErr *strconv.NumError
+		if errors.As(err, &numErr) && errors.Is(numErr.Err, strconv.ErrRange) {
+			return 1 << 32, true
+		}
+		return 0, false
+	}
+	return n, true
+}
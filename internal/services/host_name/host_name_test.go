@@ -0,0 +1,52 @@
+package host_name
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIPv4(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		want    string
+		wantOK  bool
+		wantErr bool
+	}{
+		{name: "Обычная запись", host: "127.0.0.1", want: "127.0.0.1", wantOK: true},
+		{name: "Одно десятичное число", host: "2130706433", want: "127.0.0.1", wantOK: true},
+		{name: "Шестнадцатеричные части", host: "0x7f.1", want: "127.0.0.1", wantOK: true},
+		{name: "Две части", host: "127.1", want: "127.0.0.1", wantOK: true},
+		{name: "Три части", host: "10.1.258", want: "10.1.1.2", wantOK: true},
+		{name: "Восьмеричные части", host: "0177.0.0.01", want: "127.0.0.1", wantOK: true},
+		{name: "Точка в конце", host: "192.168.0.1.", want: "192.168.0.1", wantOK: true},
+		{name: "Пустой префикс 0x", host: "0x", want: "0.0.0.0", wantOK: true},
+		{name: "Имя хоста", host: "example.com"},
+		{name: "Имя с цифрами", host: "1example.com"},
+		{name: "Шестнадцатеричное имя без префикса", host: "cafe.babe"},
+		{name: "Часть больше байта", host: "256.0.0.1", wantOK: true, wantErr: true},
+		{name: "Последняя часть слишком большая", host: "1.2.65536", wantOK: true, wantErr: true},
+		{name: "Число больше 32 бит", host: "4294967296", wantOK: true, wantErr: true},
+		{name: "Пять частей", host: "1.2.3.4.5", wantOK: true, wantErr: true},
+		{name: "Имя, оканчивающееся числом", host: "example.123", wantOK: true, wantErr: true},
+		{name: "Неверная восьмеричная цифра", host: "09.0.0.1", wantOK: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, ok, err := ParseIPv4(tt.host)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidIPv4)
+				return
+			}
+
+			require.NoError(t, err)
+			if tt.wantOK {
+				assert.Equal(t, tt.want, addr.String())
+			}
+		})
+	}
+}
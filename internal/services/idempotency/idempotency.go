@@ -0,0 +1,178 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+	"urlshortener/internal/domain/models"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// MaxKeyLength ограничение длины ключа, выбранного клиентом
+	MaxKeyLength = 255
+	// pendingTimeout через столько незавершенный запрос считается брошенным (упал экземпляр),
+	// и ключ можно занять заново. Больше таймаута записи ответа сервера
+	pendingTimeout = time.Minute
+)
+
+//go:generate mockgen -source=idempotency.go -destination=../../mocks/mock_idempotency_store.go -package=mocks
+type Store interface {
+	// IdempotencyBegin сохраняет незавершенную запись record, если ключ пользователя свободен,
+	// его запись истекла к record.CreatedAt или брошена до staleBefore. Иначе возвращает действующую запись и false
+	IdempotencyBegin(ctx context.Context, record models.IdempotencyRecord, staleBefore time.Time) (models.IdempotencyRecord, bool, error)
+	// IdempotencyComplete сохраняет ответ на незавершенный запрос, если ключ все еще занят owner.
+	// Иначе возвращает models.ErrUnfound
+	IdempotencyComplete(ctx context.Context, userID int64, key, owner string, statusCode int, contentType string, body []byte) error
+	// IdempotencyRelease освобождает ключ незавершенного запроса, чтобы его можно было повторить.
+	// Ключ, который уже занял другой владелец, не трогается
+	IdempotencyRelease(ctx context.Context, userID int64, key, owner string) error
+	// IdempotencyPrune удаляет записи, истекшие к now
+	IdempotencyPrune(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Service хранит ответы на запросы с ключом идемпотентности в течение ttl,
+// чтобы повтор после таймаута не создавал ссылки второй раз
+type Service struct {
+	store Store
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// NewService создает сервис. ttl 0 выключает идемпотентность
+func NewService(store Store, ttl time.Duration) *Service {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return &Service{store: store, ttl: ttl, now: time.Now}
+}
+
+// Enabled сообщает, учитываются ли ключи идемпотентности
+func (s *Service) Enabled() bool {
+	return s.ttl > 0
+}
+
+// Begin занимает ключ пользователя для запроса с отпечатком fingerprint.
+// Возвращает токен владельца, если запрос нужно выполнить и затем вызвать Complete или Release
+// с этим токеном, или сохраненную запись с ответом для повтора.
+// Ошибки: models.ErrIdempotencyMismatch - ключ уже использован с другим запросом,
+// models.ErrIdempotencyInProgress - первый запрос с этим ключом еще выполняется
+func (s *Service) Begin(ctx context.Context, userID int64, key, fingerprint string) (*models.IdempotencyRecord, string, error) {
+	if userID <= 0 || fingerprint == "" {
+		return nil, "", models.ErrInvalidData
+	}
+	if err := ValidateKey(key); err != nil {
+		return nil, "", err
+	}
+
+	owner, err := newOwner()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := s.now().UTC()
+	record := models.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		Owner:       owner,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+
+	existing, created, err := s.store.IdempotencyBegin(ctx, record, now.Add(-pendingTimeout))
+	if errors.Is(err, models.ErrUnfound) {
+		// Запись удалили между попыткой вставки и чтением, ключ снова свободен
+		existing, created, err = s.store.IdempotencyBegin(ctx, record, now.Add(-pendingTimeout))
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to begin idempotent request: %w", err)
+	}
+	if created {
+		return nil, owner, nil
+	}
+
+	if existing.Fingerprint != fingerprint {
+		return nil, "", models.ErrIdempotencyMismatch
+	}
+	if !existing.Completed() {
+		return nil, "", models.ErrIdempotencyInProgress
+	}
+	return &existing, "", nil
+}
+
+// Complete сохраняет ответ для повторов. Если ключ успели занять заново как брошенный,
+// ответ не сохраняется и возвращается models.ErrUnfound
+func (s *Service) Complete(ctx context.Context, userID int64, key, owner string, statusCode int, contentType string, body []byte) error {
+	if statusCode <= 0 {
+		return models.ErrInvalidData
+	}
+	if err := s.store.IdempotencyComplete(ctx, userID, key, owner, statusCode, contentType, body); err != nil {
+		return fmt.Errorf("failed to save idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release освобождает ключ, если ответ не стоит повторять (ошибка сервера)
+func (s *Service) Release(ctx context.Context, userID int64, key, owner string) error {
+	if err := s.store.IdempotencyRelease(ctx, userID, key, owner); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Run периодически удаляет истекшие записи. Работает до отмены ctx
+func (s *Service) Run(ctx context.Context, interval time.Duration, log *zerolog.Logger) {
+	if !s.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.store.IdempotencyPrune(ctx, s.now().UTC()); err != nil {
+				log.Error().Err(err).Msg("Failed to prune idempotency keys")
+			}
+		}
+	}
+}
+
+// ValidateKey проверяет ключ: непустой, не длиннее MaxKeyLength, только видимые ASCII-символы
+func ValidateKey(key string) error {
+	if key == "" || len(key) > MaxKeyLength {
+		return fmt.Errorf("%w: idempotency key must be 1-%d characters", models.ErrInvalidData, MaxKeyLength)
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return fmt.Errorf("%w: idempotency key must contain only visible ASCII characters", models.ErrInvalidData)
+		}
+	}
+	return nil
+}
+
+// newOwner случайный токен, которым запрос подтверждает, что ключ все еще занят им
+func newOwner() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate idempotency owner: %w", err)
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// Fingerprint отпечаток запроса: тот же ключ на другом маршруте или с другим телом - другой запрос
+func Fingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,153 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestService_Begin(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	fingerprint := Fingerprint("POST", "/api/shorten/batch", []byte(`[{"correlation_id":"1"}]`))
+	completed := models.IdempotencyRecord{
+		UserID:      1,
+		Key:         "retry-1",
+		Fingerprint: fingerprint,
+		StatusCode:  201,
+		ContentType: "application/json",
+		Body:        []byte(`[]`),
+	}
+	errDB := errors.New("db error")
+
+	tests := []struct {
+		name       string
+		key        string
+		setupMocks func(m *mocks.MockStore)
+		wantReplay *models.IdempotencyRecord
+		wantOwner  bool
+		wantErr    error
+	}{
+		{
+			name: "Новый ключ - запрос выполняется",
+			key:  "retry-1",
+			setupMocks: func(m *mocks.MockStore) {
+				m.EXPECT().IdempotencyBegin(gomock.Any(), gomock.Any(), now.Add(-pendingTimeout)).
+					DoAndReturn(func(ctx context.Context, r models.IdempotencyRecord, staleBefore time.Time) (models.IdempotencyRecord, bool, error) {
+						assert.Equal(t, int64(1), r.UserID)
+						assert.Equal(t, fingerprint, r.Fingerprint)
+						assert.Len(t, r.Owner, 32)
+						assert.Equal(t, now, r.CreatedAt)
+						assert.Equal(t, now.Add(time.Hour), r.ExpiresAt)
+						return r, true, nil
+					})
+			},
+			wantOwner: true,
+		},
+		{
+			name: "Повтор - сохраненный ответ",
+			key:  "retry-1",
+			setupMocks: func(m *mocks.MockStore) {
+				m.EXPECT().IdempotencyBegin(gomock.Any(), gomock.Any(), gomock.Any()).Return(completed, false, nil)
+			},
+			wantReplay: &completed,
+		},
+		{
+			name: "Тот же ключ с другим телом",
+			key:  "retry-1",
+			setupMocks: func(m *mocks.MockStore) {
+				other := completed
+				other.Fingerprint = Fingerprint("POST", "/api/shorten/batch", []byte(`[]`))
+				m.EXPECT().IdempotencyBegin(gomock.Any(), gomock.Any(), gomock.Any()).Return(other, false, nil)
+			},
+			wantErr: models.ErrIdempotencyMismatch,
+		},
+		{
+			name: "Первый запрос еще выполняется",
+			key:  "retry-1",
+			setupMocks: func(m *mocks.MockStore) {
+				pending := completed
+				pending.StatusCode = 0
+				m.EXPECT().IdempotencyBegin(gomock.Any(), gomock.Any(), gomock.Any()).Return(pending, false, nil)
+			},
+			wantErr: models.ErrIdempotencyInProgress,
+		},
+		{
+			name: "Запись удалена между вставкой и чтением - повторная попытка",
+			key:  "retry-1",
+			setupMocks: func(m *mocks.MockStore) {
+				gomock.InOrder(
+					m.EXPECT().IdempotencyBegin(gomock.Any(), gomock.Any(), gomock.Any()).Return(models.IdempotencyRecord{}, false, models.ErrUnfound),
+					m.EXPECT().IdempotencyBegin(gomock.Any(), gomock.Any(), gomock.Any()).Return(models.IdempotencyRecord{}, true, nil),
+				)
+			},
+			wantOwner: true,
+		},
+		{
+			name: "Ошибка хранилища",
+			key:  "retry-1",
+			setupMocks: func(m *mocks.MockStore) {
+				m.EXPECT().IdempotencyBegin(gomock.Any(), gomock.Any(), gomock.Any()).Return(models.IdempotencyRecord{}, false, errDB)
+			},
+			wantErr: errDB,
+		},
+		{
+			name:    "Ключ с пробелом",
+			key:     "retry 1",
+			wantErr: models.ErrInvalidData,
+		},
+		{
+			name:    "Слишком длинный ключ",
+			key:     strings.Repeat("k", MaxKeyLength+1),
+			wantErr: models.ErrInvalidData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			if tt.setupMocks != nil {
+				tt.setupMocks(store)
+			}
+
+			svc := NewService(store, time.Hour)
+			svc.now = func() time.Time { return now }
+
+			replay, owner, err := svc.Begin(context.Background(), 1, tt.key, fingerprint)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, replay)
+				assert.Empty(t, owner)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantReplay, replay)
+			assert.Equal(t, tt.wantOwner, owner != "")
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	body := []byte(`{"url":"https://example.com"}`)
+
+	assert.Equal(t, Fingerprint("POST", "/api/shorten", body), Fingerprint("POST", "/api/shorten", body))
+	assert.NotEqual(t, Fingerprint("POST", "/api/shorten", body), Fingerprint("POST", "/", body))
+	assert.NotEqual(t, Fingerprint("POST", "/api/shorten", body), Fingerprint("POST", "/api/shorten", []byte(`{}`)))
+}
+
+func TestService_Enabled(t *testing.T) {
+	assert.True(t, NewService(nil, time.Minute).Enabled())
+	assert.False(t, NewService(nil, 0).Enabled())
+	assert.False(t, NewService(nil, -time.Minute).Enabled())
+}
@@ -0,0 +1,399 @@
+package link_cache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/services/url_shortener"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	DefaultSize        = 10000
+	DefaultTTL         = time.Minute
+	DefaultNegativeTTL = 10 * time.Second
+
+	// loadTimeout ограничивает общую загрузку: она не отменяется вместе с запросом, который ее начал
+	loadTimeout = 5 * time.Second
+)
+
+// Config настройки кэша ссылок
+type Config struct {
+	Size        int           // сколько кодов держать в памяти, 0 - кэш выключен
+	TTL         time.Duration // сколько хранить найденную ссылку
+	NegativeTTL time.Duration // сколько помнить, что кода нет, 0 - не помнить
+}
+
+type txKey struct{}
+
+// PrimaryReader хранилище с репликами для чтения. Кэш загружает записи из основной базы:
+// версия с отстающей реплики прожила бы в нем весь TTL, в том числе после сброса записи
+// из-за изменения ссылки
+type PrimaryReader interface {
+	WithPrimary(ctx context.Context) context.Context
+}
+
+// txState изменения внутри транзакции. Записи сбрасываются после ее завершения:
+// раньше параллельное чтение успело бы положить в кэш еще не измененную ссылку
+type txState struct {
+	changed bool
+	keys    []string
+	ids     map[int64]string // коды ссылок, прочитанных в транзакции, для изменений по ID
+
+	templatesChanged bool
+}
+
+// utmEntry закэшированный UTM-шаблон или ошибка его отсутствия
+type utmEntry struct {
+	tpl       models.UTMTemplate
+	err       error
+	expiresAt time.Time
+}
+
+// Storage кэширует чтение ссылок по коду и привязанных к ним UTM-шаблонов для перенаправлений,
+// остальные методы передаются хранилищу. Изменения ссылок через Storage сбрасывают их записи.
+// Изменения с других экземпляров сбрасывает Run, как только приходит уведомление LISTEN/NOTIFY.
+// TTL ограничивает устаревание записей, только пока слушатель не подключен или уведомлений нет (без PostgreSQL)
+type Storage struct {
+	url_shortener.URLStorage
+
+	cfg   Config
+	now   func() time.Time
+	group singleflight.Group
+
+	mu        sync.Mutex
+	cache     *lru
+	templates map[int64]utmEntry // шаблоны не меняются, поэтому сбрасываются только удалением и Flush
+	epoch     uint64             // растет с каждым сбросом, загрузка, начатая до сброса, не кладет результат в кэш
+
+	hits          atomic.Uint64
+	negativeHits  atomic.Uint64
+	misses        atomic.Uint64
+	loads         atomic.Uint64
+	evictions     atomic.Uint64
+	invalidations atomic.Uint64
+	flushes       atomic.Uint64
+
+	retryDelay time.Duration // первая пауза перед переподключением слушателя изменений
+}
+
+// NewStorage оборачивает storage кэшем. При cfg.Size 0 все запросы идут в хранилище
+func NewStorage(storage url_shortener.URLStorage, cfg Config) *Storage {
+	if cfg.Size < 0 {
+		cfg.Size = 0
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultTTL
+	}
+	if cfg.NegativeTTL < 0 {
+		cfg.NegativeTTL = 0
+	}
+	return &Storage{
+		URLStorage: storage,
+		cfg:        cfg,
+		now:        time.Now,
+		cache:      newLRU(cfg.Size),
+		templates:  make(map[int64]utmEntry),
+		retryDelay: listenRetryMin,
+	}
+}
+
+// Enabled сообщает, включен ли кэш
+func (s *Storage) Enabled() bool {
+	return s.cfg.Size > 0
+}
+
+// Stats возвращает счетчики кэша с момента запуска
+func (s *Storage) Stats() models.CacheStats {
+	s.mu.Lock()
+	entries := s.cache.len()
+	s.mu.Unlock()
+
+	return models.CacheStats{
+		Capacity:      s.cfg.Size,
+		Entries:       entries,
+		Hits:          s.hits.Load(),
+		NegativeHits:  s.negativeHits.Load(),
+		Misses:        s.misses.Load(),
+		Loads:         s.loads.Load(),
+		Evictions:     s.evictions.Load(),
+		Invalidations: s.invalidations.Load(),
+		Flushes:       s.flushes.Load(),
+	}
+}
+
+// ShortenedLinkGetByShortKey отдает ссылку из кэша. Одновременные промахи по одному коду
+// ждут одной загрузки из хранилища. Внутри транзакции кэш не используется
+func (s *Storage) ShortenedLinkGetByShortKey(ctx context.Context, shortKey string) (models.ShortenedLink, error) {
+	if !s.Enabled() {
+		return s.URLStorage.ShortenedLinkGetByShortKey(ctx, shortKey)
+	}
+
+	if tx, ok := ctx.Value(txKey{}).(*txState); ok {
+		link, err := s.URLStorage.ShortenedLinkGetByShortKey(ctx, shortKey)
+		if err == nil {
+			tx.ids[link.ID] = link.ShortCode
+		}
+		return link, err
+	}
+
+	s.mu.Lock()
+	e, ok := s.cache.get(shortKey, s.now())
+	s.mu.Unlock()
+	if ok {
+		s.hits.Add(1)
+		if e.err != nil {
+			s.negativeHits.Add(1)
+			return models.ShortenedLink{}, e.err
+		}
+		return cloneLink(e.link), nil
+	}
+
+	s.misses.Add(1)
+	ch := s.group.DoChan(shortKey, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+		return s.load(ctx, shortKey)
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return models.ShortenedLink{}, res.Err
+		}
+		return cloneLink(res.Val.(models.ShortenedLink)), nil
+	case <-ctx.Done():
+		return models.ShortenedLink{}, ctx.Err()
+	}
+}
+
+func (s *Storage) load(ctx context.Context, shortKey string) (models.ShortenedLink, error) {
+	s.mu.Lock()
+	epoch := s.epoch
+	s.mu.Unlock()
+
+	s.loads.Add(1)
+	link, err := s.URLStorage.ShortenedLinkGetByShortKey(s.primary(ctx), shortKey)
+	switch {
+	case err == nil:
+		s.store(epoch, &entry{key: shortKey, link: link, expiresAt: s.now().Add(s.cfg.TTL)})
+	case errors.Is(err, models.ErrUnfound) && s.cfg.NegativeTTL > 0:
+		s.store(epoch, &entry{key: shortKey, err: err, expiresAt: s.now().Add(s.cfg.NegativeTTL)})
+	}
+	return link, err
+}
+
+// primary направляет загрузку в основную базу, если у хранилища есть реплики
+func (s *Storage) primary(ctx context.Context) context.Context {
+	if reader, ok := s.URLStorage.(PrimaryReader); ok {
+		return reader.WithPrimary(ctx)
+	}
+	return ctx
+}
+
+// store кладет загруженную запись, если с начала загрузки кэш не сбрасывался
+func (s *Storage) store(epoch uint64, e *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.epoch != epoch {
+		return
+	}
+	if evicted := s.cache.add(e); evicted > 0 {
+		s.evictions.Add(uint64(evicted))
+	}
+}
+
+// invalidate сбрасывает записи кодов сразу или, внутри транзакции, после ее завершения.
+// Даже без кодов загрузки, начатые до изменения, не попадут в кэш
+func (s *Storage) invalidate(ctx context.Context, keys ...string) {
+	if !s.Enabled() {
+		return
+	}
+	if tx, ok := ctx.Value(txKey{}).(*txState); ok {
+		tx.changed = true
+		tx.keys = append(tx.keys, keys...)
+		return
+	}
+	s.drop(keys)
+}
+
+// Invalidate сбрасывает записи кодов, измененных в обход Storage, например другим экземпляром
+func (s *Storage) Invalidate(codes ...string) {
+	if s.Enabled() {
+		s.drop(codes)
+	}
+}
+
+// Flush сбрасывает весь кэш, когда неизвестно, какие ссылки изменились
+func (s *Storage) Flush() {
+	if !s.Enabled() {
+		return
+	}
+	s.mu.Lock()
+	s.epoch++
+	s.cache = newLRU(s.cfg.Size)
+	s.templates = make(map[int64]utmEntry)
+	s.mu.Unlock()
+	s.flushes.Add(1)
+}
+
+func (s *Storage) drop(keys []string) {
+	s.mu.Lock()
+	s.epoch++
+	for _, key := range keys {
+		if s.cache.remove(key) {
+			s.invalidations.Add(1)
+		}
+	}
+	s.mu.Unlock()
+
+	// Новые запросы не должны дожидаться загрузки, начатой до изменения
+	for _, key := range keys {
+		s.group.Forget(key)
+	}
+}
+
+// WithinTx помечает контекст транзакции: чтения в ней идут мимо кэша, сброс откладывается до ее завершения
+func (s *Storage) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !s.Enabled() {
+		return s.URLStorage.WithinTx(ctx, fn)
+	}
+	// Вложенная транзакция копит изменения во внешней
+	if _, ok := ctx.Value(txKey{}).(*txState); ok {
+		return s.URLStorage.WithinTx(ctx, fn)
+	}
+
+	tx := &txState{ids: make(map[int64]string)}
+	err := s.URLStorage.WithinTx(ctx, func(ctx context.Context) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+	// После отката сбрасывать нечего, но лишний сброс безопасен
+	if tx.changed {
+		s.drop(tx.keys)
+	}
+	if tx.templatesChanged {
+		s.dropTemplates()
+	}
+	return err
+}
+
+// UTMTemplateGetByID отдает шаблон из кэша: он нужен каждому перенаправлению ссылки с шаблоном.
+// Внутри транзакции кэш не используется
+func (s *Storage) UTMTemplateGetByID(ctx context.Context, id int64) (models.UTMTemplate, error) {
+	if !s.Enabled() {
+		return s.URLStorage.UTMTemplateGetByID(ctx, id)
+	}
+	if _, ok := ctx.Value(txKey{}).(*txState); ok {
+		return s.URLStorage.UTMTemplateGetByID(ctx, id)
+	}
+
+	s.mu.Lock()
+	e, ok := s.templates[id]
+	epoch := s.epoch
+	s.mu.Unlock()
+	if ok && s.now().Before(e.expiresAt) {
+		return e.tpl, e.err
+	}
+
+	tpl, err := s.URLStorage.UTMTemplateGetByID(s.primary(ctx), id)
+	if err != nil && !errors.Is(err, models.ErrUnfound) {
+		return tpl, err
+	}
+
+	s.mu.Lock()
+	if s.epoch == epoch {
+		// Шаблонов обычно немного, при переполнении кэш шаблонов просто начинается заново
+		if len(s.templates) >= s.cfg.Size {
+			s.templates = make(map[int64]utmEntry)
+		}
+		s.templates[id] = utmEntry{tpl: tpl, err: err, expiresAt: s.now().Add(s.cfg.TTL)}
+	}
+	s.mu.Unlock()
+	return tpl, err
+}
+
+// UTMTemplateDelete сбрасывает кэш шаблонов целиком: удаление идет по имени, а кэш хранит шаблоны по ID
+func (s *Storage) UTMTemplateDelete(ctx context.Context, userID int64, name string) error {
+	err := s.URLStorage.UTMTemplateDelete(ctx, userID, name)
+	if !s.Enabled() {
+		return err
+	}
+	if tx, ok := ctx.Value(txKey{}).(*txState); ok {
+		tx.templatesChanged = true
+		return err
+	}
+	s.dropTemplates()
+	return err
+}
+
+func (s *Storage) dropTemplates() {
+	s.mu.Lock()
+	s.epoch++
+	s.templates = make(map[int64]utmEntry)
+	s.mu.Unlock()
+}
+
+// ShortenedLinkCreate сбрасывает запомненное отсутствие кода
+func (s *Storage) ShortenedLinkCreate(ctx context.Context, url models.ShortenedLink) (models.ShortenedLink, error) {
+	result, err := s.URLStorage.ShortenedLinkCreate(ctx, url)
+	s.invalidate(ctx, url.ShortCode)
+	return result, err
+}
+
+func (s *Storage) ShortenedLinkBatchCreate(ctx context.Context, urls []models.ShortenedLink) ([]models.ShortenedLink, error) {
+	result, err := s.URLStorage.ShortenedLinkBatchCreate(ctx, urls)
+	keys := make([]string, len(urls))
+	for i, url := range urls {
+		keys[i] = url.ShortCode
+	}
+	s.invalidate(ctx, keys...)
+	return result, err
+}
+
+func (s *Storage) ShortenedLinkUpdate(ctx context.Context, url models.ShortenedLink) (models.ShortenedLink, error) {
+	result, err := s.URLStorage.ShortenedLinkUpdate(ctx, url)
+	s.invalidate(ctx, url.ShortCode)
+	return result, err
+}
+
+func (s *Storage) ShortenedLinkBatchDelete(ctx context.Context, id int64, shortCode []string) error {
+	err := s.URLStorage.ShortenedLinkBatchDelete(ctx, id, shortCode)
+	s.invalidate(ctx, shortCode...)
+	return err
+}
+
+// ShortenedLinkSetModeration меняет ссылку по ID, код ищется среди закэшированных и прочитанных в транзакции
+func (s *Storage) ShortenedLinkSetModeration(ctx context.Context, linkID int64, status string) error {
+	err := s.URLStorage.ShortenedLinkSetModeration(ctx, linkID, status)
+	if !s.Enabled() {
+		return err
+	}
+
+	var keys []string
+	if tx, ok := ctx.Value(txKey{}).(*txState); ok {
+		if key, ok := tx.ids[linkID]; ok {
+			keys = append(keys, key)
+		}
+	}
+	s.mu.Lock()
+	if key, ok := s.cache.keyByID(linkID); ok {
+		keys = append(keys, key)
+	}
+	s.mu.Unlock()
+
+	s.invalidate(ctx, keys...)
+	return err
+}
+
+// cloneLink копирует срезы ссылки: вызывающий код может менять их, например заполняя статистику вариантов
+func cloneLink(link models.ShortenedLink) models.ShortenedLink {
+	link.RedirectRules = slices.Clone(link.RedirectRules)
+	link.Variants = slices.Clone(link.Variants)
+	return link
+}
@@ -0,0 +1,462 @@
+package link_cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var testLink = models.ShortenedLink{
+	ID:          7,
+	ShortCode:   "abc",
+	OriginalURL: "https://example.com/",
+	UserID:      1,
+	Variants:    []models.LinkVariant{{Name: "a", TargetURL: "https://example.com/a", Weight: 1}},
+}
+
+var errUnfound = fmt.Errorf("%w: no link", models.ErrUnfound)
+
+// runTx выполняет транзакцию мока без базы
+func runTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func newTestStorage(m *mocks.MockURLStorage, size int) (*Storage, *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewStorage(m, Config{Size: size, TTL: time.Minute, NegativeTTL: 10 * time.Second})
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestStorage_GetByShortKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		setupMocks func(m *mocks.MockURLStorage)
+		run        func(t *testing.T, s *Storage, now *time.Time)
+		wantStats  models.CacheStats
+	}{
+		{
+			name: "Повторное чтение берется из кэша",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc").Return(testLink, nil)
+			},
+			run: func(t *testing.T, s *Storage, now *time.Time) {
+				for i := 0; i < 3; i++ {
+					link, err := s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+					require.NoError(t, err)
+					assert.Equal(t, testLink.OriginalURL, link.OriginalURL)
+				}
+			},
+			wantStats: models.CacheStats{Entries: 1, Hits: 2, Misses: 1, Loads: 1},
+		},
+		{
+			name: "Отсутствие кода запоминается на NegativeTTL",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "nope").Return(models.ShortenedLink{}, errUnfound).Times(2)
+			},
+			run: func(t *testing.T, s *Storage, now *time.Time) {
+				for i := 0; i < 2; i++ {
+					_, err := s.ShortenedLinkGetByShortKey(context.Background(), "nope")
+					assert.ErrorIs(t, err, models.ErrUnfound)
+				}
+				*now = now.Add(11 * time.Second)
+				_, err := s.ShortenedLinkGetByShortKey(context.Background(), "nope")
+				assert.ErrorIs(t, err, models.ErrUnfound)
+			},
+			wantStats: models.CacheStats{Entries: 1, Hits: 1, NegativeHits: 1, Misses: 2, Loads: 2},
+		},
+		{
+			name: "Истекшая запись загружается заново",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc").Return(testLink, nil).Times(2)
+			},
+			run: func(t *testing.T, s *Storage, now *time.Time) {
+				_, err := s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+				require.NoError(t, err)
+				*now = now.Add(time.Minute)
+				_, err = s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+				require.NoError(t, err)
+			},
+			wantStats: models.CacheStats{Entries: 1, Misses: 2, Loads: 2},
+		},
+		{
+			name: "Ошибка хранилища не кэшируется",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc").Return(models.ShortenedLink{}, fmt.Errorf("db error")).Times(2)
+			},
+			run: func(t *testing.T, s *Storage, now *time.Time) {
+				for i := 0; i < 2; i++ {
+					_, err := s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+					assert.Error(t, err)
+				}
+			},
+			wantStats: models.CacheStats{Misses: 2, Loads: 2},
+		},
+		{
+			name: "Давно не запрошенные коды вытесняются",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, key string) (models.ShortenedLink, error) {
+						return models.ShortenedLink{ShortCode: key}, nil
+					}).Times(4)
+			},
+			run: func(t *testing.T, s *Storage, now *time.Time) {
+				// a, b, обращение к a, c вытесняет b, b загружается снова
+				for _, key := range []string{"a", "b", "a", "c", "a", "b"} {
+					link, err := s.ShortenedLinkGetByShortKey(context.Background(), key)
+					require.NoError(t, err)
+					assert.Equal(t, key, link.ShortCode)
+				}
+			},
+			wantStats: models.CacheStats{Entries: 2, Hits: 2, Misses: 4, Loads: 4, Evictions: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockURLStorage(ctrl)
+			tt.setupMocks(mockStorage)
+
+			size := 8
+			if tt.wantStats.Evictions > 0 {
+				size = 2
+			}
+			s, now := newTestStorage(mockStorage, size)
+			tt.run(t, s, now)
+
+			tt.wantStats.Capacity = size
+			assert.Equal(t, tt.wantStats, s.Stats())
+		})
+	}
+}
+
+func TestStorage_Invalidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		setupMocks func(m *mocks.MockURLStorage)
+		change     func(s *Storage) error
+	}{
+		{
+			name: "Изменение ссылки",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().ShortenedLinkUpdate(gomock.Any(), gomock.Any()).Return(testLink, nil)
+			},
+			change: func(s *Storage) error {
+				_, err := s.ShortenedLinkUpdate(context.Background(), testLink)
+				return err
+			},
+		},
+		{
+			name: "Удаление ссылки",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().ShortenedLinkBatchDelete(gomock.Any(), int64(1), []string{"abc"}).Return(nil)
+			},
+			change: func(s *Storage) error {
+				return s.ShortenedLinkBatchDelete(context.Background(), 1, []string{"abc"})
+			},
+		},
+		{
+			name: "Модерация по ID",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().ShortenedLinkSetModeration(gomock.Any(), testLink.ID, models.ModerationBanned).Return(nil)
+			},
+			change: func(s *Storage) error {
+				return s.ShortenedLinkSetModeration(context.Background(), testLink.ID, models.ModerationBanned)
+			},
+		},
+		{
+			name: "Модерация в транзакции сбрасывается после ее завершения",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().WithinTx(gomock.Any(), gomock.Any()).DoAndReturn(runTx)
+				m.EXPECT().ShortenedLinkSetModeration(gomock.Any(), testLink.ID, models.ModerationQuarantined).Return(nil)
+			},
+			change: func(s *Storage) error {
+				return s.WithinTx(context.Background(), func(ctx context.Context) error {
+					if err := s.ShortenedLinkSetModeration(ctx, testLink.ID, models.ModerationQuarantined); err != nil {
+						return err
+					}
+					// До фиксации транзакции запись еще на месте
+					s.mu.Lock()
+					defer s.mu.Unlock()
+					if _, ok := s.cache.get("abc", s.now()); !ok {
+						return fmt.Errorf("entry dropped before commit")
+					}
+					return nil
+				})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockURLStorage(ctrl)
+			mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc").Return(testLink, nil).Times(2)
+			tt.setupMocks(mockStorage)
+
+			s, _ := newTestStorage(mockStorage, 8)
+			_, err := s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+			require.NoError(t, err)
+
+			require.NoError(t, tt.change(s))
+
+			_, err = s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+			require.NoError(t, err)
+			assert.Equal(t, uint64(1), s.Stats().Invalidations)
+		})
+	}
+}
+
+func TestStorage_CreateForgetsMissingCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	gomock.InOrder(
+		mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc").Return(models.ShortenedLink{}, errUnfound),
+		mockStorage.EXPECT().ShortenedLinkCreate(gomock.Any(), gomock.Any()).Return(testLink, nil),
+		mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc").Return(testLink, nil),
+	)
+
+	s, _ := newTestStorage(mockStorage, 8)
+	_, err := s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+	require.ErrorIs(t, err, models.ErrUnfound)
+
+	_, err = s.ShortenedLinkCreate(context.Background(), testLink)
+	require.NoError(t, err)
+
+	link, err := s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+	require.NoError(t, err)
+	assert.Equal(t, testLink.ID, link.ID)
+}
+
+func TestStorage_ConcurrentMissesLoadOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	const readers = 8
+	release := make(chan struct{})
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc").
+		DoAndReturn(func(ctx context.Context, key string) (models.ShortenedLink, error) {
+			<-release
+			return testLink, nil
+		})
+
+	s, _ := newTestStorage(mockStorage, 8)
+
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			link, err := s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+			assert.NoError(t, err)
+			assert.Equal(t, testLink.ShortCode, link.ShortCode)
+		}()
+	}
+
+	// Загрузка отпускается, когда все читатели промахнулись и ждут ее
+	require.Eventually(t, func() bool { return s.Stats().Misses == readers }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, uint64(1), s.Stats().Loads)
+}
+
+func TestStorage_TransactionBypassesCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc").Return(testLink, nil).Times(2)
+	mockStorage.EXPECT().WithinTx(gomock.Any(), gomock.Any()).DoAndReturn(runTx)
+
+	s, _ := newTestStorage(mockStorage, 8)
+	_, err := s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+	require.NoError(t, err)
+
+	err = s.WithinTx(context.Background(), func(ctx context.Context) error {
+		_, err := s.ShortenedLinkGetByShortKey(ctx, "abc")
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), s.Stats().Hits)
+}
+
+type primaryKey struct{}
+
+// primaryStorage хранилище с репликами: помечает контекст чтений из основной базы
+type primaryStorage struct {
+	*mocks.MockURLStorage
+}
+
+func (primaryStorage) WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func TestStorage_LoadsFromPrimary(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fromPrimary := func(ctx context.Context) bool {
+		primary, _ := ctx.Value(primaryKey{}).(bool)
+		return primary
+	}
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc").
+		DoAndReturn(func(ctx context.Context, _ string) (models.ShortenedLink, error) {
+			assert.True(t, fromPrimary(ctx), "cache load")
+			return testLink, nil
+		})
+	mockStorage.EXPECT().UTMTemplateGetByID(gomock.Any(), int64(3)).
+		DoAndReturn(func(ctx context.Context, _ int64) (models.UTMTemplate, error) {
+			assert.True(t, fromPrimary(ctx), "template load")
+			return models.UTMTemplate{ID: 3}, nil
+		})
+	// Остальные чтения идут мимо кэша и маршрут не меняют
+	mockStorage.EXPECT().ShortenedLinkGetBatchByUser(gomock.Any(), int64(1)).
+		DoAndReturn(func(ctx context.Context, _ int64) ([]models.ShortenedLink, error) {
+			assert.False(t, fromPrimary(ctx), "other reads")
+			return nil, nil
+		})
+
+	s := NewStorage(primaryStorage{mockStorage}, Config{Size: 8})
+	_, err := s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+	require.NoError(t, err)
+	_, err = s.UTMTemplateGetByID(context.Background(), 3)
+	require.NoError(t, err)
+	_, err = s.ShortenedLinkGetBatchByUser(context.Background(), 1)
+	require.NoError(t, err)
+}
+
+func TestStorage_UTMTemplateGetByID(t *testing.T) {
+	tpl := models.UTMTemplate{ID: 3, UserID: 1, Name: "newsletter", Source: "mail"}
+
+	tests := []struct {
+		name       string
+		setupMocks func(m *mocks.MockURLStorage)
+		run        func(t *testing.T, s *Storage, now *time.Time)
+	}{
+		{
+			name: "Повторное чтение шаблона берется из кэша",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().UTMTemplateGetByID(gomock.Any(), int64(3)).Return(tpl, nil)
+			},
+			run: func(t *testing.T, s *Storage, now *time.Time) {
+				for i := 0; i < 3; i++ {
+					got, err := s.UTMTemplateGetByID(context.Background(), 3)
+					require.NoError(t, err)
+					assert.Equal(t, tpl, got)
+				}
+			},
+		},
+		{
+			name: "Удаленный шаблон запоминается до TTL",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().UTMTemplateGetByID(gomock.Any(), int64(3)).Return(models.UTMTemplate{}, models.ErrUnfound).Times(2)
+			},
+			run: func(t *testing.T, s *Storage, now *time.Time) {
+				for i := 0; i < 2; i++ {
+					_, err := s.UTMTemplateGetByID(context.Background(), 3)
+					assert.ErrorIs(t, err, models.ErrUnfound)
+				}
+				*now = now.Add(time.Minute)
+				_, err := s.UTMTemplateGetByID(context.Background(), 3)
+				assert.ErrorIs(t, err, models.ErrUnfound)
+			},
+		},
+		{
+			name: "Удаление шаблона сбрасывает кэш шаблонов",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().UTMTemplateGetByID(gomock.Any(), int64(3)).Return(tpl, nil)
+				m.EXPECT().UTMTemplateDelete(gomock.Any(), int64(1), "newsletter").Return(nil)
+				m.EXPECT().UTMTemplateGetByID(gomock.Any(), int64(3)).Return(models.UTMTemplate{}, models.ErrUnfound)
+			},
+			run: func(t *testing.T, s *Storage, now *time.Time) {
+				_, err := s.UTMTemplateGetByID(context.Background(), 3)
+				require.NoError(t, err)
+				require.NoError(t, s.UTMTemplateDelete(context.Background(), 1, "newsletter"))
+				_, err = s.UTMTemplateGetByID(context.Background(), 3)
+				assert.ErrorIs(t, err, models.ErrUnfound)
+			},
+		},
+		{
+			name: "Ошибка хранилища не кэшируется",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().UTMTemplateGetByID(gomock.Any(), int64(3)).Return(models.UTMTemplate{}, errors.New("connection reset"))
+				m.EXPECT().UTMTemplateGetByID(gomock.Any(), int64(3)).Return(tpl, nil)
+			},
+			run: func(t *testing.T, s *Storage, now *time.Time) {
+				_, err := s.UTMTemplateGetByID(context.Background(), 3)
+				require.Error(t, err)
+				got, err := s.UTMTemplateGetByID(context.Background(), 3)
+				require.NoError(t, err)
+				assert.Equal(t, tpl, got)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockURLStorage(ctrl)
+			tt.setupMocks(mockStorage)
+
+			s, now := newTestStorage(mockStorage, 8)
+			tt.run(t, s, now)
+		})
+	}
+}
+
+func TestStorage_ReturnsCopies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc").Return(testLink, nil)
+
+	s, _ := newTestStorage(mockStorage, 8)
+	link, err := s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+	require.NoError(t, err)
+	link.Variants[0].Clicks = 100
+
+	cached, err := s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+	require.NoError(t, err)
+	assert.Zero(t, cached.Variants[0].Clicks)
+}
+
+func TestStorage_Disabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc").Return(testLink, nil).Times(2)
+
+	s, _ := newTestStorage(mockStorage, 0)
+	assert.False(t, s.Enabled())
+	for i := 0; i < 2; i++ {
+		_, err := s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, models.CacheStats{}, s.Stats())
+}
@@ -0,0 +1,61 @@
+package link_cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	listenRetryMin = time.Second
+	listenRetryMax = 30 * time.Second
+)
+
+// ChangeFeed поток изменений ссылок, сделанных любым экземпляром сервиса
+type ChangeFeed interface {
+	// ShortenedLinkListenChanges вызывает ready после подписки и onChange с кодами измененных ссылок.
+	// Возвращает ошибку при разрыве соединения, nil - после отмены ctx
+	ShortenedLinkListenChanges(ctx context.Context, ready func(), onChange func(codes []string)) error
+}
+
+// Run сбрасывает записи ссылок, измененных другими экземплярами. После разрыва подписки
+// уведомления могли потеряться, поэтому кэш сбрасывается целиком, а подписка восстанавливается
+// с растущей паузой. Работает до отмены ctx
+func (s *Storage) Run(ctx context.Context, feed ChangeFeed, log *zerolog.Logger) {
+	if !s.Enabled() {
+		return
+	}
+
+	delay := s.retryDelay
+	for {
+		subscribed := false
+		err := feed.ShortenedLinkListenChanges(ctx, func() {
+			// Изменения до подписки не придут, а кэш мог заполниться раньше
+			s.Flush()
+			subscribed = true
+		}, func(codes []string) {
+			s.Invalidate(codes...)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.Flush()
+		if subscribed {
+			delay = s.retryDelay
+		}
+		log.
+			Warn().
+			Err(err).
+			Dur("retry_in", delay).
+			Msg("Link changes listener disconnected, cache flushed")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, listenRetryMax)
+	}
+}
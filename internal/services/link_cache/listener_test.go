@@ -0,0 +1,94 @@
+package link_cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"urlshortener/internal/mocks"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// scriptedFeed по очереди выполняет сценарии подписок, после последнего ждет отмены
+type scriptedFeed struct {
+	sessions []func(ready func(), onChange func(codes []string)) error
+	calls    int
+}
+
+func (f *scriptedFeed) ShortenedLinkListenChanges(ctx context.Context, ready func(), onChange func(codes []string)) error {
+	if f.calls < len(f.sessions) {
+		session := f.sessions[f.calls]
+		f.calls++
+		return session(ready, onChange)
+	}
+	f.calls++
+	<-ctx.Done()
+	return nil
+}
+
+func TestStorage_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc").Return(testLink, nil).AnyTimes()
+
+	s, _ := newTestStorage(mockStorage, 8)
+	s.retryDelay = time.Millisecond
+	log := zerolog.Nop()
+
+	cached := func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		_, ok := s.cache.get("abc", s.now())
+		return ok
+	}
+	load := func() {
+		_, err := s.ShortenedLinkGetByShortKey(context.Background(), "abc")
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	feed := &scriptedFeed{sessions: []func(ready func(), onChange func(codes []string)) error{
+		func(ready func(), onChange func(codes []string)) error {
+			ready()
+			load()
+			onChange([]string{"abc"})
+			assert.False(t, cached(), "notified code must be evicted")
+
+			// Разрыв соединения: уведомления могли потеряться
+			load()
+			return errors.New("connection reset")
+		},
+		func(ready func(), onChange func(codes []string)) error {
+			assert.False(t, cached(), "cache must be flushed after the listener drops")
+			return errors.New("connection refused")
+		},
+		func(ready func(), onChange func(codes []string)) error {
+			ready()
+			cancel()
+			return nil
+		},
+	}}
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, feed, &log)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not stop after cancel")
+	}
+
+	assert.Equal(t, 3, feed.calls)
+	// Подписки и разрывы: ready, разрыв, разрыв, ready
+	assert.Equal(t, uint64(4), s.Stats().Flushes)
+	assert.Equal(t, uint64(1), s.Stats().Invalidations)
+}
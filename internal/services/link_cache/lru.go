@@ -0,0 +1,92 @@
+package link_cache
+
+import (
+	"container/list"
+	"time"
+	"urlshortener/internal/domain/models"
+)
+
+// entry закэшированный ответ хранилища: ссылка или ошибка отсутствия кода
+type entry struct {
+	key       string
+	link      models.ShortenedLink
+	err       error // не nil - кода нет в хранилище
+	expiresAt time.Time
+}
+
+// lru ограниченный по размеру список записей, вытесняются давно не запрошенные. Не потокобезопасен
+type lru struct {
+	size  int
+	order *list.List // от недавно запрошенных к давним
+	items map[string]*list.Element
+	ids   map[int64]string // код ссылки по ее ID, чтобы сбрасывать запись при изменениях по ID
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+		ids:   make(map[int64]string, size),
+	}
+}
+
+// get возвращает живую запись. Истекшая запись удаляется
+func (c *lru) get(key string, now time.Time) (*entry, bool) {
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !now.Before(e.expiresAt) {
+		c.removeElement(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e, true
+}
+
+// add кладет запись и возвращает число вытесненных
+func (c *lru) add(e *entry) int {
+	if el, ok := c.items[e.key]; ok {
+		c.removeElement(el)
+	}
+	c.items[e.key] = c.order.PushFront(e)
+	if e.err == nil {
+		c.ids[e.link.ID] = e.key
+	}
+
+	evicted := 0
+	for c.order.Len() > c.size {
+		c.removeElement(c.order.Back())
+		evicted++
+	}
+	return evicted
+}
+
+func (c *lru) remove(key string) bool {
+	el, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	c.removeElement(el)
+	return true
+}
+
+// keyByID код закэшированной ссылки с этим ID
+func (c *lru) keyByID(id int64) (string, bool) {
+	key, ok := c.ids[id]
+	return key, ok
+}
+
+func (c *lru) len() int {
+	return c.order.Len()
+}
+
+func (c *lru) removeElement(el *list.Element) {
+	e := c.order.Remove(el).(*entry)
+	delete(c.items, e.key)
+	if e.err == nil && c.ids[e.link.ID] == e.key {
+		delete(c.ids, e.link.ID)
+	}
+}
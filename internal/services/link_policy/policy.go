@@ -0,0 +1,293 @@
+package link_policy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/services/host_name"
+
+	"github.com/rs/zerolog"
+)
+
+// Config настройки политики адресов назначения
+type Config struct {
+	BlocklistPath  string // файл с запрещенными хостами, пусто - без блок-листа
+	AllowlistPath  string // файл с разрешенными хостами
+	AllowlistOnly  bool   // сокращать только адреса из allowlist
+	BlockPrivateIP bool   // запрещать loopback, частные и служебные IP-адреса
+	BaseURL        string // адрес самого сервиса, ссылки на него дают петлю перенаправлений
+}
+
+// hostList набор правил из файла: точные имена и суффиксы (.example.com - домен и все поддомены)
+type hostList struct {
+	exact    map[string]bool
+	suffixes []string
+	modTime  time.Time
+}
+
+// Policy решает, можно ли сокращать адрес назначения.
+// Списки загружаются из файлов и перечитываются при изменении без перезапуска сервиса
+type Policy struct {
+	cfg      Config
+	selfHost string
+
+	mu        sync.RWMutex
+	blocklist *hostList
+	allowlist *hostList
+}
+
+// NewPolicy загружает списки из файлов. Режим allowlist-only без файла allowlist не допускается
+func NewPolicy(cfg Config) (*Policy, error) {
+	if cfg.AllowlistOnly && cfg.AllowlistPath == "" {
+		return nil, errors.New("allowlist-only mode requires an allowlist file")
+	}
+
+	p := &Policy{cfg: cfg}
+	if cfg.BaseURL != "" {
+		base, err := url.Parse(cfg.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base URL: %w", err)
+		}
+		p.selfHost = canonicalHost(base)
+	}
+
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload перечитывает оба списка. При ошибке остаются прежние списки
+func (p *Policy) Reload() error {
+	blocklist, err := loadHostList(p.cfg.BlocklistPath)
+	if err != nil {
+		return fmt.Errorf("failed to load blocklist: %w", err)
+	}
+	allowlist, err := loadHostList(p.cfg.AllowlistPath)
+	if err != nil {
+		return fmt.Errorf("failed to load allowlist: %w", err)
+	}
+
+	p.mu.Lock()
+	p.blocklist = blocklist
+	p.allowlist = allowlist
+	p.mu.Unlock()
+	return nil
+}
+
+// Watch проверяет файлы списков раз в interval и перечитывает их при изменении.
+// Работает до отмены ctx
+func (p *Policy) Watch(ctx context.Context, interval time.Duration, log *zerolog.Logger) {
+	if p.cfg.BlocklistPath == "" && p.cfg.AllowlistPath == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !p.changed() {
+				continue
+			}
+			if err := p.Reload(); err != nil {
+				log.Error().Err(err).Msg("Failed to reload link policy, keeping previous lists")
+				continue
+			}
+			log.Info().Msg("Link policy lists reloaded")
+		}
+	}
+}
+
+// changed сообщает, изменился ли какой-либо из файлов с момента загрузки
+func (p *Policy) changed() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return fileChanged(p.cfg.BlocklistPath, p.blocklist) || fileChanged(p.cfg.AllowlistPath, p.allowlist)
+}
+
+// Check проверяет нормализованный адрес назначения. Отказ возвращается как *models.PolicyViolation.
+// Хосты из allowlist не блокируются блок-листом, что позволяет делать исключения для поддоменов
+func (p *Policy) Check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: invalid URL", models.ErrInvalidData)
+	}
+	// Адреса без хоста (mailto:, tel:) никуда не перенаправляют по HTTP
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return nil
+	}
+
+	if p.selfHost != "" && canonicalHost(u) == p.selfHost {
+		return violation(models.PolicyReasonSelfReference, host)
+	}
+
+	if p.cfg.BlockPrivateIP && isPrivateHost(host) {
+		return violation(models.PolicyReasonPrivateAddress, host)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.cfg.AllowlistOnly && !p.allowlist.matches(host) {
+		return violation(models.PolicyReasonNotAllowlisted, host)
+	}
+	if p.blocklist.matches(host) && !p.allowlist.matches(host) {
+		return violation(models.PolicyReasonBlocklisted, host)
+	}
+
+	return nil
+}
+
+func violation(reason, host string) error {
+	return &models.PolicyViolation{Reason: reason, Host: host}
+}
+
+// isPrivateHost распознает IP-литералы внутренних сетей и имена localhost. IPv4 разбирается
+// так же, как в браузере, поэтому 2130706433 и 127.1 тоже считаются loopback. Хост, который
+// оканчивается числом, но адресом не является, браузер не откроет - он тоже отклоняется
+func isPrivateHost(host string) bool {
+	host = strings.TrimSuffix(host, ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		var isIP bool
+		addr, isIP, err = host_name.ParseIPv4(host)
+		if !isIP {
+			return false
+		}
+		if err != nil {
+			return true
+		}
+	}
+	addr = addr.Unmap()
+	return addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified()
+}
+
+// canonicalHost возвращает хост с портом, порт по умолчанию для схемы отбрасывается
+func canonicalHost(u *url.URL) string {
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port == "" {
+		return host
+	}
+	return host + ":" + port
+}
+
+func (l *hostList) matches(host string) bool {
+	if l == nil {
+		return false
+	}
+	if l.exact[host] {
+		return true
+	}
+	for _, suffix := range l.suffixes {
+		if host == suffix[1:] || strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validHost допускает IP-адреса и имена из букв, цифр, "-", "_" и "."
+func validHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return true
+	}
+	for _, r := range host {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func fileChanged(path string, list *hostList) bool {
+	if path == "" {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		// Пропавший файл не сбрасывает списки, ошибку покажет Reload при следующем изменении
+		return false
+	}
+	return list == nil || !info.ModTime().Equal(list.modTime)
+}
+
+// loadHostList читает список хостов: одна запись на строку, # - комментарий.
+// Запись с ведущей точкой или "*." задает суффикс, остальные - точное имя
+func loadHostList(path string) (*hostList, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	list := &hostList{exact: make(map[string]bool), modTime: info.ModTime()}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry := scanner.Text()
+		if i := strings.IndexByte(entry, '#'); i >= 0 {
+			entry = entry[:i]
+		}
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		suffix := strings.HasPrefix(entry, ".") || strings.HasPrefix(entry, "*.")
+		entry = strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+
+		// Имена переводятся в punycode так же, как при нормализации адресов назначения
+		host, err := host_name.ToASCII(entry)
+		if err != nil || !validHost(host) {
+			return nil, fmt.Errorf("line %d: invalid host %q", line, entry)
+		}
+
+		if suffix {
+			list.suffixes = append(list.suffixes, "."+host)
+		} else {
+			list.exact[host] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
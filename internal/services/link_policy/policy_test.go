@@ -0,0 +1,119 @@
+package link_policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"urlshortener/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeList(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestPolicy_Check(t *testing.T) {
+	dir := t.TempDir()
+	blocklist := filepath.Join(dir, "blocklist.txt")
+	allowlist := filepath.Join(dir, "allowlist.txt")
+	writeList(t, blocklist, "# фишинг\nevil.example\n.bad.example   # домен и поддомены\n*.пример.рф\n")
+	writeList(t, allowlist, "good.bad.example\n")
+
+	policy, err := NewPolicy(Config{
+		BlocklistPath:  blocklist,
+		AllowlistPath:  allowlist,
+		BlockPrivateIP: true,
+		BaseURL:        "https://sho.rt",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		url        string
+		wantReason string
+	}{
+		{name: "Обычный адрес", url: "https://example.com/"},
+		{name: "Точное совпадение", url: "https://evil.example/login", wantReason: models.PolicyReasonBlocklisted},
+		{name: "Поддомен точного имени не блокируется", url: "https://www.evil.example/"},
+		{name: "Суффикс совпадает с доменом", url: "https://bad.example/", wantReason: models.PolicyReasonBlocklisted},
+		{name: "Суффикс совпадает с поддоменом", url: "https://a.b.bad.example/", wantReason: models.PolicyReasonBlocklisted},
+		{name: "Похожий домен не совпадает с суффиксом", url: "https://notbad.example/"},
+		{name: "Исключение из allowlist", url: "https://good.bad.example/"},
+		{name: "IDN в блок-листе", url: "https://xn--e1afmkfd.xn--p1ai.xn--e1afmkfd.xn--p1ai/", wantReason: models.PolicyReasonBlocklisted},
+		{name: "Loopback", url: "http://127.0.0.1:8080/", wantReason: models.PolicyReasonPrivateAddress},
+		{name: "Loopback одним числом", url: "http://2130706433/", wantReason: models.PolicyReasonPrivateAddress},
+		{name: "Loopback в шестнадцатеричной записи", url: "http://0x7f.1/", wantReason: models.PolicyReasonPrivateAddress},
+		{name: "Сокращенный loopback", url: "http://127.1/", wantReason: models.PolicyReasonPrivateAddress},
+		{name: "Частная сеть", url: "http://10.1.2.3/", wantReason: models.PolicyReasonPrivateAddress},
+		{name: "IPv6 loopback", url: "http://[::1]/", wantReason: models.PolicyReasonPrivateAddress},
+		{name: "IPv4 в IPv6", url: "http://[::ffff:192.168.0.1]/", wantReason: models.PolicyReasonPrivateAddress},
+		{name: "localhost", url: "http://api.localhost/", wantReason: models.PolicyReasonPrivateAddress},
+		{name: "Публичный IP", url: "http://8.8.8.8/"},
+		{name: "Ссылка на сам сервис", url: "https://sho.rt:443/abc", wantReason: models.PolicyReasonSelfReference},
+		{name: "Другой порт того же хоста", url: "https://sho.rt:8443/abc"},
+		{name: "Адрес без хоста", url: "mailto:team@evil.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(tt.url)
+			if tt.wantReason == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var violation *models.PolicyViolation
+			require.True(t, errors.As(err, &violation), "want policy violation, got %v", err)
+			assert.Equal(t, tt.wantReason, violation.Reason)
+			assert.ErrorIs(t, err, models.ErrBlocked)
+		})
+	}
+}
+
+func TestPolicy_AllowlistOnly(t *testing.T) {
+	_, err := NewPolicy(Config{AllowlistOnly: true})
+	require.Error(t, err)
+
+	allowlist := filepath.Join(t.TempDir(), "allowlist.txt")
+	writeList(t, allowlist, ".corp.example\n")
+
+	policy, err := NewPolicy(Config{AllowlistPath: allowlist, AllowlistOnly: true})
+	require.NoError(t, err)
+
+	assert.NoError(t, policy.Check("https://wiki.corp.example/page"))
+
+	var violation *models.PolicyViolation
+	require.True(t, errors.As(policy.Check("https://example.com/"), &violation))
+	assert.Equal(t, models.PolicyReasonNotAllowlisted, violation.Reason)
+	assert.Equal(t, "example.com", violation.Host)
+}
+
+func TestPolicy_Reload(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeList(t, blocklist, "one.example\n")
+
+	policy, err := NewPolicy(Config{BlocklistPath: blocklist})
+	require.NoError(t, err)
+	assert.False(t, policy.changed())
+	assert.Error(t, policy.Check("https://one.example/"))
+
+	writeList(t, blocklist, "two.example\n")
+	// Явно сдвигаем время изменения: на некоторых ФС точность mtime - секунда
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(blocklist, future, future))
+	assert.True(t, policy.changed())
+
+	require.NoError(t, policy.Reload())
+	assert.NoError(t, policy.Check("https://one.example/"))
+	assert.Error(t, policy.Check("https://two.example/"))
+
+	// Битый файл не сбрасывает загруженные списки
+	writeList(t, blocklist, "bad host\n")
+	assert.Error(t, policy.Reload())
+	assert.Error(t, policy.Check("https://two.example/"))
+}
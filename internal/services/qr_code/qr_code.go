@@ -0,0 +1,162 @@
+package qr_code
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"urlshortener/internal/domain/models"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	defaultSize   = 256
+	defaultMargin = 4
+	defaultECC    = "M"
+
+	minSize   = 64
+	maxSize   = 2048
+	maxMargin = 16
+)
+
+var eccLevels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Encoder рисует QR-коды локально, без обращения к внешним сервисам
+type Encoder struct{}
+
+// NewEncoder создает новый экземпляр кодировщика
+func NewEncoder() *Encoder {
+	return &Encoder{}
+}
+
+// Normalize подставляет значения по умолчанию и проверяет параметры отрисовки
+func (e *Encoder) Normalize(opts models.QRCodeOptions) (models.QRCodeOptions, error) {
+	opts.Format = strings.ToLower(opts.Format)
+	if opts.Format == "" {
+		opts.Format = models.QRCodeFormatPNG
+	}
+	if opts.Format != models.QRCodeFormatPNG && opts.Format != models.QRCodeFormatSVG {
+		return models.QRCodeOptions{}, fmt.Errorf("%w: unsupported format %q", models.ErrInvalidData, opts.Format)
+	}
+
+	if opts.Size == 0 {
+		opts.Size = defaultSize
+	}
+	if opts.Size < minSize || opts.Size > maxSize {
+		return models.QRCodeOptions{}, fmt.Errorf("%w: size must be between %d and %d", models.ErrInvalidData, minSize, maxSize)
+	}
+
+	if opts.Margin < 0 {
+		opts.Margin = defaultMargin
+	}
+	if opts.Margin > maxMargin {
+		return models.QRCodeOptions{}, fmt.Errorf("%w: margin must be between 0 and %d", models.ErrInvalidData, maxMargin)
+	}
+
+	opts.ECC = strings.ToUpper(opts.ECC)
+	if opts.ECC == "" {
+		opts.ECC = defaultECC
+	}
+	if _, ok := eccLevels[opts.ECC]; !ok {
+		return models.QRCodeOptions{}, fmt.Errorf("%w: unsupported ecc level %q", models.ErrInvalidData, opts.ECC)
+	}
+
+	return opts, nil
+}
+
+// Encode кодирует content в QR-код и возвращает изображение в запрошенном формате
+func (e *Encoder) Encode(content string, opts models.QRCodeOptions) ([]byte, error) {
+	if content == "" {
+		return nil, models.ErrInvalidData
+	}
+
+	opts, err := e.Normalize(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	qr, err := qrcode.New(content, eccLevels[opts.ECC])
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	// Рамку рисуем сами, чтобы ее ширина настраивалась
+	qr.DisableBorder = true
+	bitmap := qr.Bitmap()
+
+	switch opts.Format {
+	case models.QRCodeFormatSVG:
+		return renderSVG(bitmap, opts.Size, opts.Margin), nil
+	default:
+		return renderPNG(bitmap, opts.Size, opts.Margin)
+	}
+}
+
+// renderPNG рисует символ целым числом пикселей на модуль. Если модулей с рамкой больше,
+// чем size, изображение увеличивается до одного пикселя на модуль: обрезанный код не сканируется
+func renderPNG(bitmap [][]bool, size, margin int) ([]byte, error) {
+	modules := len(bitmap) + 2*margin
+	if size < modules {
+		size = modules
+	}
+	scale := size / modules
+	// Центрируем символ, если сторона не делится на число модулей нацело
+	offset := (size - scale*modules) / 2
+	if offset < 0 {
+		offset = 0
+	}
+
+	palette := color.Palette{color.White, color.Black}
+	img := image.NewPaletted(image.Rect(0, 0, size, size), palette)
+
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			startX := offset + (x+margin)*scale
+			startY := offset + (y+margin)*scale
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(startX+dx, startY+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func renderSVG(bitmap [][]bool, size, margin int) []byte {
+	modules := len(bitmap) + 2*margin
+
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x+margin, y+margin)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, modules, modules)
+	buf.WriteString(`<rect width="100%" height="100%" fill="#ffffff"/>`)
+	fmt.Fprintf(&buf, `<path fill="#000000" d="%s"/>`, path.String())
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,139 @@
+package qr_code
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+	"urlshortener/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncoder_Normalize(t *testing.T) {
+	encoder := NewEncoder()
+
+	tests := []struct {
+		name        string
+		input       models.QRCodeOptions
+		want        models.QRCodeOptions
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name:  "Значения по умолчанию",
+			input: models.QRCodeOptions{Margin: -1},
+			want: models.QRCodeOptions{
+				Format: models.QRCodeFormatPNG,
+				Size:   defaultSize,
+				Margin: defaultMargin,
+				ECC:    defaultECC,
+			},
+		},
+		{
+			name:  "Регистр формата и уровня коррекции не важен",
+			input: models.QRCodeOptions{Format: "SVG", Size: 512, Margin: 0, ECC: "h"},
+			want: models.QRCodeOptions{
+				Format: models.QRCodeFormatSVG,
+				Size:   512,
+				Margin: 0,
+				ECC:    "H",
+			},
+		},
+		{
+			name:        "Неизвестный формат",
+			input:       models.QRCodeOptions{Format: "gif"},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:        "Слишком маленький размер",
+			input:       models.QRCodeOptions{Size: 10},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:        "Слишком широкая рамка",
+			input:       models.QRCodeOptions{Margin: maxMargin + 1},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:        "Неизвестный уровень коррекции",
+			input:       models.QRCodeOptions{ECC: "X"},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encoder.Normalize(tt.input)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	encoder := NewEncoder()
+
+	t.Run("PNG заданного размера", func(t *testing.T) {
+		data, err := encoder.Encode("http://short/abc123", models.QRCodeOptions{
+			Format: models.QRCodeFormatPNG,
+			Size:   300,
+			Margin: 2,
+		})
+		require.NoError(t, err)
+
+		img, err := png.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, 300, img.Bounds().Dx())
+		assert.Equal(t, 300, img.Bounds().Dy())
+	})
+
+	t.Run("PNG меньше числа модулей увеличивается, а не обрезается", func(t *testing.T) {
+		// Длинный адрес с уровнем H дает больше 64 модулей вместе с рамкой
+		content := "http://short/" + strings.Repeat("a", 150)
+		data, err := encoder.Encode(content, models.QRCodeOptions{
+			Format: models.QRCodeFormatPNG,
+			Size:   minSize,
+			Margin: maxMargin,
+			ECC:    "H",
+		})
+		require.NoError(t, err)
+
+		img, err := png.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Greater(t, img.Bounds().Dx(), minSize)
+		assert.Equal(t, img.Bounds().Dx(), img.Bounds().Dy())
+	})
+
+	t.Run("SVG с тихой зоной в viewBox", func(t *testing.T) {
+		data, err := encoder.Encode("http://short/abc123", models.QRCodeOptions{
+			Format: models.QRCodeFormatSVG,
+			Size:   128,
+			Margin: 4,
+		})
+		require.NoError(t, err)
+
+		svg := string(data)
+		assert.True(t, strings.Contains(svg, `<svg `))
+		assert.True(t, strings.Contains(svg, `width="128"`))
+		// версия 2 (25 модулей) + 2*4 модуля рамки
+		assert.True(t, strings.Contains(svg, `viewBox="0 0 33 33"`))
+	})
+
+	t.Run("Пустое содержимое", func(t *testing.T) {
+		_, err := encoder.Encode("", models.QRCodeOptions{})
+		assert.ErrorIs(t, err, models.ErrInvalidData)
+	})
+}
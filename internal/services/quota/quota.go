@@ -0,0 +1,108 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"urlshortener/internal/domain/models"
+)
+
+//go:generate mockgen -source=quota.go -destination=../../mocks/mock_quota_storage.go -package=mocks
+type UsageStorage interface {
+	// UserLockForQuota блокирует пользователя до конца транзакции, чтобы параллельные создания не обходили квоту
+	UserLockForQuota(ctx context.Context, userID int64) error
+	ShortenedLinkCountActiveByUser(ctx context.Context, userID int64) (int, error)
+}
+
+// Limits лимиты тарифа. 0 - без ограничения
+type Limits struct {
+	MaxActiveLinks int // ссылок, которые не удалены, на пользователя
+	MaxBatchSize   int // ссылок в одном пакетном запросе
+}
+
+// Service проверяет квоты пользователей при создании ссылок
+type Service struct {
+	storage UsageStorage
+	limits  Limits
+}
+
+func NewService(storage UsageStorage, limits Limits) *Service {
+	if limits.MaxActiveLinks < 0 {
+		limits.MaxActiveLinks = 0
+	}
+	if limits.MaxBatchSize < 0 {
+		limits.MaxBatchSize = 0
+	}
+	return &Service{storage: storage, limits: limits}
+}
+
+// Check проверяет квоту без блокировки пользователя. Вызывается до выдачи кодов,
+// чтобы пользователь с исчерпанной квотой не расходовал их. Параллельные запросы
+// могут пройти Check вместе, окончательно квоту проверяет Reserve. Отказ - *models.QuotaExceeded
+func (s *Service) Check(ctx context.Context, userID int64, links int) error {
+	if s.limits.MaxActiveLinks == 0 || links <= 0 {
+		return nil
+	}
+	return s.checkActiveLinks(ctx, userID, links)
+}
+
+// Reserve проверяет, что пользователь может создать еще links ссылок.
+// Вызывается внутри транзакции создания: пользователь блокируется до ее окончания,
+// поэтому параллельные запросы не превысят квоту. Отказ - *models.QuotaExceeded
+func (s *Service) Reserve(ctx context.Context, userID int64, links int) error {
+	if s.limits.MaxActiveLinks == 0 || links <= 0 {
+		return nil
+	}
+
+	if err := s.storage.UserLockForQuota(ctx, userID); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+	return s.checkActiveLinks(ctx, userID, links)
+}
+
+// checkActiveLinks сравнивает число активных ссылок пользователя с квотой
+func (s *Service) checkActiveLinks(ctx context.Context, userID int64, links int) error {
+	active, err := s.storage.ShortenedLinkCountActiveByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to count active links: %w", err)
+	}
+
+	if active+links > s.limits.MaxActiveLinks {
+		return &models.QuotaExceeded{
+			Quota:     models.QuotaActiveLinks,
+			Limit:     s.limits.MaxActiveLinks,
+			Used:      active,
+			Requested: links,
+		}
+	}
+	return nil
+}
+
+// CheckBatchSize проверяет размер пакетного запроса до его обработки
+func (s *Service) CheckBatchSize(size int) error {
+	if s.limits.MaxBatchSize == 0 || size <= s.limits.MaxBatchSize {
+		return nil
+	}
+	return &models.QuotaExceeded{
+		Quota:     models.QuotaBatchSize,
+		Limit:     s.limits.MaxBatchSize,
+		Requested: size,
+	}
+}
+
+// Usage возвращает использование квот пользователем
+func (s *Service) Usage(ctx context.Context, userID int64) (models.QuotaUsage, error) {
+	if userID <= 0 {
+		return models.QuotaUsage{}, models.ErrInvalidData
+	}
+
+	active, err := s.storage.ShortenedLinkCountActiveByUser(ctx, userID)
+	if err != nil {
+		return models.QuotaUsage{}, fmt.Errorf("failed to count active links: %w", err)
+	}
+
+	return models.QuotaUsage{
+		ActiveLinks:    active,
+		MaxActiveLinks: s.limits.MaxActiveLinks,
+		MaxBatchSize:   s.limits.MaxBatchSize,
+	}, nil
+}
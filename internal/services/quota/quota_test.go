@@ -0,0 +1,202 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestService_Reserve(t *testing.T) {
+	errDB := errors.New("db error")
+
+	tests := []struct {
+		name       string
+		limits     Limits
+		links      int
+		setupMocks func(m *mocks.MockUsageStorage)
+		wantErr    error
+		wantQuota  *models.QuotaExceeded
+	}{
+		{
+			name:   "Квота не задана - хранилище не опрашивается",
+			limits: Limits{},
+			links:  10,
+		},
+		{
+			name:   "Ссылки помещаются в квоту",
+			limits: Limits{MaxActiveLinks: 5},
+			links:  2,
+			setupMocks: func(m *mocks.MockUsageStorage) {
+				gomock.InOrder(
+					m.EXPECT().UserLockForQuota(gomock.Any(), int64(1)).Return(nil),
+					m.EXPECT().ShortenedLinkCountActiveByUser(gomock.Any(), int64(1)).Return(3, nil),
+				)
+			},
+		},
+		{
+			name:   "Превышение квоты",
+			limits: Limits{MaxActiveLinks: 5},
+			links:  3,
+			setupMocks: func(m *mocks.MockUsageStorage) {
+				m.EXPECT().UserLockForQuota(gomock.Any(), int64(1)).Return(nil)
+				m.EXPECT().ShortenedLinkCountActiveByUser(gomock.Any(), int64(1)).Return(3, nil)
+			},
+			wantErr: models.ErrQuota,
+			wantQuota: &models.QuotaExceeded{
+				Quota:     models.QuotaActiveLinks,
+				Limit:     5,
+				Used:      3,
+				Requested: 3,
+			},
+		},
+		{
+			name:   "Ошибка блокировки пользователя",
+			limits: Limits{MaxActiveLinks: 5},
+			links:  1,
+			setupMocks: func(m *mocks.MockUsageStorage) {
+				m.EXPECT().UserLockForQuota(gomock.Any(), int64(1)).Return(models.ErrUnfound)
+			},
+			wantErr: models.ErrUnfound,
+		},
+		{
+			name:   "Ошибка подсчета ссылок",
+			limits: Limits{MaxActiveLinks: 5},
+			links:  1,
+			setupMocks: func(m *mocks.MockUsageStorage) {
+				m.EXPECT().UserLockForQuota(gomock.Any(), int64(1)).Return(nil)
+				m.EXPECT().ShortenedLinkCountActiveByUser(gomock.Any(), int64(1)).Return(0, errDB)
+			},
+			wantErr: errDB,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storage := mocks.NewMockUsageStorage(ctrl)
+			if tt.setupMocks != nil {
+				tt.setupMocks(storage)
+			}
+
+			err := NewService(storage, tt.limits).Reserve(context.Background(), 1, tt.links)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantQuota != nil {
+				var exceeded *models.QuotaExceeded
+				require.ErrorAs(t, err, &exceeded)
+				assert.Equal(t, tt.wantQuota, exceeded)
+			}
+		})
+	}
+}
+
+func TestService_Check(t *testing.T) {
+	tests := []struct {
+		name       string
+		limits     Limits
+		links      int
+		setupMocks func(m *mocks.MockUsageStorage)
+		wantErr    error
+	}{
+		{
+			name:   "Квота не задана - хранилище не опрашивается",
+			limits: Limits{},
+			links:  10,
+		},
+		{
+			name:   "Ссылки помещаются в квоту, пользователь не блокируется",
+			limits: Limits{MaxActiveLinks: 5},
+			links:  2,
+			setupMocks: func(m *mocks.MockUsageStorage) {
+				m.EXPECT().ShortenedLinkCountActiveByUser(gomock.Any(), int64(1)).Return(3, nil)
+			},
+		},
+		{
+			name:   "Квота уже исчерпана",
+			limits: Limits{MaxActiveLinks: 5},
+			links:  1,
+			setupMocks: func(m *mocks.MockUsageStorage) {
+				m.EXPECT().ShortenedLinkCountActiveByUser(gomock.Any(), int64(1)).Return(5, nil)
+			},
+			wantErr: models.ErrQuota,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storage := mocks.NewMockUsageStorage(ctrl)
+			if tt.setupMocks != nil {
+				tt.setupMocks(storage)
+			}
+
+			err := NewService(storage, tt.limits).Check(context.Background(), 1, tt.links)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestService_CheckBatchSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  Limits
+		size    int
+		wantErr bool
+	}{
+		{name: "Без ограничения", limits: Limits{}, size: 1000},
+		{name: "Размер на границе", limits: Limits{MaxBatchSize: 3}, size: 3},
+		{name: "Размер больше лимита", limits: Limits{MaxBatchSize: 3}, size: 4, wantErr: true},
+		{name: "Отрицательный лимит считается отключенным", limits: Limits{MaxBatchSize: -1}, size: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewService(nil, tt.limits).CheckBatchSize(tt.size)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+
+			var exceeded *models.QuotaExceeded
+			require.ErrorAs(t, err, &exceeded)
+			assert.Equal(t, models.QuotaBatchSize, exceeded.Quota)
+			assert.Equal(t, tt.limits.MaxBatchSize, exceeded.Limit)
+			assert.Equal(t, tt.size, exceeded.Requested)
+		})
+	}
+}
+
+func TestService_Usage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := mocks.NewMockUsageStorage(ctrl)
+	storage.EXPECT().ShortenedLinkCountActiveByUser(gomock.Any(), int64(1)).Return(4, nil)
+
+	svc := NewService(storage, Limits{MaxActiveLinks: 10, MaxBatchSize: 50})
+
+	usage, err := svc.Usage(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.QuotaUsage{ActiveLinks: 4, MaxActiveLinks: 10, MaxBatchSize: 50}, usage)
+
+	_, err = svc.Usage(context.Background(), 0)
+	assert.ErrorIs(t, err, models.ErrInvalidData)
+}
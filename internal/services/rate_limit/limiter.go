@@ -0,0 +1,110 @@
+package rate_limit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Области ограничения, у каждой свой бюджет
+const (
+	ScopeCreate   = "create"   // создание ссылок
+	ScopeRegister = "register" // регистрация пользователей
+	ScopeRedirect = "redirect" // переходы по ссылкам
+	ScopeReport   = "report"   // жалобы на ссылки
+)
+
+// Rule бюджет области: корзина на Burst запросов пополняется со скоростью PerMinute запросов в минуту.
+// PerMinute 0 - без ограничения
+type Rule struct {
+	PerMinute int
+	Burst     int
+}
+
+// Store хранит корзины токенов. Ключ уже содержит область
+type Store interface {
+	// RateLimitTake списывает токен из корзины key, создавая полную корзину при первом обращении.
+	// При отказе возвращает время до появления следующего токена
+	RateLimitTake(ctx context.Context, key string, rate, burst float64) (bool, time.Duration, error)
+	// RateLimitPrune удаляет корзины, не использованные дольше idle
+	RateLimitPrune(ctx context.Context, idle time.Duration) (int64, error)
+}
+
+// Limiter ограничивает частоту запросов по областям (token bucket).
+// Состояние корзин живет в Store: в памяти процесса или в общей БД для нескольких экземпляров
+type Limiter struct {
+	store Store
+	rules map[string]Rule
+}
+
+// NewLimiter создает ограничитель. Области без правила или с PerMinute 0 не ограничиваются,
+// Burst меньше 1 приравнивается к PerMinute
+func NewLimiter(store Store, rules map[string]Rule) *Limiter {
+	normalized := make(map[string]Rule, len(rules))
+	for scope, rule := range rules {
+		if rule.PerMinute <= 0 {
+			continue
+		}
+		if rule.Burst < 1 {
+			rule.Burst = rule.PerMinute
+		}
+		normalized[scope] = rule
+	}
+	return &Limiter{store: store, rules: normalized}
+}
+
+// Enabled сообщает, ограничена ли область
+func (l *Limiter) Enabled(scope string) bool {
+	_, ok := l.rules[scope]
+	return ok
+}
+
+// Allow списывает токен клиента key в области scope
+func (l *Limiter) Allow(ctx context.Context, scope, key string) (bool, time.Duration, error) {
+	rule, ok := l.rules[scope]
+	if !ok {
+		return true, 0, nil
+	}
+
+	allowed, retryAfter, err := l.store.RateLimitTake(ctx, scope+":"+key, float64(rule.PerMinute)/60, float64(rule.Burst))
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to take token: %w", err)
+	}
+	return allowed, retryAfter, nil
+}
+
+// Run периодически удаляет корзины, которые уже восстановились бы до полной:
+// они ничем не отличаются от новых. Работает до отмены ctx
+func (l *Limiter) Run(ctx context.Context, interval time.Duration, log *zerolog.Logger) {
+	if len(l.rules) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := l.store.RateLimitPrune(ctx, l.maxRefill()); err != nil {
+				log.Error().Err(err).Msg("Failed to prune rate limit buckets")
+			}
+		}
+	}
+}
+
+// maxRefill время, за которое пустая корзина любой области становится полной
+func (l *Limiter) maxRefill() time.Duration {
+	var longest time.Duration
+	for _, rule := range l.rules {
+		refill := time.Duration(float64(rule.Burst) / float64(rule.PerMinute) * float64(time.Minute))
+		if refill > longest {
+			longest = refill
+		}
+	}
+	return longest
+}
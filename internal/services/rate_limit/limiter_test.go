@@ -0,0 +1,101 @@
+package rate_limit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	limiter := NewLimiter(store, map[string]Rule{
+		ScopeCreate:   {PerMinute: 60, Burst: 3},
+		ScopeRegister: {PerMinute: 6},
+		ScopeRedirect: {PerMinute: 0},
+	})
+	ctx := context.Background()
+
+	t.Run("Корзина расходуется и пополняется", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			allowed, _, err := limiter.Allow(ctx, ScopeCreate, "user:1")
+			require.NoError(t, err)
+			assert.True(t, allowed, "request %d", i)
+		}
+
+		allowed, retryAfter, err := limiter.Allow(ctx, ScopeCreate, "user:1")
+		require.NoError(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, time.Second, retryAfter)
+
+		now = now.Add(time.Second)
+		allowed, _, err = limiter.Allow(ctx, ScopeCreate, "user:1")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("Клиенты и области не делят бюджет", func(t *testing.T) {
+		allowed, _, err := limiter.Allow(ctx, ScopeCreate, "user:2")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+
+		allowed, _, err = limiter.Allow(ctx, ScopeRegister, "user:1")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("Burst по умолчанию равен PerMinute", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			allowed, _, _ := limiter.Allow(ctx, ScopeRegister, "ip:192.0.2.1")
+			assert.True(t, allowed, "request %d", i)
+		}
+		allowed, _, _ := limiter.Allow(ctx, ScopeRegister, "ip:192.0.2.1")
+		assert.True(t, allowed)
+		allowed, retryAfter, _ := limiter.Allow(ctx, ScopeRegister, "ip:192.0.2.1")
+		assert.False(t, allowed)
+		assert.Equal(t, 10*time.Second, retryAfter)
+	})
+
+	t.Run("Область без ограничения", func(t *testing.T) {
+		assert.False(t, limiter.Enabled(ScopeRedirect))
+		assert.False(t, limiter.Enabled(ScopeReport))
+		for i := 0; i < 100; i++ {
+			allowed, _, err := limiter.Allow(ctx, ScopeRedirect, "ip:192.0.2.1")
+			require.NoError(t, err)
+			require.True(t, allowed)
+		}
+	})
+}
+
+func TestMemoryStore_RateLimitPrune(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, _, err := store.RateLimitTake(ctx, "create:user:1", 1, 2)
+	require.NoError(t, err)
+	now = now.Add(30 * time.Second)
+	_, _, err = store.RateLimitTake(ctx, "create:user:2", 1, 2)
+	require.NoError(t, err)
+
+	now = now.Add(30 * time.Second)
+	pruned, err := store.RateLimitPrune(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+	assert.Len(t, store.buckets, 1)
+	assert.Contains(t, store.buckets, "create:user:2")
+}
+
+func TestLimiter_maxRefill(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), map[string]Rule{
+		ScopeCreate:   {PerMinute: 60, Burst: 120},
+		ScopeRedirect: {PerMinute: 600},
+	})
+	assert.Equal(t, 2*time.Minute, limiter.maxRefill())
+}
@@ -0,0 +1,79 @@
+package rate_limit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// MemoryStore хранит корзины в памяти процесса. Подходит для одного экземпляра сервиса
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (m *MemoryStore) RateLimitTake(ctx context.Context, key string, rate, burst float64) (bool, time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return false, 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	b, exists := m.buckets[key]
+	if !exists {
+		b = &bucket{tokens: burst, updated: now}
+		m.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	return false, retryAfter(b.tokens, rate), nil
+}
+
+func (m *MemoryStore) RateLimitPrune(ctx context.Context, idle time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pruned int64
+	now := m.now()
+	for key, b := range m.buckets {
+		if now.Sub(b.updated) >= idle {
+			delete(m.buckets, key)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+// retryAfter время до накопления целого токена
+func retryAfter(tokens, rate float64) time.Duration {
+	if rate <= 0 {
+		return time.Minute
+	}
+	return time.Duration((1 - tokens) / rate * float64(time.Second))
+}
@@ -0,0 +1,131 @@
+package short_code
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Стратегии генерации кодов
+const (
+	StrategyRandom     = "random"     // случайные символы алфавита
+	StrategySequential = "sequential" // порядковый номер в системе счисления алфавита
+	StrategyHashids    = "hashids"    // порядковый номер, перемешанный солью, чтобы коды не шли подряд
+	StrategyHash       = "hash"       // хэш адреса назначения: одинаковый адрес - одинаковый код
+	StrategyWords      = "words"      // несколько слов через дефис
+)
+
+const (
+	// MaxLength предел роста кодов из символов, больше не помещается в колонку short_key
+	MaxLength = 32
+	// lookalikes символы, которые легко спутать: 0/O/o, 1/l/I
+	lookalikes = "0Oo1lI"
+	// minAlphabet алфавит короче дает слишком длинные коды
+	minAlphabet = 16
+)
+
+var ErrInvalidConfig = errors.New("invalid short code config")
+
+// CodeGenerator выдает кандидатов в коды. Уникальность кандидата проверяет резерв в хранилище
+type CodeGenerator interface {
+	// Generate возвращает кандидата для ссылки на url. attempt - номер попытки для этого url:
+	// детерминированные стратегии на разных попытках дают разные коды
+	Generate(ctx context.Context, url string, attempt int) (string, error)
+	// Deterministic сообщает, что код зависит от адреса и не может быть выдан заранее
+	Deterministic() bool
+	// Grow удлиняет коды, когда свободных кодов текущей длины почти не осталось
+	Grow()
+}
+
+// Counter выдает непересекающиеся блоки порядковых номеров для последовательных стратегий
+type Counter interface {
+	// ShortCodeAllocateIDs резервирует n номеров подряд и возвращает первый из них
+	ShortCodeAllocateIDs(ctx context.Context, n int) (int64, error)
+}
+
+// Config настройки генерации. Нулевые значения - настройки по умолчанию
+type Config struct {
+	Strategy          string
+	Length            int    // начальная длина кода, для words - число слов
+	Alphabet          string // символы кода, кроме words
+	ExcludeLookalikes bool   // убрать из алфавита 0/O/o и 1/l/I
+	Salt              string // соль перемешивания для hashids
+}
+
+// NewGenerator создает генератор стратегии cfg.Strategy. counter нужен только последовательным стратегиям
+func NewGenerator(cfg Config, counter Counter) (CodeGenerator, error) {
+	if cfg.Strategy == "" {
+		cfg.Strategy = StrategyRandom
+	}
+
+	if cfg.Strategy == StrategyWords {
+		if cfg.Length == 0 {
+			cfg.Length = defaultWords
+		}
+		if cfg.Length < minWords || cfg.Length > maxWords {
+			return nil, fmt.Errorf("%w: words strategy needs %d-%d words", ErrInvalidConfig, minWords, maxWords)
+		}
+		return newWordsGenerator(cfg.Length), nil
+	}
+
+	if cfg.Length == 0 {
+		cfg.Length = CodeLength
+	}
+	if cfg.Length < 1 || cfg.Length > MaxLength {
+		return nil, fmt.Errorf("%w: code length must be 1-%d", ErrInvalidConfig, MaxLength)
+	}
+	alphabet, err := buildAlphabet(cfg.Alphabet, cfg.ExcludeLookalikes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Strategy {
+	case StrategyRandom:
+		return newRandomGenerator(alphabet, cfg.Length), nil
+	case StrategySequential, StrategyHashids:
+		if counter == nil {
+			return nil, fmt.Errorf("%w: %s strategy needs a counter", ErrInvalidConfig, cfg.Strategy)
+		}
+		if cfg.Strategy == StrategyHashids {
+			return newSequentialGenerator(counter, newHashidsCodec(alphabet, cfg.Length, cfg.Salt)), nil
+		}
+		return newSequentialGenerator(counter, newBijectiveCodec(alphabet, cfg.Length)), nil
+	case StrategyHash:
+		return newHashGenerator(alphabet, cfg.Length), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown strategy %q", ErrInvalidConfig, cfg.Strategy)
+	}
+}
+
+// buildAlphabet проверяет алфавит: только буквы, цифры, '-' и '_' без повторов
+func buildAlphabet(alphabet string, excludeLookalikes bool) (string, error) {
+	if alphabet == "" {
+		alphabet = CodeAlphabet
+	}
+	if excludeLookalikes {
+		alphabet = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(lookalikes, r) {
+				return -1
+			}
+			return r
+		}, alphabet)
+	}
+
+	seen := make(map[byte]bool, len(alphabet))
+	for i := 0; i < len(alphabet); i++ {
+		c := alphabet[i]
+		valid := c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c == '-' || c == '_'
+		if !valid {
+			return "", fmt.Errorf("%w: alphabet may contain only letters, digits, '-' and '_'", ErrInvalidConfig)
+		}
+		if seen[c] {
+			return "", fmt.Errorf("%w: alphabet has duplicate %q", ErrInvalidConfig, c)
+		}
+		seen[c] = true
+	}
+	if len(alphabet) < minAlphabet {
+		return "", fmt.Errorf("%w: alphabet needs at least %d symbols", ErrInvalidConfig, minAlphabet)
+	}
+	return alphabet, nil
+}
@@ -0,0 +1,159 @@
+package short_code
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryCounter счетчик номеров без хранилища
+type memoryCounter struct {
+	next  int64
+	calls int
+}
+
+func (c *memoryCounter) ShortCodeAllocateIDs(ctx context.Context, n int) (int64, error) {
+	c.calls++
+	first := c.next
+	c.next += int64(n)
+	return first, nil
+}
+
+func TestNewGenerator(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		counter Counter
+		wantErr bool
+	}{
+		{name: "По умолчанию - случайные коды", cfg: Config{}},
+		{name: "Последовательные коды", cfg: Config{Strategy: StrategySequential}, counter: &memoryCounter{}},
+		{name: "Hashids", cfg: Config{Strategy: StrategyHashids, Salt: "pepper"}, counter: &memoryCounter{}},
+		{name: "Хэш адреса", cfg: Config{Strategy: StrategyHash, Length: 6}},
+		{name: "Слова", cfg: Config{Strategy: StrategyWords}},
+		{name: "Неизвестная стратегия", cfg: Config{Strategy: "uuid"}, wantErr: true},
+		{name: "Последовательные коды без счетчика", cfg: Config{Strategy: StrategySequential}, wantErr: true},
+		{name: "Слишком длинный код", cfg: Config{Length: MaxLength + 1}, wantErr: true},
+		{name: "Слишком много слов", cfg: Config{Strategy: StrategyWords, Length: maxWords + 1}, wantErr: true},
+		{name: "Недопустимый символ в алфавите", cfg: Config{Alphabet: "abcdefghijklmnop/"}, wantErr: true},
+		{name: "Повтор в алфавите", cfg: Config{Alphabet: "abcdefghijklmnopa"}, wantErr: true},
+		{name: "Короткий алфавит", cfg: Config{Alphabet: "abcdef"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator, err := NewGenerator(tt.cfg, tt.counter)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidConfig)
+				return
+			}
+			require.NoError(t, err)
+
+			code, err := generator.Generate(context.Background(), "https://example.com/", 0)
+			require.NoError(t, err)
+			assert.NotEmpty(t, code)
+		})
+	}
+}
+
+func TestBuildAlphabetExcludesLookalikes(t *testing.T) {
+	alphabet, err := buildAlphabet("", true)
+	require.NoError(t, err)
+	assert.Len(t, alphabet, len(CodeAlphabet)-len(lookalikes))
+	for _, r := range lookalikes {
+		assert.NotContains(t, alphabet, string(r))
+	}
+}
+
+func TestSequentialCodecs(t *testing.T) {
+	const alphabet = "0123456789abcdef"
+
+	codecs := map[string]codec{
+		"bijective": newBijectiveCodec(alphabet, 2),
+		"hashids":   newHashidsCodec(alphabet, 2, "pepper"),
+	}
+
+	for name, c := range codecs {
+		t.Run(name, func(t *testing.T) {
+			// 16^2 двухсимвольных кодов, дальше длина растет
+			seen := make(map[string]bool)
+			for id := uint64(0); id < 16*16+16*16*16; id++ {
+				code := c.Encode(id)
+				require.False(t, seen[code], "code %s repeated for id %d", code, id)
+				seen[code] = true
+
+				if id < 16*16 {
+					assert.Len(t, code, 2)
+				} else {
+					assert.Len(t, code, 3)
+				}
+			}
+		})
+	}
+
+	bijective := newBijectiveCodec(alphabet, 2)
+	assert.Equal(t, "00", bijective.Encode(0))
+	assert.Equal(t, "01", bijective.Encode(1))
+	assert.Equal(t, "000", bijective.Encode(256))
+
+	hashids := newHashidsCodec(alphabet, 2, "pepper")
+	assert.NotEqual(t, "01", hashids.Encode(1))
+	assert.Equal(t, hashids.Encode(42), newHashidsCodec(alphabet, 2, "pepper").Encode(42))
+	assert.NotEqual(t, hashids.Encode(42), newHashidsCodec(alphabet, 2, "salt").Encode(42))
+}
+
+func TestSequentialGeneratorBlocks(t *testing.T) {
+	counter := &memoryCounter{}
+	generator := newSequentialGenerator(counter, newBijectiveCodec(CodeAlphabet, 4))
+
+	seen := make(map[string]bool)
+	for i := 0; i < idBlockSize+1; i++ {
+		code, err := generator.Generate(context.Background(), "", 0)
+		require.NoError(t, err)
+		require.False(t, seen[code])
+		seen[code] = true
+	}
+	assert.Equal(t, 2, counter.calls)
+}
+
+func TestHashGenerator(t *testing.T) {
+	generator := newHashGenerator(CodeAlphabet, 7)
+	ctx := context.Background()
+
+	first, _ := generator.Generate(ctx, "https://example.com/", 0)
+	again, _ := generator.Generate(ctx, "https://example.com/", 0)
+	retry, _ := generator.Generate(ctx, "https://example.com/", 1)
+	other, _ := generator.Generate(ctx, "https://example.org/", 0)
+
+	assert.Len(t, first, 7)
+	assert.Equal(t, first, again)
+	assert.NotEqual(t, first, retry)
+	assert.NotEqual(t, first, other)
+	assert.True(t, generator.Deterministic())
+
+	generator.Grow()
+	longer, _ := generator.Generate(ctx, "https://example.com/", 0)
+	assert.Len(t, longer, 8)
+}
+
+func TestWordsGenerator(t *testing.T) {
+	generator := newWordsGenerator(3)
+
+	code, err := generator.Generate(context.Background(), "", 0)
+	require.NoError(t, err)
+
+	words := strings.Split(code, "-")
+	require.Len(t, words, 3)
+	for _, word := range words {
+		assert.Contains(t, wordList[:], word)
+	}
+
+	for i := 0; i < maxWords; i++ {
+		generator.Grow()
+	}
+	code, _ = generator.Generate(context.Background(), "", 0)
+	assert.Len(t, strings.Split(code, "-"), maxWords)
+}
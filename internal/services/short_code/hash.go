@@ -0,0 +1,43 @@
+package short_code
+
+import (
+	"context"
+	"crypto/sha256"
+	"math/big"
+	"strconv"
+	"sync/atomic"
+)
+
+// hashGenerator код - хэш адреса назначения в алфавите. При коллизии берется хэш со следующим номером попытки
+type hashGenerator struct {
+	alphabet string
+	length   atomic.Int32
+}
+
+func newHashGenerator(alphabet string, length int) *hashGenerator {
+	g := &hashGenerator{alphabet: alphabet}
+	g.length.Store(int32(length))
+	return g
+}
+
+func (g *hashGenerator) Generate(ctx context.Context, url string, attempt int) (string, error) {
+	sum := sha256.Sum256([]byte(url + "\x00" + strconv.Itoa(attempt)))
+	value := new(big.Int).SetBytes(sum[:])
+	base := big.NewInt(int64(len(g.alphabet)))
+	digit := new(big.Int)
+
+	b := make([]byte, g.length.Load())
+	for i := range b {
+		value.DivMod(value, base, digit)
+		b[i] = g.alphabet[digit.Int64()]
+	}
+	return string(b), nil
+}
+
+func (g *hashGenerator) Deterministic() bool {
+	return true
+}
+
+func (g *hashGenerator) Grow() {
+	growLength(&g.length, MaxLength)
+}
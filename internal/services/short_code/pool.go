@@ -0,0 +1,242 @@
+package short_code
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"urlshortener/internal/domain/models"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	CodeLength   = 8
+	CodeAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	DefaultPoolSize = 256
+	// maxIdleRounds столько раз подряд хранилище может не выдать ни одного кода, прежде чем Take сдастся
+	maxIdleRounds = 10
+)
+
+var ErrCodesExhausted = errors.New("failed to reserve unused short codes")
+
+//go:generate mockgen -source=pool.go -destination=../../mocks/mock_short_code_store.go -package=mocks -mock_names=Store=MockShortCodeStore
+type Store interface {
+	// ShortCodeReserve атомарно резервирует свободные коды из candidates и возвращает зарезервированные.
+	// Занятые ссылками или зарезервированные ранее коды пропускаются
+	ShortCodeReserve(ctx context.Context, candidates []string) ([]string, error)
+}
+
+// Pool выдает заранее зарезервированные в хранилище коды.
+// Резерв в хранилище гарантирует, что код не достанется двум ссылкам даже с нескольких экземпляров,
+// поэтому создание ссылки не проверяет код перед вставкой.
+// Коды, зависящие от адреса, заранее не выдать: они резервируются при создании
+type Pool struct {
+	store     Store
+	generator CodeGenerator
+	size      int // сколько кодов держать в запасе
+	lowWater  int // ниже этого запаса запускается фоновое пополнение
+
+	mu     sync.Mutex
+	codes  []string
+	refill chan struct{}
+}
+
+// NewPool создает пул на size кодов, size меньше 1 заменяется на DefaultPoolSize.
+// Пополняется пул в Run, до запуска Run коды резервируются по запросу
+func NewPool(store Store, generator CodeGenerator, size int) *Pool {
+	if size < 1 {
+		size = DefaultPoolSize
+	}
+	return &Pool{
+		store:     store,
+		generator: generator,
+		size:      size,
+		lowWater:  size / 4,
+		refill:    make(chan struct{}, 1),
+	}
+}
+
+// Take выдает по коду на каждый адрес из urls. Если запаса не хватает, недостающие резервируются сразу
+func (p *Pool) Take(ctx context.Context, urls []string) ([]string, error) {
+	if len(urls) == 0 {
+		return nil, models.ErrInvalidData
+	}
+	if p.generator.Deterministic() {
+		return p.reserveFor(ctx, urls)
+	}
+
+	n := len(urls)
+	codes := p.takeBuffered(n)
+	for idle := 0; len(codes) < n; {
+		reserved, err := p.reserve(ctx, n-len(codes))
+		if err != nil {
+			p.putBack(codes)
+			return nil, err
+		}
+		if len(reserved) == 0 {
+			idle++
+			if idle >= maxIdleRounds {
+				p.putBack(codes)
+				return nil, ErrCodesExhausted
+			}
+			continue
+		}
+		codes = append(codes, reserved...)
+	}
+	return codes, nil
+}
+
+// Run пополняет пул до size, когда запас опускается ниже четверти. Работает до отмены ctx
+func (p *Pool) Run(ctx context.Context, log *zerolog.Logger) {
+	if p.generator.Deterministic() {
+		return
+	}
+
+	p.fill(ctx, log)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.refill:
+			p.fill(ctx, log)
+		}
+	}
+}
+
+// fill резервирует коды вне блокировки, чтобы не задерживать Take на время запроса к хранилищу
+func (p *Pool) fill(ctx context.Context, log *zerolog.Logger) {
+	for idle := 0; idle < maxIdleRounds; {
+		p.mu.Lock()
+		need := p.size - len(p.codes)
+		p.mu.Unlock()
+		if need <= 0 {
+			return
+		}
+
+		reserved, err := p.reserve(ctx, need)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error().Err(err).Msg("Failed to refill short code pool")
+			}
+			return
+		}
+		if len(reserved) == 0 {
+			idle++
+			continue
+		}
+		p.putBack(reserved)
+	}
+	log.Warn().Msg("Short code pool was not refilled, keyspace may be running out")
+}
+
+func (p *Pool) takeBuffered(n int) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Коды выдаются в порядке резерва, последовательные стратегии так и остаются последовательными
+	n = min(n, len(p.codes))
+	codes := make([]string, n)
+	copy(codes, p.codes[:n])
+	p.codes = p.codes[n:]
+
+	if len(p.codes) < p.lowWater {
+		select {
+		case p.refill <- struct{}{}:
+		default:
+		}
+	}
+	return codes
+}
+
+// putBack возвращает в запас зарезервированные, но не выданные коды
+func (p *Pool) putBack(codes []string) {
+	if len(codes) == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.codes = append(p.codes, codes...)
+}
+
+func (p *Pool) reserve(ctx context.Context, n int) ([]string, error) {
+	candidates := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	// В маленьком пространстве кодов случайные кандидаты часто повторяются, число попыток ограничено
+	for attempts := 0; len(candidates) < n && attempts < 2*n; attempts++ {
+		code, err := p.generator.Generate(ctx, "", 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate short code: %w", err)
+		}
+		if !seen[code] {
+			seen[code] = true
+			candidates = append(candidates, code)
+		}
+	}
+
+	reserved, err := p.store.ShortCodeReserve(ctx, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve short codes: %w", err)
+	}
+	p.growIfCrowded(len(candidates), len(reserved))
+	return reserved, nil
+}
+
+// reserveFor резервирует коды, зависящие от адреса. Адреса, чей код занят, пробуются со следующей попыткой
+func (p *Pool) reserveFor(ctx context.Context, urls []string) ([]string, error) {
+	codes := make([]string, len(urls))
+	pending := make([]int, len(urls))
+	for i := range urls {
+		pending[i] = i
+	}
+
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if attempt >= maxIdleRounds {
+			return nil, ErrCodesExhausted
+		}
+
+		candidates := make([]string, 0, len(pending))
+		owners := make(map[string]int, len(pending))
+		var retry []int
+		for _, i := range pending {
+			code, err := p.generator.Generate(ctx, urls[i], attempt)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate short code: %w", err)
+			}
+			// Одинаковые адреса в одном пакете дают один код, остальным нужна следующая попытка
+			if _, taken := owners[code]; taken {
+				retry = append(retry, i)
+				continue
+			}
+			owners[code] = i
+			candidates = append(candidates, code)
+		}
+
+		reserved, err := p.store.ShortCodeReserve(ctx, candidates)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reserve short codes: %w", err)
+		}
+		p.growIfCrowded(len(candidates), len(reserved))
+
+		got := make(map[string]bool, len(reserved))
+		for _, code := range reserved {
+			codes[owners[code]] = code
+			got[code] = true
+		}
+		for _, code := range candidates {
+			if !got[code] {
+				retry = append(retry, owners[code])
+			}
+		}
+		pending = retry
+	}
+	return codes, nil
+}
+
+// growIfCrowded удлиняет коды, если больше половины кандидатов уже занято
+func (p *Pool) growIfCrowded(candidates, reserved int) {
+	if candidates > 1 && reserved*2 < candidates {
+		p.generator.Grow()
+	}
+}
@@ -0,0 +1,211 @@
+package short_code
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// reserveAll резервирует все кандидаты, как пустое хранилище
+func reserveAll(ctx context.Context, candidates []string) ([]string, error) {
+	return candidates, nil
+}
+
+func testURLs(n int) []string {
+	urls := make([]string, n)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("https://example.com/%d", i)
+	}
+	return urls
+}
+
+func newTestPool(store Store, size int) *Pool {
+	return NewPool(store, newRandomGenerator(CodeAlphabet, CodeLength), size)
+}
+
+func TestPool_Take(t *testing.T) {
+	errDB := errors.New("db error")
+
+	tests := []struct {
+		name       string
+		n          int
+		setupMocks func(m *mocks.MockShortCodeStore)
+		wantErr    error
+	}{
+		{
+			name: "Пустой пул резервирует коды сразу",
+			n:    3,
+			setupMocks: func(m *mocks.MockShortCodeStore) {
+				m.EXPECT().ShortCodeReserve(gomock.Any(), gomock.Len(3)).DoAndReturn(reserveAll)
+			},
+		},
+		{
+			name: "Занятые коды пропускаются, недостающие резервируются повторно",
+			n:    2,
+			setupMocks: func(m *mocks.MockShortCodeStore) {
+				gomock.InOrder(
+					m.EXPECT().ShortCodeReserve(gomock.Any(), gomock.Len(2)).
+						DoAndReturn(func(ctx context.Context, candidates []string) ([]string, error) {
+							return candidates[:1], nil
+						}),
+					m.EXPECT().ShortCodeReserve(gomock.Any(), gomock.Len(1)).DoAndReturn(reserveAll),
+				)
+			},
+		},
+		{
+			name: "Хранилище не выдает свободных кодов",
+			n:    1,
+			setupMocks: func(m *mocks.MockShortCodeStore) {
+				m.EXPECT().ShortCodeReserve(gomock.Any(), gomock.Any()).Return([]string{}, nil).Times(maxIdleRounds)
+			},
+			wantErr: ErrCodesExhausted,
+		},
+		{
+			name: "Ошибка хранилища",
+			n:    1,
+			setupMocks: func(m *mocks.MockShortCodeStore) {
+				m.EXPECT().ShortCodeReserve(gomock.Any(), gomock.Any()).Return(nil, errDB)
+			},
+			wantErr: errDB,
+		},
+		{
+			name:    "Некорректное количество",
+			n:       0,
+			wantErr: models.ErrInvalidData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockShortCodeStore(ctrl)
+			if tt.setupMocks != nil {
+				tt.setupMocks(store)
+			}
+
+			codes, err := newTestPool(store, 8).Take(context.Background(), testURLs(tt.n))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, codes, tt.n)
+			for _, code := range codes {
+				assert.Len(t, code, CodeLength)
+			}
+		})
+	}
+}
+
+func TestPool_TakeFromBuffer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockShortCodeStore(ctrl)
+	store.EXPECT().ShortCodeReserve(gomock.Any(), gomock.Len(8)).DoAndReturn(reserveAll)
+
+	pool := newTestPool(store, 8)
+	pool.fill(context.Background(), nil)
+
+	// Весь запас выдается без обращений к хранилищу
+	seen := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		codes, err := pool.Take(context.Background(), testURLs(2))
+		require.NoError(t, err)
+		for _, code := range codes {
+			assert.False(t, seen[code], "code %s issued twice", code)
+			seen[code] = true
+		}
+	}
+
+	select {
+	case <-pool.refill:
+	default:
+		t.Fatal("refill was not requested when the pool ran low")
+	}
+}
+
+func TestPool_TakeReturnsCodesOnError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockShortCodeStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().ShortCodeReserve(gomock.Any(), gomock.Len(4)).DoAndReturn(reserveAll),
+		store.EXPECT().ShortCodeReserve(gomock.Any(), gomock.Len(2)).Return(nil, errors.New("db error")),
+	)
+
+	pool := newTestPool(store, 4)
+	pool.fill(context.Background(), nil)
+
+	_, err := pool.Take(context.Background(), testURLs(6))
+	require.Error(t, err)
+
+	// Неудачный запрос не теряет уже зарезервированные коды
+	codes, err := pool.Take(context.Background(), testURLs(4))
+	require.NoError(t, err)
+	assert.Len(t, codes, 4)
+}
+
+func TestPool_TakeDeterministic(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	generator := newHashGenerator(CodeAlphabet, CodeLength)
+	first, _ := generator.Generate(context.Background(), "https://example.com/a", 0)
+	retry, _ := generator.Generate(context.Background(), "https://example.com/a", 1)
+	other, _ := generator.Generate(context.Background(), "https://example.com/b", 0)
+
+	// Код первого адреса занят, для него берется следующая попытка
+	store := mocks.NewMockShortCodeStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().ShortCodeReserve(gomock.Any(), []string{first, other}).Return([]string{other}, nil),
+		store.EXPECT().ShortCodeReserve(gomock.Any(), []string{retry}).DoAndReturn(reserveAll),
+	)
+
+	codes, err := NewPool(store, generator, 8).Take(context.Background(), []string{"https://example.com/a", "https://example.com/b"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{retry, other}, codes)
+}
+
+func TestPool_GrowsWhenCrowded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockShortCodeStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().ShortCodeReserve(gomock.Any(), gomock.Len(4)).
+			DoAndReturn(func(ctx context.Context, candidates []string) ([]string, error) {
+				return candidates[:1], nil
+			}),
+		store.EXPECT().ShortCodeReserve(gomock.Any(), gomock.Len(3)).
+			DoAndReturn(func(ctx context.Context, candidates []string) ([]string, error) {
+				for _, code := range candidates {
+					assert.Len(t, code, CodeLength+1)
+				}
+				return candidates, nil
+			}),
+	)
+
+	codes, err := newTestPool(store, 8).Take(context.Background(), testURLs(4))
+	require.NoError(t, err)
+	assert.Len(t, codes, 4)
+}
+
+func TestRandomCode(t *testing.T) {
+	code := RandomCode()
+	assert.Len(t, code, CodeLength)
+	for _, r := range code {
+		assert.Contains(t, CodeAlphabet, string(r))
+	}
+}
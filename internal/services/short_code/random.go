@@ -0,0 +1,58 @@
+package short_code
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"sync/atomic"
+)
+
+// randomGenerator случайные коды, свободный код находится резервом
+type randomGenerator struct {
+	alphabet string
+	length   atomic.Int32
+}
+
+func newRandomGenerator(alphabet string, length int) *randomGenerator {
+	g := &randomGenerator{alphabet: alphabet}
+	g.length.Store(int32(length))
+	return g
+}
+
+func (g *randomGenerator) Generate(ctx context.Context, url string, attempt int) (string, error) {
+	return randomString(g.alphabet, int(g.length.Load())), nil
+}
+
+func (g *randomGenerator) Deterministic() bool {
+	return false
+}
+
+func (g *randomGenerator) Grow() {
+	growLength(&g.length, MaxLength)
+}
+
+// growLength увеличивает длину на единицу, но не больше limit
+func growLength(length *atomic.Int32, limit int) {
+	for {
+		current := length.Load()
+		if int(current) >= limit || length.CompareAndSwap(current, current+1) {
+			return
+		}
+	}
+}
+
+// RandomCode случайный код длины CodeLength из CodeAlphabet
+func RandomCode() string {
+	return randomString(CodeAlphabet, CodeLength)
+}
+
+func randomString(alphabet string, length int) string {
+	b := make([]byte, length)
+	letterCount := big.NewInt(int64(len(alphabet)))
+
+	for i := range b {
+		n, _ := rand.Int(rand.Reader, letterCount)
+		b[i] = alphabet[n.Int64()]
+	}
+	return string(b)
+}
@@ -0,0 +1,170 @@
+package short_code
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"sync"
+)
+
+// idBlockSize сколько номеров экземпляр берет у счетчика за раз
+const idBlockSize = 64
+
+// codec переводит порядковый номер в код. Разные номера дают разные коды
+type codec interface {
+	Encode(id uint64) string
+}
+
+// sequentialGenerator коды из порядковых номеров общего счетчика. Номера не повторяются,
+// поэтому коды не конфликтуют друг с другом, а длина растет вместе с номером
+type sequentialGenerator struct {
+	counter Counter
+	codec   codec
+
+	mu   sync.Mutex
+	next int64
+	end  int64
+}
+
+func newSequentialGenerator(counter Counter, codec codec) *sequentialGenerator {
+	return &sequentialGenerator{counter: counter, codec: codec}
+}
+
+func (g *sequentialGenerator) Generate(ctx context.Context, url string, attempt int) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.next >= g.end {
+		first, err := g.counter.ShortCodeAllocateIDs(ctx, idBlockSize)
+		if err != nil {
+			return "", fmt.Errorf("failed to allocate code ids: %w", err)
+		}
+		g.next, g.end = first, first+idBlockSize
+	}
+
+	id := g.next
+	g.next++
+	return g.codec.Encode(uint64(id)), nil
+}
+
+func (g *sequentialGenerator) Deterministic() bool {
+	return false
+}
+
+// Grow не нужен: код удлиняется, когда номера текущей длины заканчиваются
+func (g *sequentialGenerator) Grow() {}
+
+// tiers раскладывает номера по длинам: первые base^minLength номеров получают коды длины minLength,
+// следующие base^(minLength+1) - на символ длиннее и так далее
+type tiers struct {
+	alphabet  string
+	minLength int
+}
+
+// locate возвращает длину кода номера id, его номер среди кодов этой длины и их число
+func (t tiers) locate(id uint64) (length int, local uint64, capacity uint64) {
+	base := uint64(len(t.alphabet))
+	for length = t.minLength; ; length++ {
+		capacity = pow(base, length)
+		if id < capacity || length >= MaxLength {
+			return length, id, capacity
+		}
+		id -= capacity
+	}
+}
+
+func (t tiers) digits(alphabet string, value uint64, length int) string {
+	base := uint64(len(alphabet))
+	b := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		b[i] = alphabet[value%base]
+		value /= base
+	}
+	return string(b)
+}
+
+// pow base^exp, при переполнении - math.MaxUint64
+func pow(base uint64, exp int) uint64 {
+	result := uint64(1)
+	for i := 0; i < exp; i++ {
+		hi, lo := bits.Mul64(result, base)
+		if hi != 0 {
+			return math.MaxUint64
+		}
+		result = lo
+	}
+	return result
+}
+
+// bijectiveCodec номер в системе счисления алфавита: 0 -> "0000", 1 -> "0001"
+type bijectiveCodec struct {
+	tiers
+}
+
+func newBijectiveCodec(alphabet string, length int) *bijectiveCodec {
+	return &bijectiveCodec{tiers{alphabet: alphabet, minLength: length}}
+}
+
+func (c *bijectiveCodec) Encode(id uint64) string {
+	length, local, _ := c.locate(id)
+	return c.digits(c.alphabet, local, length)
+}
+
+// hashidsCodec как в Hashids, соседние номера дают непохожие коды: алфавит перемешан солью,
+// а номер внутри своей длины переставлен взаимно однозначно (a*x + b) mod base^length
+type hashidsCodec struct {
+	tiers
+	shuffled   string
+	multiplier uint64
+	offset     uint64
+}
+
+func newHashidsCodec(alphabet string, length int, salt string) *hashidsCodec {
+	sum := sha256.Sum256([]byte(salt))
+
+	shuffled := []byte(alphabet)
+	rng := rand.New(rand.NewChaCha8(sum))
+	rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	return &hashidsCodec{
+		tiers:      tiers{alphabet: alphabet, minLength: length},
+		shuffled:   string(shuffled),
+		multiplier: binary.BigEndian.Uint64(sum[:8]),
+		offset:     binary.BigEndian.Uint64(sum[8:16]),
+	}
+}
+
+func (c *hashidsCodec) Encode(id uint64) string {
+	length, local, capacity := c.locate(id)
+	if capacity != math.MaxUint64 {
+		local = c.permute(local, capacity)
+	}
+	return c.digits(c.shuffled, local, length)
+}
+
+// permute перестановка [0, capacity): множитель взаимно прост с основанием алфавита,
+// значит и с capacity = base^length
+func (c *hashidsCodec) permute(x, capacity uint64) uint64 {
+	base := uint64(len(c.alphabet))
+	a := c.multiplier % capacity
+	for a < 2 || gcd(a, base) != 1 {
+		a = (a + 1) % capacity
+	}
+
+	hi, lo := bits.Mul64(a, x)
+	lo, carry := bits.Add64(lo, c.offset%capacity, 0)
+	return bits.Rem64(hi+carry, lo, capacity)
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
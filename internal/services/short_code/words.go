@@ -0,0 +1,66 @@
+package short_code
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	defaultWords = 3
+	minWords     = 2
+	maxWords     = 6
+)
+
+// wordList короткие слова без похожих написаний, 128 штук - 7 бит на слово
+var wordList = [...]string{
+	"acorn", "amber", "anchor", "apple", "arrow", "aspen", "atlas", "badge",
+	"bamboo", "basil", "beacon", "berry", "birch", "bison", "blaze", "bloom",
+	"breeze", "brook", "cabin", "cactus", "camel", "candle", "canyon", "cedar",
+	"cherry", "cider", "cliff", "clover", "cobalt", "comet", "coral", "cotton",
+	"crane", "creek", "crystal", "daisy", "delta", "desert", "dolphin", "dune",
+	"eagle", "echo", "ember", "falcon", "fern", "fig", "flint", "forest",
+	"fox", "galaxy", "garden", "geyser", "ginger", "glacier", "granite", "grove",
+	"harbor", "hazel", "heron", "honey", "island", "ivy", "jade", "jasmine",
+	"juniper", "kayak", "kettle", "kiwi", "lagoon", "lantern", "lark", "lemon",
+	"lily", "lotus", "maple", "marble", "meadow", "melon", "mint", "mist",
+	"moss", "nectar", "nova", "oak", "oasis", "ocean", "olive", "orbit",
+	"orchid", "otter", "panda", "pebble", "pepper", "pine", "planet", "plum",
+	"pond", "poppy", "prairie", "quartz", "quill", "rain", "raven", "reef",
+	"ridge", "river", "robin", "saddle", "sage", "salmon", "sequoia", "shell",
+	"sierra", "spruce", "star", "stone", "summit", "sunset", "thistle", "tiger",
+	"timber", "topaz", "tulip", "tundra", "valley", "velvet", "walnut", "willow",
+}
+
+// wordsGenerator коды из случайных слов через дефис, их легко продиктовать
+type wordsGenerator struct {
+	words atomic.Int32
+}
+
+func newWordsGenerator(words int) *wordsGenerator {
+	g := &wordsGenerator{}
+	g.words.Store(int32(words))
+	return g
+}
+
+func (g *wordsGenerator) Generate(ctx context.Context, url string, attempt int) (string, error) {
+	count := int(g.words.Load())
+	listSize := big.NewInt(int64(len(wordList)))
+
+	words := make([]string, count)
+	for i := range words {
+		n, _ := rand.Int(rand.Reader, listSize)
+		words[i] = wordList[n.Int64()]
+	}
+	return strings.Join(words, "-"), nil
+}
+
+func (g *wordsGenerator) Deterministic() bool {
+	return false
+}
+
+func (g *wordsGenerator) Grow() {
+	growLength(&g.words, maxWords)
+}
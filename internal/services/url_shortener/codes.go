@@ -0,0 +1,33 @@
+package url_shortener
+
+import "context"
+
+// CodeSource выдает коды, уже зарезервированные за сервисом, их не нужно проверять перед вставкой.
+// По коду на каждый адрес: некоторые стратегии строят код из адреса
+type CodeSource interface {
+	Take(ctx context.Context, urls []string) ([]string, error)
+}
+
+// WithCodePool берет коды новых ссылок из пула вместо подбора с проверкой
+func WithCodePool(codes CodeSource) Option {
+	return func(s *URLShortener) {
+		s.codes = codes
+	}
+}
+
+// takeCodes выдает коды для новых ссылок на urls
+func (s *URLShortener) takeCodes(ctx context.Context, urls []string) ([]string, error) {
+	if s.codes != nil {
+		return s.codes.Take(ctx, urls)
+	}
+
+	codes := make([]string, 0, len(urls))
+	for len(codes) < len(urls) {
+		code, err := s.generateUniqueToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
@@ -0,0 +1,69 @@
+package url_shortener
+
+import (
+	"context"
+	"testing"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type stubCodes struct {
+	codes []string
+	calls int
+}
+
+func (c *stubCodes) Take(ctx context.Context, urls []string) ([]string, error) {
+	c.calls++
+	taken := c.codes[:len(urls)]
+	c.codes = c.codes[len(urls):]
+	return taken, nil
+}
+
+func TestURLShortener_CodePool(t *testing.T) {
+	t.Run("Одиночное создание берет код из пула без проверки", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := mocks.NewMockURLStorage(ctrl)
+		codes := &stubCodes{codes: []string{"pool0001"}}
+		service := NewServiceURLShortener(mockStorage, "http://short", WithCodePool(codes))
+
+		mockStorage.EXPECT().ShortenedLinkCreate(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, link models.ShortenedLink) (models.ShortenedLink, error) {
+				return link, nil
+			})
+
+		got, err := service.SetURL(context.Background(), models.ShortenedLink{OriginalURL: "https://example.com/", UserID: 1})
+		require.NoError(t, err)
+		assert.Equal(t, "pool0001", got.ShortCode)
+	})
+
+	t.Run("Пакет получает коды одним обращением", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := mocks.NewMockURLStorage(ctrl)
+		codes := &stubCodes{codes: []string{"pool0001", "pool0002"}}
+		service := NewServiceURLShortener(mockStorage, "http://short", WithCodePool(codes))
+
+		mockStorage.EXPECT().ShortenedLinkBatchExists(gomock.Any(), gomock.Any()).Return(nil, nil)
+		mockStorage.EXPECT().ShortenedLinkBatchCreate(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, links []models.ShortenedLink) ([]models.ShortenedLink, error) {
+				return links, nil
+			})
+
+		got, err := service.BatchCreate(context.Background(), []models.ShortenedLink{
+			{OriginalURL: "https://example.com/a", UserID: 1},
+			{OriginalURL: "https://example.com/b", UserID: 1},
+		})
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		assert.Equal(t, "pool0001", got[0].ShortCode)
+		assert.Equal(t, "pool0002", got[1].ShortCode)
+		assert.Equal(t, 1, codes.calls)
+	})
+}
@@ -0,0 +1,156 @@
+package url_shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+	"urlshortener/internal/domain/models"
+)
+
+// DefaultReportThreshold число разных отправителей жалоб, после которого ссылка уходит в карантин
+const DefaultReportThreshold = 5
+
+// maxReportDetails ограничение длины комментария к жалобе в символах
+const maxReportDetails = 1000
+
+var abuseReasons = map[string]bool{
+	models.AbuseReasonPhishing: true,
+	models.AbuseReasonMalware:  true,
+	models.AbuseReasonSpam:     true,
+	models.AbuseReasonIllegal:  true,
+	models.AbuseReasonOther:    true,
+}
+
+// WithReportThreshold задает число разных отправителей жалоб для автоматического карантина.
+// 0 отключает карантин, жалобы только копятся для администратора
+func WithReportThreshold(threshold int) Option {
+	return func(s *URLShortener) {
+		if threshold < 0 {
+			threshold = 0
+		}
+		s.reportThreshold = threshold
+	}
+}
+
+// ReportLink принимает жалобу на ссылку. Когда число разных отправителей нерассмотренных жалоб
+// достигает порога, ссылка помещается в карантин. Заблокированные ссылки остаются заблокированными
+func (s *URLShortener) ReportLink(ctx context.Context, shortKey string, report models.AbuseReport) (models.AbuseReport, error) {
+	report.Reason = strings.ToLower(strings.TrimSpace(report.Reason))
+	if !abuseReasons[report.Reason] {
+		return models.AbuseReport{}, fmt.Errorf("%w: unknown report reason %q", models.ErrInvalidData, report.Reason)
+	}
+	report.Details = strings.TrimSpace(report.Details)
+	if utf8.RuneCountInString(report.Details) > maxReportDetails {
+		return models.AbuseReport{}, fmt.Errorf("%w: details must be at most %d characters", models.ErrInvalidData, maxReportDetails)
+	}
+
+	var created models.AbuseReport
+	err := s.storage.WithinTx(ctx, func(ctx context.Context) error {
+		link, err := s.getLink(ctx, shortKey)
+		if err != nil {
+			return err
+		}
+
+		report.LinkID = link.ID
+		report.Resolved = false
+		report.CreatedAt = s.now()
+		created, err = s.storage.AbuseReportCreate(ctx, report)
+		if err != nil {
+			return fmt.Errorf("failed to save report: %w", err)
+		}
+
+		if s.reportThreshold == 0 || link.Moderation != models.ModerationNone {
+			return nil
+		}
+
+		reporters, err := s.storage.AbuseReportCountReporters(ctx, link.ID)
+		if err != nil {
+			return fmt.Errorf("failed to count reporters: %w", err)
+		}
+		if reporters < s.reportThreshold {
+			return nil
+		}
+
+		if err := s.storage.ShortenedLinkSetModeration(ctx, link.ID, models.ModerationQuarantined); err != nil {
+			return fmt.Errorf("failed to quarantine link: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.AbuseReport{}, err
+	}
+
+	return created, nil
+}
+
+// GetPendingReports возвращает очередь модерации: ссылки с нерассмотренными жалобами
+func (s *URLShortener) GetPendingReports(ctx context.Context) ([]models.AbuseReportSummary, error) {
+	summaries, err := s.storage.AbuseReportListPending(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending reports: %w", err)
+	}
+	return summaries, nil
+}
+
+// GetLinkReports возвращает ссылку и все жалобы на нее, включая рассмотренные.
+// Удаленные ссылки тоже доступны администратору
+func (s *URLShortener) GetLinkReports(ctx context.Context, shortKey string) (models.ShortenedLink, []models.AbuseReport, error) {
+	link, err := s.moderatedLink(ctx, shortKey)
+	if err != nil {
+		return models.ShortenedLink{}, nil, err
+	}
+
+	reports, err := s.storage.AbuseReportGetByLink(ctx, link.ID)
+	if err != nil {
+		return models.ShortenedLink{}, nil, fmt.Errorf("failed to get reports: %w", err)
+	}
+
+	return link, reports, nil
+}
+
+// ClearLink снимает карантин или блокировку и закрывает жалобы на ссылку
+func (s *URLShortener) ClearLink(ctx context.Context, shortKey string) (models.ShortenedLink, error) {
+	return s.moderate(ctx, shortKey, models.ModerationNone)
+}
+
+// BanLink блокирует ссылку и закрывает жалобы на нее
+func (s *URLShortener) BanLink(ctx context.Context, shortKey string) (models.ShortenedLink, error) {
+	return s.moderate(ctx, shortKey, models.ModerationBanned)
+}
+
+func (s *URLShortener) moderate(ctx context.Context, shortKey string, status string) (models.ShortenedLink, error) {
+	var result models.ShortenedLink
+	err := s.storage.WithinTx(ctx, func(ctx context.Context) error {
+		link, err := s.moderatedLink(ctx, shortKey)
+		if err != nil {
+			return err
+		}
+
+		if err := s.storage.AbuseReportResolve(ctx, link.ID); err != nil {
+			return fmt.Errorf("failed to resolve reports: %w", err)
+		}
+		if err := s.storage.ShortenedLinkSetModeration(ctx, link.ID, status); err != nil {
+			return fmt.Errorf("failed to set moderation: %w", err)
+		}
+
+		link.Moderation = status
+		result = link
+		return nil
+	})
+	if err != nil {
+		return models.ShortenedLink{}, err
+	}
+
+	return result, nil
+}
+
+// moderatedLink ищет ссылку для администратора: удаленная ссылка не считается ошибкой
+func (s *URLShortener) moderatedLink(ctx context.Context, shortKey string) (models.ShortenedLink, error) {
+	link, err := s.getLink(ctx, shortKey)
+	if err != nil && !errors.Is(err, models.ErrGone) {
+		return models.ShortenedLink{}, err
+	}
+	return link, nil
+}
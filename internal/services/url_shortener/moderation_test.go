@@ -0,0 +1,203 @@
+package url_shortener
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestURLShortener_ReportLink(t *testing.T) {
+	link := models.ShortenedLink{ID: 7, ShortCode: "abc123", OriginalURL: "https://example.com/", UserID: 1}
+
+	tests := []struct {
+		name       string
+		link       models.ShortenedLink
+		report     models.AbuseReport
+		reporters  int
+		setupMocks func(m *mocks.MockURLStorage, link models.ShortenedLink, reporters int)
+		wantErr    error
+	}{
+		{
+			name:      "Жалоба ниже порога",
+			link:      link,
+			report:    models.AbuseReport{Reason: "Spam", Reporter: "192.0.2.1"},
+			reporters: 2,
+			setupMocks: func(m *mocks.MockURLStorage, link models.ShortenedLink, reporters int) {
+				m.EXPECT().AbuseReportCreate(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, r models.AbuseReport) (models.AbuseReport, error) {
+						assert.Equal(t, models.AbuseReasonSpam, r.Reason)
+						assert.Equal(t, link.ID, r.LinkID)
+						r.ID = 1
+						return r, nil
+					})
+				m.EXPECT().AbuseReportCountReporters(gomock.Any(), link.ID).Return(reporters, nil)
+			},
+		},
+		{
+			name:      "Порог достигнут - карантин",
+			link:      link,
+			report:    models.AbuseReport{Reason: models.AbuseReasonPhishing, Reporter: "192.0.2.3"},
+			reporters: 3,
+			setupMocks: func(m *mocks.MockURLStorage, link models.ShortenedLink, reporters int) {
+				m.EXPECT().AbuseReportCreate(gomock.Any(), gomock.Any()).Return(models.AbuseReport{ID: 3}, nil)
+				m.EXPECT().AbuseReportCountReporters(gomock.Any(), link.ID).Return(reporters, nil)
+				m.EXPECT().ShortenedLinkSetModeration(gomock.Any(), link.ID, models.ModerationQuarantined).Return(nil)
+			},
+		},
+		{
+			name: "Заблокированная ссылка не уходит в карантин",
+			link: func() models.ShortenedLink {
+				l := link
+				l.Moderation = models.ModerationBanned
+				return l
+			}(),
+			report: models.AbuseReport{Reason: models.AbuseReasonMalware},
+			setupMocks: func(m *mocks.MockURLStorage, link models.ShortenedLink, reporters int) {
+				m.EXPECT().AbuseReportCreate(gomock.Any(), gomock.Any()).Return(models.AbuseReport{ID: 4}, nil)
+			},
+		},
+		{
+			name: "Удаленная ссылка",
+			link: func() models.ShortenedLink {
+				l := link
+				l.DeletedFlag = true
+				return l
+			}(),
+			report:     models.AbuseReport{Reason: models.AbuseReasonSpam},
+			setupMocks: func(m *mocks.MockURLStorage, link models.ShortenedLink, reporters int) {},
+			wantErr:    models.ErrGone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockURLStorage(ctrl)
+			service := NewServiceURLShortener(mockStorage, "http://short", WithReportThreshold(3))
+
+			mockStorage.EXPECT().
+				WithinTx(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+					return fn(ctx)
+				})
+			mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), tt.link.ShortCode).Return(tt.link, nil)
+			tt.setupMocks(mockStorage, tt.link, tt.reporters)
+
+			_, err := service.ReportLink(context.Background(), tt.link.ShortCode, tt.report)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("Неверная жалоба отклоняется до обращения к хранилищу", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		service := NewServiceURLShortener(mocks.NewMockURLStorage(ctrl), "http://short")
+
+		_, err := service.ReportLink(context.Background(), "abc123", models.AbuseReport{Reason: "boring"})
+		assert.ErrorIs(t, err, models.ErrInvalidData)
+
+		_, err = service.ReportLink(context.Background(), "abc123", models.AbuseReport{
+			Reason:  models.AbuseReasonOther,
+			Details: strings.Repeat("x", maxReportDetails+1),
+		})
+		assert.ErrorIs(t, err, models.ErrInvalidData)
+	})
+}
+
+func TestURLShortener_GetURLModeration(t *testing.T) {
+	tests := []struct {
+		name       string
+		moderation string
+		wantErr    error
+		wantStatus models.LinkStatus
+	}{
+		{name: "Без модерации", moderation: models.ModerationNone, wantStatus: models.LinkStatusActive},
+		{name: "Карантин", moderation: models.ModerationQuarantined, wantErr: models.ErrQuarantined, wantStatus: models.LinkStatusQuarantined},
+		{name: "Блокировка", moderation: models.ModerationBanned, wantErr: models.ErrBanned, wantStatus: models.LinkStatusBanned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockURLStorage(ctrl)
+			service := NewServiceURLShortener(mockStorage, "http://short")
+
+			link := models.ShortenedLink{ID: 1, ShortCode: "abc123", OriginalURL: "https://example.com/", Moderation: tt.moderation}
+			mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc123").Return(link, nil).Times(2)
+
+			got, err := service.GetURL(context.Background(), "abc123")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			// Ссылка возвращается и вместе с ошибкой, чтобы показать предупреждение
+			assert.Equal(t, link.OriginalURL, got.OriginalURL)
+
+			_, status, err := service.GetLinkInfo(context.Background(), "abc123")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, status)
+		})
+	}
+}
+
+func TestURLShortener_Moderate(t *testing.T) {
+	link := models.ShortenedLink{ID: 7, ShortCode: "abc123", OriginalURL: "https://example.com/", Moderation: models.ModerationQuarantined}
+
+	tests := []struct {
+		name   string
+		action func(s *URLShortener) (models.ShortenedLink, error)
+		want   string
+	}{
+		{
+			name: "Снятие карантина",
+			action: func(s *URLShortener) (models.ShortenedLink, error) {
+				return s.ClearLink(context.Background(), "abc123")
+			},
+			want: models.ModerationNone,
+		},
+		{
+			name:   "Блокировка",
+			action: func(s *URLShortener) (models.ShortenedLink, error) { return s.BanLink(context.Background(), "abc123") },
+			want:   models.ModerationBanned,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockURLStorage(ctrl)
+			service := NewServiceURLShortener(mockStorage, "http://short")
+
+			mockStorage.EXPECT().
+				WithinTx(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+					return fn(ctx)
+				})
+			mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc123").Return(link, nil)
+			mockStorage.EXPECT().AbuseReportResolve(gomock.Any(), link.ID).Return(nil)
+			mockStorage.EXPECT().ShortenedLinkSetModeration(gomock.Any(), link.ID, tt.want).Return(nil)
+
+			got, err := tt.action(service)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Moderation)
+		})
+	}
+}
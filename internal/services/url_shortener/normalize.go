@@ -0,0 +1,131 @@
+package url_shortener
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/services/host_name"
+)
+
+// URLNormalization правила приведения адресов назначения к единому виду
+type URLNormalization struct {
+	AllowedSchemes   []string // допустимые схемы в нижнем регистре
+	StripDefaultPort bool     // убирать :80 для http и :443 для https
+	StripFragment    bool     // убирать #фрагмент
+}
+
+// DefaultURLNormalization разрешает только http и https и убирает порты по умолчанию
+var DefaultURLNormalization = URLNormalization{
+	AllowedSchemes:   []string{"http", "https"},
+	StripDefaultPort: true,
+}
+
+// WithURLNormalization задает правила проверки и нормализации адресов назначения
+func WithURLNormalization(n URLNormalization) Option {
+	return func(s *URLShortener) {
+		s.normalization = n
+	}
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// NormalizeURL проверяет адрес назначения и приводит его к единому виду, чтобы одинаковые
+// адреса в разной записи не создавали разные ссылки
+func (s *URLShortener) NormalizeURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", invalidURL("empty URL")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", invalidURL("malformed URL")
+	}
+
+	// url.Parse приводит схему к нижнему регистру
+	if u.Scheme == "" {
+		return "", invalidURL("missing scheme")
+	}
+	if !s.schemeAllowed(u.Scheme) {
+		return "", invalidURL(fmt.Sprintf("scheme %q is not allowed", u.Scheme))
+	}
+
+	// Для схем без иерархической части (mailto:, tel:) нормализовать нечего
+	if u.Opaque != "" && defaultPorts[u.Scheme] == "" {
+		return u.String(), nil
+	}
+
+	if u.Host == "" {
+		return "", invalidURL("missing host")
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+
+	port := u.Port()
+	if s.normalization.StripDefaultPort && defaultPorts[u.Scheme] == port {
+		port = ""
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	u.Host = host
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if s.normalization.StripFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+
+	return u.String(), nil
+}
+
+func (s *URLShortener) schemeAllowed(scheme string) bool {
+	for _, allowed := range s.normalization.AllowedSchemes {
+		if scheme == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeHost приводит имя хоста к нижнему регистру и переводит IDN в punycode.
+// IP-адреса возвращаются как есть, а IPv4 в записи, которую понимают браузеры
+// (2130706433, 0x7f.1, 127.1), переводится в обычную
+func normalizeHost(host string) (string, error) {
+	if host == "" {
+		return "", invalidURL("missing host")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return strings.ToLower(host), nil
+	}
+
+	ascii, err := host_name.ToASCII(strings.TrimSuffix(host, "."))
+	if err != nil || ascii == "" {
+		return "", invalidURL(fmt.Sprintf("invalid host %q", host))
+	}
+
+	addr, isIP, err := host_name.ParseIPv4(ascii)
+	if err != nil {
+		return "", invalidURL(fmt.Sprintf("invalid host %q", host))
+	}
+	if isIP {
+		return addr.String(), nil
+	}
+	return ascii, nil
+}
+
+func invalidURL(reason string) error {
+	return fmt.Errorf("%w: invalid URL: %s", models.ErrInvalidData, reason)
+}
@@ -0,0 +1,80 @@
+package url_shortener
+
+import (
+	"testing"
+	"urlshortener/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLShortener_NormalizeURL(t *testing.T) {
+	service := NewServiceURLShortener(nil, "http://short")
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "Хост в нижнем регистре и корневой путь", input: "http://Example.COM", want: "http://example.com/"},
+		{name: "Пробелы и перевод строки по краям", input: "  https://example.com/a?b=1\n", want: "https://example.com/a?b=1"},
+		{name: "Порт по умолчанию", input: "https://example.com:443/path", want: "https://example.com/path"},
+		{name: "Нестандартный порт сохраняется", input: "http://example.com:8080/", want: "http://example.com:8080/"},
+		{name: "IDN переводится в punycode", input: "https://Пример.рф/путь", want: "https://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C"},
+		{name: "IPv6 с портом по умолчанию", input: "http://[2001:DB8::1]:80/", want: "http://[2001:db8::1]/"},
+		{name: "Схема в верхнем регистре", input: "HTTPS://example.com/", want: "https://example.com/"},
+		{name: "Фрагмент сохраняется по умолчанию", input: "https://example.com/#top", want: "https://example.com/#top"},
+		{name: "Путь не меняет регистр", input: "https://example.com/CaseSensitive", want: "https://example.com/CaseSensitive"},
+		{name: "IPv4 одним числом", input: "http://2130706433/", want: "http://127.0.0.1/"},
+		{name: "IPv4 в шестнадцатеричной записи", input: "http://0x7f.1:8080/a", want: "http://127.0.0.1:8080/a"},
+		{name: "Сокращенный IPv4", input: "http://127.1/", want: "http://127.0.0.1/"},
+		{name: "Имя, оканчивающееся числом", input: "http://example.123/", wantErr: true},
+		{name: "Пустая строка", input: "   ", wantErr: true},
+		{name: "Схема javascript", input: "javascript:alert(1)", wantErr: true},
+		{name: "Слово без схемы", input: "example", wantErr: true},
+		{name: "Адрес без схемы", input: "example.com/path", wantErr: true},
+		{name: "http без хоста", input: "http:///path", wantErr: true},
+		{name: "http в непрозрачной форме", input: "http:example.com", wantErr: true},
+		{name: "Некорректный хост", input: "http://exa mple.com/", wantErr: true},
+		{name: "Управляющий символ", input: "http://example.com/\x7f", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.NormalizeURL(tt.input)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, models.ErrInvalidData)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			// Повторная нормализация ничего не меняет
+			again, err := service.NormalizeURL(got)
+			require.NoError(t, err)
+			assert.Equal(t, got, again)
+		})
+	}
+}
+
+func TestURLShortener_NormalizeURLConfigured(t *testing.T) {
+	service := NewServiceURLShortener(nil, "http://short", WithURLNormalization(URLNormalization{
+		AllowedSchemes: []string{"https", "mailto"},
+		StripFragment:  true,
+	}))
+
+	got, err := service.NormalizeURL("https://Example.com:443/page#section")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com:443/page", got)
+
+	got, err = service.NormalizeURL("mailto:team@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "mailto:team@example.com", got)
+
+	_, err = service.NormalizeURL("http://example.com/")
+	assert.ErrorIs(t, err, models.ErrInvalidData)
+}
@@ -0,0 +1,41 @@
+package url_shortener
+
+import "urlshortener/internal/domain/models"
+
+// DestinationPolicy решает, можно ли сокращать адрес назначения.
+// Отказ возвращается как *models.PolicyViolation
+type DestinationPolicy interface {
+	Check(rawURL string) error
+}
+
+// WithDestinationPolicy включает проверку адресов назначения политикой
+func WithDestinationPolicy(policy DestinationPolicy) Option {
+	return func(s *URLShortener) {
+		s.policy = policy
+	}
+}
+
+// checkDestinations проверяет все адреса, на которые может перенаправить ссылка:
+// основной адрес, цели правил и A/B-вариантов. Пустой originalURL не проверяется
+func (s *URLShortener) checkDestinations(originalURL string, rules []models.RedirectRule, variants []models.LinkVariant) error {
+	if s.policy == nil {
+		return nil
+	}
+
+	if originalURL != "" {
+		if err := s.policy.Check(originalURL); err != nil {
+			return err
+		}
+	}
+	for _, rule := range rules {
+		if err := s.policy.Check(rule.TargetURL); err != nil {
+			return err
+		}
+	}
+	for _, variant := range variants {
+		if err := s.policy.Check(variant.TargetURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package url_shortener
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// hostPolicy запрещает перечисленные хосты
+type hostPolicy map[string]bool
+
+func (p hostPolicy) Check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if p[u.Hostname()] {
+		return &models.PolicyViolation{Reason: models.PolicyReasonBlocklisted, Host: u.Hostname()}
+	}
+	return nil
+}
+
+func TestURLShortener_DestinationPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	service := NewServiceURLShortener(mockStorage, "http://short",
+		WithDestinationPolicy(hostPolicy{"evil.example": true}))
+
+	tests := []struct {
+		name string
+		link models.ShortenedLink
+	}{
+		{
+			name: "Основной адрес",
+			link: models.ShortenedLink{OriginalURL: "https://EVIL.example/login", UserID: 1},
+		},
+		{
+			name: "Цель правила",
+			link: models.ShortenedLink{
+				OriginalURL: "https://example.com/",
+				UserID:      1,
+				RedirectRules: []models.RedirectRule{
+					{Type: models.RedirectRulePlatform, Value: models.PlatformIOS, TargetURL: "https://evil.example/ios"},
+				},
+			},
+		},
+		{
+			name: "Цель варианта",
+			link: models.ShortenedLink{
+				OriginalURL: "https://example.com/",
+				UserID:      1,
+				Variants:    []models.LinkVariant{{TargetURL: "https://evil.example/b", Weight: 1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Отказ до обращения к хранилищу
+			_, err := service.SetURL(context.Background(), tt.link)
+
+			var violation *models.PolicyViolation
+			require.True(t, errors.As(err, &violation), "want policy violation, got %v", err)
+			assert.Equal(t, "evil.example", violation.Host)
+
+			_, err = service.BatchCreate(context.Background(), []models.ShortenedLink{tt.link})
+			assert.ErrorIs(t, err, models.ErrBlocked)
+		})
+	}
+
+	t.Run("Новые цели при изменении ссылки", func(t *testing.T) {
+		rules := []models.RedirectRule{
+			{Type: models.RedirectRuleLanguage, Value: "de", TargetURL: "https://evil.example/de"},
+		}
+		_, err := service.UpdateLink(context.Background(), 1, "abc123", models.ShortenedLinkUpdate{RedirectRules: &rules})
+		assert.ErrorIs(t, err, models.ErrBlocked)
+	})
+}
@@ -0,0 +1,52 @@
+package url_shortener
+
+import "context"
+
+// QuotaChecker проверяет квоты пользователя при создании ссылок.
+// Отказ возвращается как *models.QuotaExceeded
+type QuotaChecker interface {
+	// Check быстрая проверка без блокировки, до выдачи кодов
+	Check(ctx context.Context, userID int64, links int) error
+	// Reserve окончательная проверка внутри транзакции создания
+	Reserve(ctx context.Context, userID int64, links int) error
+	CheckBatchSize(size int) error
+}
+
+// WithQuota включает проверку квот при создании ссылок
+func WithQuota(quota QuotaChecker) Option {
+	return func(s *URLShortener) {
+		s.quota = quota
+	}
+}
+
+// checkQuota отказывает пользователю с исчерпанной квотой до выдачи кодов, чтобы не тратить их впустую.
+// Без квот проверять нечего
+func (s *URLShortener) checkQuota(ctx context.Context, userID int64, links int) error {
+	if s.quota == nil {
+		return nil
+	}
+	return s.quota.Check(ctx, userID, links)
+}
+
+// withQuota выполняет create в одной транзакции с проверкой квоты,
+// чтобы параллельные запросы пользователя не превысили ее. Без квот create вызывается как есть
+func (s *URLShortener) withQuota(ctx context.Context, userID int64, links int, create func(ctx context.Context) error) error {
+	if s.quota == nil {
+		return create(ctx)
+	}
+
+	return s.storage.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.quota.Reserve(ctx, userID, links); err != nil {
+			return err
+		}
+		return create(ctx)
+	})
+}
+
+// CheckBatchSize проверяет размер пакетного запроса, без квот любой размер допустим
+func (s *URLShortener) CheckBatchSize(size int) error {
+	if s.quota == nil {
+		return nil
+	}
+	return s.quota.CheckBatchSize(size)
+}
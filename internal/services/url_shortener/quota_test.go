@@ -0,0 +1,153 @@
+package url_shortener
+
+import (
+	"context"
+	"testing"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type stubQuota struct {
+	checkErr   error
+	reserveErr error
+	batchErr   error
+	checked    int
+	reserved   int
+}
+
+func (q *stubQuota) Check(ctx context.Context, userID int64, links int) error {
+	q.checked += links
+	return q.checkErr
+}
+
+func (q *stubQuota) Reserve(ctx context.Context, userID int64, links int) error {
+	q.reserved += links
+	return q.reserveErr
+}
+
+func (q *stubQuota) CheckBatchSize(size int) error {
+	return q.batchErr
+}
+
+func TestURLShortener_SetURLQuota(t *testing.T) {
+	exceeded := &models.QuotaExceeded{Quota: models.QuotaActiveLinks, Limit: 2, Used: 2, Requested: 1}
+
+	withinTx := func(m *mocks.MockURLStorage) {
+		m.EXPECT().
+			WithinTx(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+				return fn(ctx)
+			})
+	}
+
+	tests := []struct {
+		name         string
+		quota        *stubQuota
+		setupMocks   func(m *mocks.MockURLStorage)
+		wantReserved int
+		wantCodes    int
+		wantErr      error
+	}{
+		{
+			name:  "Квота позволяет создать ссылку",
+			quota: &stubQuota{},
+			setupMocks: func(m *mocks.MockURLStorage) {
+				withinTx(m)
+				m.EXPECT().ShortenedLinkCreate(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, link models.ShortenedLink) (models.ShortenedLink, error) {
+						return link, nil
+					})
+			},
+			wantReserved: 1,
+			wantCodes:    1,
+		},
+		{
+			name:       "Квота исчерпана к моменту создания - ссылка не создается",
+			quota:      &stubQuota{reserveErr: exceeded},
+			setupMocks: withinTx,
+			// Код уже выдан: параллельный запрос занял квоту между проверками
+			wantReserved: 1,
+			wantCodes:    1,
+			wantErr:      models.ErrQuota,
+		},
+		{
+			name:    "Квота исчерпана заранее - коды не выдаются",
+			quota:   &stubQuota{checkErr: exceeded},
+			wantErr: models.ErrQuota,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockURLStorage(ctrl)
+			codes := &stubCodes{codes: []string{"pool0001"}}
+			service := NewServiceURLShortener(mockStorage, "http://short", WithQuota(tt.quota), WithCodePool(codes))
+
+			if tt.setupMocks != nil {
+				tt.setupMocks(mockStorage)
+			}
+
+			_, err := service.SetURL(context.Background(), models.ShortenedLink{OriginalURL: "https://example.com/", UserID: 1})
+			assert.Equal(t, 1, tt.quota.checked)
+			assert.Equal(t, tt.wantReserved, tt.quota.reserved)
+			assert.Equal(t, tt.wantCodes, codes.calls)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			var got *models.QuotaExceeded
+			require.ErrorAs(t, err, &got)
+			assert.Equal(t, exceeded, got)
+		})
+	}
+}
+
+func TestURLShortener_BatchCreateQuota(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	quota := &stubQuota{batchErr: &models.QuotaExceeded{Quota: models.QuotaBatchSize, Limit: 1, Requested: 2}}
+	service := NewServiceURLShortener(mockStorage, "http://short", WithQuota(quota))
+
+	_, err := service.BatchCreate(context.Background(), []models.ShortenedLink{
+		{OriginalURL: "https://example.com/a", UserID: 1},
+		{OriginalURL: "https://example.com/b", UserID: 1},
+	})
+	assert.ErrorIs(t, err, models.ErrQuota)
+	assert.Zero(t, quota.checked)
+	assert.Zero(t, quota.reserved)
+}
+
+func TestURLShortener_BatchCreateQuotaBeforeCodes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	quota := &stubQuota{checkErr: &models.QuotaExceeded{Quota: models.QuotaActiveLinks, Limit: 2, Used: 1, Requested: 2}}
+	codes := &stubCodes{codes: []string{"pool0001", "pool0002"}}
+	service := NewServiceURLShortener(mockStorage, "http://short", WithQuota(quota), WithCodePool(codes))
+
+	// Уже существующая ссылка не учитывается в квоте
+	mockStorage.EXPECT().ShortenedLinkBatchExists(gomock.Any(), gomock.Any()).
+		Return([]models.ShortenedLink{{OriginalURL: "https://example.com/a", ShortCode: "existing", UserID: 1}}, nil)
+
+	_, err := service.BatchCreate(context.Background(), []models.ShortenedLink{
+		{OriginalURL: "https://example.com/a", UserID: 1},
+		{OriginalURL: "https://example.com/b", UserID: 1},
+		{OriginalURL: "https://example.com/c", UserID: 1},
+	})
+	assert.ErrorIs(t, err, models.ErrQuota)
+	assert.Equal(t, 2, quota.checked)
+	assert.Zero(t, quota.reserved)
+	assert.Zero(t, codes.calls)
+}
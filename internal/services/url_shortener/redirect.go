@@ -0,0 +1,128 @@
+package url_shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"urlshortener/internal/domain/models"
+)
+
+// BuildRedirectURL собирает адрес перенаправления из ссылки и входящего запроса.
+// Назначение берется из первого подходящего правила перенаправления, затем из
+// A/B-варианта, выбранного по весу, иначе - OriginalURL.
+// Хвост пути дописывается только при PassPath, параметры запроса - только при PassQuery,
+// параметры привязанного UTM-шаблона - всегда. Параметры, уже заданные в URL назначения,
+// не перезаписываются.
+func (s *URLShortener) BuildRedirectURL(ctx context.Context, link models.ShortenedLink, req models.RedirectRequest) (models.RedirectTarget, error) {
+	if req.ExtraPath != "" && !link.PassPath {
+		return models.RedirectTarget{}, fmt.Errorf("%w: URL not found", models.ErrUnfound)
+	}
+
+	target := models.RedirectTarget{URL: link.OriginalURL}
+	if ruleURL, ok := matchRedirectRule(link.RedirectRules, req); ok {
+		target.URL = ruleURL
+	} else if variant, ok := s.chooseVariant(link, req.Variant); ok {
+		target.URL = variant.TargetURL
+		target.Variant = variant.Name
+	}
+
+	utm, err := s.linkUTMValues(ctx, link)
+	if err != nil {
+		return models.RedirectTarget{}, err
+	}
+
+	if (req.ExtraPath == "" || !link.PassPath) && (len(req.Query) == 0 || !link.PassQuery) && len(utm) == 0 {
+		return target, nil
+	}
+
+	dest, err := url.Parse(target.URL)
+	if err != nil {
+		return models.RedirectTarget{}, fmt.Errorf("failed to parse destination URL: %w", err)
+	}
+
+	if link.PassPath && req.ExtraPath != "" {
+		dest = appendPath(dest, req.ExtraPath)
+	}
+
+	// Шаблон считается частью назначения, поэтому применяется раньше входящих параметров
+	if len(utm) > 0 {
+		dest.RawQuery = mergeQuery(dest.RawQuery, utm)
+	}
+
+	if link.PassQuery && len(req.Query) > 0 {
+		dest.RawQuery = mergeQuery(dest.RawQuery, req.Query)
+	}
+
+	target.URL = dest.String()
+	return target, nil
+}
+
+// linkUTMValues загружает параметры UTM-шаблона, привязанного к ссылке. С кэшем перенаправлений
+// шаблон берется из памяти вместе со ссылкой. Удаленный к этому моменту шаблон просто не применяется
+func (s *URLShortener) linkUTMValues(ctx context.Context, link models.ShortenedLink) (url.Values, error) {
+	if link.UTMTemplateID == 0 {
+		return nil, nil
+	}
+
+	tpl, err := s.storage.UTMTemplateGetByID(ctx, link.UTMTemplateID)
+	if err != nil {
+		if errors.Is(err, models.ErrUnfound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get UTM template: %w", err)
+	}
+
+	return utmValues(tpl), nil
+}
+
+// appendPath дописывает сегменты пути к URL назначения. Сегменты "." и ".."
+// отбрасываются, чтобы хвост не мог выйти за пределы пути назначения.
+func appendPath(dest *url.URL, extraPath string) *url.URL {
+	var segments []string
+	for _, segment := range strings.Split(extraPath, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	if len(segments) == 0 {
+		return dest
+	}
+
+	result := dest.JoinPath(segments...)
+	if strings.HasSuffix(extraPath, "/") && !strings.HasSuffix(result.Path, "/") {
+		result.Path += "/"
+		if result.RawPath != "" {
+			result.RawPath += "/"
+		}
+	}
+	return result
+}
+
+// mergeQuery добавляет к исходной строке запроса параметры, которых в ней еще нет.
+// Исходная строка не перекодируется, чтобы не менять URL назначения.
+func mergeQuery(rawQuery string, extra url.Values) string {
+	existing, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// Нестандартную строку запроса не трогаем, чтобы ничего не сломать
+		existing = url.Values{}
+	}
+
+	added := url.Values{}
+	for key, values := range extra {
+		if _, ok := existing[key]; ok {
+			continue
+		}
+		added[key] = values
+	}
+
+	if len(added) == 0 {
+		return rawQuery
+	}
+	if rawQuery == "" {
+		return added.Encode()
+	}
+	return rawQuery + "&" + added.Encode()
+}
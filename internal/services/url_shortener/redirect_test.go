@@ -0,0 +1,182 @@
+package url_shortener
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestURLShortener_BuildRedirectURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	service := NewServiceURLShortener(mockStorage, "http://short")
+	// Точка выбора варианта: всегда 1, то есть второй по счету вес
+	service.randIntN = func(n int) int { return 1 }
+
+	tests := []struct {
+		name        string
+		link        models.ShortenedLink
+		extraPath   string
+		query       url.Values
+		header      http.Header
+		mockSetup   func()
+		want        string
+		wantVariant string
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name:  "Без передачи параметров URL не меняется",
+			link:  models.ShortenedLink{OriginalURL: "https://example.com/page?a=1"},
+			query: url.Values{"utm_source": {"x"}},
+			want:  "https://example.com/page?a=1",
+		},
+		{
+			name:  "Параметры запроса дописываются, существующие не перезаписываются",
+			link:  models.ShortenedLink{OriginalURL: "https://example.com/page?a=1#top", PassQuery: true},
+			query: url.Values{"a": {"2"}, "utm_source": {"x"}},
+			want:  "https://example.com/page?a=1&utm_source=x#top",
+		},
+		{
+			name:      "Хвост пути дописывается к пути назначения",
+			link:      models.ShortenedLink{OriginalURL: "https://example.com/docs", PassPath: true},
+			extraPath: "guide/intro",
+			want:      "https://example.com/docs/guide/intro",
+		},
+		{
+			name:      "Переход выше пути назначения отбрасывается",
+			link:      models.ShortenedLink{OriginalURL: "https://example.com/docs/", PassPath: true},
+			extraPath: "../admin/",
+			want:      "https://example.com/docs/admin/",
+		},
+		{
+			name:      "Хвост пути и параметры вместе",
+			link:      models.ShortenedLink{OriginalURL: "https://example.com", PassPath: true, PassQuery: true},
+			extraPath: "a b",
+			query:     url.Values{"q": {"1"}},
+			want:      "https://example.com/a%20b?q=1",
+		},
+		{
+			name: "Привязанный UTM-шаблон не перезаписывает параметры назначения",
+			link: models.ShortenedLink{OriginalURL: "https://example.com/?utm_source=site", UTMTemplateID: 7},
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					UTMTemplateGetByID(gomock.Any(), int64(7)).
+					Return(models.UTMTemplate{ID: 7, Source: "mail", Medium: "email"}, nil)
+			},
+			want: "https://example.com/?utm_source=site&utm_medium=email",
+		},
+		{
+			name:  "UTM-шаблон применяется раньше входящих параметров",
+			link:  models.ShortenedLink{OriginalURL: "https://example.com/", PassQuery: true, UTMTemplateID: 7},
+			query: url.Values{"utm_medium": {"banner"}, "q": {"1"}},
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					UTMTemplateGetByID(gomock.Any(), int64(7)).
+					Return(models.UTMTemplate{ID: 7, Medium: "email"}, nil)
+			},
+			want: "https://example.com/?utm_medium=email&q=1",
+		},
+		{
+			name: "Удаленный UTM-шаблон игнорируется",
+			link: models.ShortenedLink{OriginalURL: "https://example.com/", UTMTemplateID: 7},
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					UTMTemplateGetByID(gomock.Any(), int64(7)).
+					Return(models.UTMTemplate{}, models.ErrUnfound)
+			},
+			want: "https://example.com/",
+		},
+		{
+			name: "Назначение из подходящего правила, хвост пути дописывается к нему",
+			link: models.ShortenedLink{
+				OriginalURL: "https://example.com",
+				PassPath:    true,
+				RedirectRules: []models.RedirectRule{
+					{Type: models.RedirectRulePlatform, Value: models.PlatformAndroid, TargetURL: "https://play.example.com"},
+					{Type: models.RedirectRulePlatform, Value: models.PlatformIOS, TargetURL: "https://apps.example.com/app"},
+				},
+			},
+			extraPath: "promo",
+			header:    http.Header{"User-Agent": {"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148"}},
+			want:      "https://apps.example.com/app/promo",
+		},
+		{
+			name: "Без подходящего правила используется OriginalURL",
+			link: models.ShortenedLink{
+				OriginalURL: "https://example.com",
+				RedirectRules: []models.RedirectRule{
+					{Type: models.RedirectRuleHeader, Header: "X-Beta", TargetURL: "https://beta.example.com"},
+				},
+			},
+			header: http.Header{"User-Agent": {"curl/8.0"}},
+			want:   "https://example.com",
+		},
+		{
+			name: "Вариант выбирается по весу",
+			link: models.ShortenedLink{
+				OriginalURL: "https://example.com",
+				PassQuery:   true,
+				Variants: []models.LinkVariant{
+					{Name: "a", TargetURL: "https://a.example.com", Weight: 1},
+					{Name: "b", TargetURL: "https://b.example.com", Weight: 1},
+				},
+			},
+			query:       url.Values{"q": {"1"}},
+			want:        "https://b.example.com?q=1",
+			wantVariant: "b",
+		},
+		{
+			name: "Правило важнее вариантов",
+			link: models.ShortenedLink{
+				OriginalURL: "https://example.com",
+				RedirectRules: []models.RedirectRule{
+					{Type: models.RedirectRuleHeader, Header: "X-Beta", TargetURL: "https://beta.example.com"},
+				},
+				Variants: []models.LinkVariant{{Name: "a", TargetURL: "https://a.example.com", Weight: 1}},
+			},
+			header: http.Header{"X-Beta": {"1"}},
+			want:   "https://beta.example.com",
+		},
+		{
+			name:        "Хвост пути для ссылки без PassPath",
+			link:        models.ShortenedLink{OriginalURL: "https://example.com"},
+			extraPath:   "extra",
+			wantErr:     true,
+			expectedErr: models.ErrUnfound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mockSetup != nil {
+				tt.mockSetup()
+			}
+
+			got, err := service.BuildRedirectURL(context.Background(), tt.link, models.RedirectRequest{
+				ExtraPath: tt.extraPath,
+				Query:     tt.query,
+				Header:    tt.header,
+			})
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.URL)
+			assert.Equal(t, tt.wantVariant, got.Variant)
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package url_shortener
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"urlshortener/internal/domain/models"
+)
+
+const maxRedirectRules = 32
+
+var knownPlatforms = map[string]bool{
+	models.PlatformIOS:     true,
+	models.PlatformAndroid: true,
+	models.PlatformMobile:  true,
+	models.PlatformDesktop: true,
+	models.PlatformWindows: true,
+	models.PlatformMacOS:   true,
+	models.PlatformLinux:   true,
+}
+
+// normalizeRedirectRules проверяет правила и приводит значения к каноническому виду
+func (s *URLShortener) normalizeRedirectRules(rules []models.RedirectRule) ([]models.RedirectRule, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	if len(rules) > maxRedirectRules {
+		return nil, fmt.Errorf("%w: too many redirect rules, max %d", models.ErrInvalidData, maxRedirectRules)
+	}
+
+	result := make([]models.RedirectRule, 0, len(rules))
+	for i, rule := range rules {
+		rule.Type = strings.ToLower(strings.TrimSpace(rule.Type))
+		rule.Value = strings.TrimSpace(rule.Value)
+
+		switch rule.Type {
+		case models.RedirectRulePlatform:
+			rule.Value = strings.ToLower(rule.Value)
+			if !knownPlatforms[rule.Value] {
+				return nil, fmt.Errorf("%w: rule %d: unknown platform %q", models.ErrInvalidData, i, rule.Value)
+			}
+			rule.Header = ""
+		case models.RedirectRuleLanguage:
+			if rule.Value == "" {
+				return nil, fmt.Errorf("%w: rule %d: language is required", models.ErrInvalidData, i)
+			}
+			rule.Value = strings.ToLower(rule.Value)
+			rule.Header = ""
+		case models.RedirectRuleHeader:
+			rule.Header = http.CanonicalHeaderKey(strings.TrimSpace(rule.Header))
+			if rule.Header == "" {
+				return nil, fmt.Errorf("%w: rule %d: header name is required", models.ErrInvalidData, i)
+			}
+		case models.RedirectRuleCountry:
+			if !s.geoTargeting {
+				return nil, fmt.Errorf("%w: rule %d: geo targeting is disabled", models.ErrInvalidData, i)
+			}
+			countries, err := normalizeCountries(rule.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: rule %d: %v", models.ErrInvalidData, i, err)
+			}
+			rule.Value = countries
+			rule.Header = ""
+		default:
+			return nil, fmt.Errorf("%w: rule %d: unknown rule type %q", models.ErrInvalidData, i, rule.Type)
+		}
+
+		target, err := url.Parse(rule.TargetURL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return nil, fmt.Errorf("%w: rule %d: target must be an absolute http(s) URL", models.ErrInvalidData, i)
+		}
+
+		result = append(result, rule)
+	}
+
+	return result, nil
+}
+
+// matchRedirectRule возвращает назначение первого подходящего правила
+func matchRedirectRule(rules []models.RedirectRule, req models.RedirectRequest) (string, bool) {
+	header := req.Header
+	if len(rules) == 0 {
+		return "", false
+	}
+
+	var (
+		platforms map[string]bool
+		language  string
+	)
+
+	for _, rule := range rules {
+		switch rule.Type {
+		case models.RedirectRulePlatform:
+			if platforms == nil {
+				platforms = detectPlatforms(header.Get("User-Agent"))
+			}
+			if platforms[rule.Value] {
+				return rule.TargetURL, true
+			}
+		case models.RedirectRuleLanguage:
+			if language == "" {
+				language = preferredLanguage(header.Get("Accept-Language"))
+			}
+			// "de" подходит для "de" и "de-at", "pt-br" - только для "pt-br"
+			if language == rule.Value || strings.HasPrefix(language, rule.Value+"-") {
+				return rule.TargetURL, true
+			}
+		case models.RedirectRuleCountry:
+			if req.Country == "" {
+				continue
+			}
+			for _, country := range strings.Split(rule.Value, ",") {
+				if country == req.Country {
+					return rule.TargetURL, true
+				}
+			}
+		case models.RedirectRuleHeader:
+			values, ok := header[rule.Header]
+			if !ok {
+				continue
+			}
+			if rule.Value == "" {
+				return rule.TargetURL, true
+			}
+			for _, value := range values {
+				if strings.EqualFold(strings.TrimSpace(value), rule.Value) {
+					return rule.TargetURL, true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+// normalizeCountries приводит список ISO-кодов стран через запятую к виду "DE,AT"
+func normalizeCountries(value string) (string, error) {
+	var countries []string
+	seen := make(map[string]bool)
+	for _, country := range strings.Split(value, ",") {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if country == "" {
+			continue
+		}
+		if len(country) != 2 || country[0] < 'A' || country[0] > 'Z' || country[1] < 'A' || country[1] > 'Z' {
+			return "", fmt.Errorf("invalid country code %q", country)
+		}
+		if !seen[country] {
+			seen[country] = true
+			countries = append(countries, country)
+		}
+	}
+	if len(countries) == 0 {
+		return "", fmt.Errorf("country is required")
+	}
+	return strings.Join(countries, ","), nil
+}
+
+// detectPlatforms определяет по User-Agent операционную систему и класс устройства
+func detectPlatforms(userAgent string) map[string]bool {
+	ua := strings.ToLower(userAgent)
+	platforms := make(map[string]bool, 2)
+
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		platforms[models.PlatformIOS] = true
+	case strings.Contains(ua, "android"):
+		platforms[models.PlatformAndroid] = true
+	case strings.Contains(ua, "windows"):
+		platforms[models.PlatformWindows] = true
+	case strings.Contains(ua, "macintosh"), strings.Contains(ua, "mac os x"):
+		platforms[models.PlatformMacOS] = true
+	case strings.Contains(ua, "linux"), strings.Contains(ua, "x11"):
+		platforms[models.PlatformLinux] = true
+	}
+
+	if platforms[models.PlatformIOS] || platforms[models.PlatformAndroid] || strings.Contains(ua, "mobile") {
+		platforms[models.PlatformMobile] = true
+	} else if len(platforms) > 0 {
+		platforms[models.PlatformDesktop] = true
+	}
+
+	return platforms
+}
+
+// preferredLanguage возвращает язык с наибольшим весом из Accept-Language в нижнем регистре
+func preferredLanguage(acceptLanguage string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var langs []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		langs = append(langs, weighted{tag: tag, q: q})
+	}
+
+	if len(langs) == 0 {
+		return ""
+	}
+
+	// При равном весе сохраняется порядок из заголовка
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+	return langs[0].tag
+}
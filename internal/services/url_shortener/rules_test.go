@@ -0,0 +1,186 @@
+package url_shortener
+
+import (
+	"net/http"
+	"testing"
+	"urlshortener/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLShortener_NormalizeRedirectRules(t *testing.T) {
+	service := NewServiceURLShortener(nil, "http://short", WithGeoTargeting(true))
+
+	tests := []struct {
+		name        string
+		service     *URLShortener
+		input       []models.RedirectRule
+		want        []models.RedirectRule
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name:  "Пустой список",
+			input: []models.RedirectRule{},
+			want:  nil,
+		},
+		{
+			name: "Значения приводятся к каноническому виду",
+			input: []models.RedirectRule{
+				{Type: " Platform ", Value: "iOS", TargetURL: "https://apps.example.com"},
+				{Type: "language", Value: "pt-BR", TargetURL: "https://example.com/br"},
+				{Type: "header", Header: "x-beta", Value: "On", TargetURL: "https://beta.example.com"},
+			},
+			want: []models.RedirectRule{
+				{Type: models.RedirectRulePlatform, Value: models.PlatformIOS, TargetURL: "https://apps.example.com"},
+				{Type: models.RedirectRuleLanguage, Value: "pt-br", TargetURL: "https://example.com/br"},
+				{Type: models.RedirectRuleHeader, Header: "X-Beta", Value: "On", TargetURL: "https://beta.example.com"},
+			},
+		},
+		{
+			name:        "Неизвестная платформа",
+			input:       []models.RedirectRule{{Type: "platform", Value: "symbian", TargetURL: "https://example.com"}},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:        "Правило по заголовку без имени заголовка",
+			input:       []models.RedirectRule{{Type: "header", Value: "1", TargetURL: "https://example.com"}},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:        "Относительный адрес назначения",
+			input:       []models.RedirectRule{{Type: "language", Value: "de", TargetURL: "/de"}},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:  "Список стран",
+			input: []models.RedirectRule{{Type: "country", Value: "de, at,DE", TargetURL: "https://example.com/dach"}},
+			want:  []models.RedirectRule{{Type: models.RedirectRuleCountry, Value: "DE,AT", TargetURL: "https://example.com/dach"}},
+		},
+		{
+			name:        "Некорректный код страны",
+			input:       []models.RedirectRule{{Type: "country", Value: "DEU", TargetURL: "https://example.com"}},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:        "Правила по стране без базы GeoIP",
+			service:     NewServiceURLShortener(nil, "http://short"),
+			input:       []models.RedirectRule{{Type: "country", Value: "DE", TargetURL: "https://example.com"}},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:        "Неизвестный тип правила",
+			input:       []models.RedirectRule{{Type: "cookie", TargetURL: "https://example.com"}},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service
+			if tt.service != nil {
+				svc = tt.service
+			}
+
+			got, err := svc.normalizeRedirectRules(tt.input)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMatchRedirectRule(t *testing.T) {
+	rules := []models.RedirectRule{
+		{Type: models.RedirectRuleCountry, Value: "DE,AT", TargetURL: "https://example.com/dach"},
+		{Type: models.RedirectRuleHeader, Header: "X-Beta", TargetURL: "https://beta.example.com"},
+		{Type: models.RedirectRulePlatform, Value: models.PlatformIOS, TargetURL: "https://apps.example.com"},
+		{Type: models.RedirectRulePlatform, Value: models.PlatformAndroid, TargetURL: "https://play.example.com"},
+		{Type: models.RedirectRuleLanguage, Value: "de", TargetURL: "https://example.com/de"},
+		{Type: models.RedirectRulePlatform, Value: models.PlatformDesktop, TargetURL: "https://example.com/web"},
+	}
+
+	tests := []struct {
+		name      string
+		header    http.Header
+		country   string
+		want      string
+		wantMatch bool
+	}{
+		{
+			name:      "iPhone",
+			header:    http.Header{"User-Agent": {"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"}},
+			want:      "https://apps.example.com",
+			wantMatch: true,
+		},
+		{
+			name:      "Android",
+			header:    http.Header{"User-Agent": {"Mozilla/5.0 (Linux; Android 14; Pixel 8) Mobile Safari/537.36"}},
+			want:      "https://play.example.com",
+			wantMatch: true,
+		},
+		{
+			name: "Правила проверяются по порядку",
+			header: http.Header{
+				"User-Agent": {"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"},
+				"X-Beta":     {"1"},
+			},
+			want:      "https://beta.example.com",
+			wantMatch: true,
+		},
+		{
+			name: "Язык с регионом подходит под правило по основному языку",
+			header: http.Header{
+				"User-Agent":      {"Mozilla/5.0 (Windows NT 10.0; Win64; x64)"},
+				"Accept-Language": {"en;q=0.5, de-AT"},
+			},
+			want:      "https://example.com/de",
+			wantMatch: true,
+		},
+		{
+			name:      "Десктоп",
+			header:    http.Header{"User-Agent": {"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0)"}},
+			want:      "https://example.com/web",
+			wantMatch: true,
+		},
+		{
+			name:      "Страна клиента",
+			header:    http.Header{"User-Agent": {"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"}},
+			country:   "AT",
+			want:      "https://example.com/dach",
+			wantMatch: true,
+		},
+		{
+			name:   "Ничего не подошло",
+			header: http.Header{"User-Agent": {"curl/8.0"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchRedirectRule(rules, models.RedirectRequest{Header: tt.header, Country: tt.country})
+			assert.Equal(t, tt.wantMatch, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	assert.Equal(t, "fr-ch", preferredLanguage("fr-CH, fr;q=0.9, en;q=0.8"))
+	assert.Equal(t, "en", preferredLanguage("de;q=0.7, en"))
+	assert.Equal(t, "", preferredLanguage("*"))
+	assert.Equal(t, "", preferredLanguage("de;q=0"))
+}
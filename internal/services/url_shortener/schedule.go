@@ -0,0 +1,28 @@
+package url_shortener
+
+import (
+	"fmt"
+	"time"
+	"urlshortener/internal/domain/models"
+)
+
+// checkActiveWindow проверяет, что ссылка сейчас внутри окна активности.
+// Граница ActiveFrom включается в окно, ActiveUntil - нет
+func (s *URLShortener) checkActiveWindow(link models.ShortenedLink) error {
+	now := s.now()
+	if !link.ActiveFrom.IsZero() && now.Before(link.ActiveFrom) {
+		return fmt.Errorf("%w: active from %s", models.ErrNotYetActive, link.ActiveFrom.UTC().Format(time.RFC3339))
+	}
+	if !link.ActiveUntil.IsZero() && !now.Before(link.ActiveUntil) {
+		return fmt.Errorf("%w: ended at %s", models.ErrExpired, link.ActiveUntil.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// validateActiveWindow проверяет, что окно активности не пустое
+func validateActiveWindow(from, until time.Time) error {
+	if !from.IsZero() && !until.IsZero() && !until.After(from) {
+		return fmt.Errorf("%w: active_until must be after active_from", models.ErrInvalidData)
+	}
+	return nil
+}
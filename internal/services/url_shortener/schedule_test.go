@@ -0,0 +1,89 @@
+package url_shortener
+
+import (
+	"context"
+	"testing"
+	"time"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestURLShortener_GetURLActiveWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	service := NewServiceURLShortener(mockStorage, "http://short")
+
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	tests := []struct {
+		name        string
+		link        models.ShortenedLink
+		wantStatus  models.LinkStatus
+		expectedErr error
+	}{
+		{
+			name:       "Окно не задано",
+			link:       models.ShortenedLink{ShortCode: "abc123"},
+			wantStatus: models.LinkStatusActive,
+		},
+		{
+			name:       "Внутри окна",
+			link:       models.ShortenedLink{ShortCode: "abc123", ActiveFrom: now.Add(-time.Hour), ActiveUntil: now.Add(time.Hour)},
+			wantStatus: models.LinkStatusActive,
+		},
+		{
+			name:       "Начало окна включается",
+			link:       models.ShortenedLink{ShortCode: "abc123", ActiveFrom: now},
+			wantStatus: models.LinkStatusActive,
+		},
+		{
+			name:        "Окно еще не началось",
+			link:        models.ShortenedLink{ShortCode: "abc123", ActiveFrom: now.Add(time.Minute)},
+			wantStatus:  models.LinkStatusScheduled,
+			expectedErr: models.ErrNotYetActive,
+		},
+		{
+			name:        "Окно закончилось",
+			link:        models.ShortenedLink{ShortCode: "abc123", ActiveUntil: now},
+			wantStatus:  models.LinkStatusExpired,
+			expectedErr: models.ErrExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc123").Return(tt.link, nil).Times(2)
+
+			got, err := service.GetURL(context.Background(), "abc123")
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			// Ссылка возвращается и вне окна, чтобы обработчик мог показать даты
+			assert.Equal(t, tt.link, got)
+
+			_, status, err := service.GetLinkInfo(context.Background(), "abc123")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, status)
+		})
+	}
+}
+
+func TestValidateActiveWindow(t *testing.T) {
+	from := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, validateActiveWindow(time.Time{}, time.Time{}))
+	assert.NoError(t, validateActiveWindow(from, time.Time{}))
+	assert.NoError(t, validateActiveWindow(time.Time{}, from))
+	assert.NoError(t, validateActiveWindow(from, from.Add(time.Second)))
+	assert.ErrorIs(t, validateActiveWindow(from, from), models.ErrInvalidData)
+	assert.ErrorIs(t, validateActiveWindow(from, from.Add(-time.Hour)), models.ErrInvalidData)
+}
@@ -2,13 +2,14 @@ package url_shortener
 
 import (
 	"context"
-	"crypto/rand"
 	"errors"
 	"fmt"
-	"math/big"
+	mathrand "math/rand/v2"
+	"net/http"
 	"sync"
 	"time"
 	"urlshortener/internal/domain/models"
+	"urlshortener/internal/services/short_code"
 )
 
 /*
@@ -24,6 +25,24 @@ type URLStorage interface {
 	ShortenedLinkBatchCreate(ctx context.Context, urls []models.ShortenedLink) ([]models.ShortenedLink, error)
 	ShortenedLinkBatchExists(ctx context.Context, originalURLs []string) ([]models.ShortenedLink, error)
 	ShortenedLinkBatchDelete(ctx context.Context, id int64, shortCode []string) error
+	ShortenedLinkUpdate(ctx context.Context, url models.ShortenedLink) (models.ShortenedLink, error)
+
+	UTMTemplateCreate(ctx context.Context, tpl models.UTMTemplate) (models.UTMTemplate, error)
+	UTMTemplateGetByName(ctx context.Context, userID int64, name string) (models.UTMTemplate, error)
+	UTMTemplateGetByID(ctx context.Context, id int64) (models.UTMTemplate, error)
+	UTMTemplateGetBatchByUser(ctx context.Context, userID int64) ([]models.UTMTemplate, error)
+	UTMTemplateDelete(ctx context.Context, userID int64, name string) error
+
+	ShortenedLinkVariantAddClicks(ctx context.Context, clicks []models.VariantClicks) error
+	ShortenedLinkVariantStats(ctx context.Context, linkID int64) (map[string]int64, error)
+
+	AbuseReportCreate(ctx context.Context, report models.AbuseReport) (models.AbuseReport, error)
+	AbuseReportCountReporters(ctx context.Context, linkID int64) (int, error)
+	AbuseReportGetByLink(ctx context.Context, linkID int64) ([]models.AbuseReport, error)
+	AbuseReportListPending(ctx context.Context) ([]models.AbuseReportSummary, error)
+	AbuseReportResolve(ctx context.Context, linkID int64) error
+	ShortenedLinkSetModeration(ctx context.Context, linkID int64, status string) error
+
 	Ping(ctx context.Context) error
 
 	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
@@ -33,6 +52,30 @@ type URLStorage interface {
 type URLShortener struct {
 	storage URLStorage
 	baseURL string
+
+	geoTargeting  bool              // разрешены правила по стране
+	normalization URLNormalization  // проверка и нормализация адресов назначения
+	policy        DestinationPolicy // блок-листы и прочие ограничения адресов назначения, nil - без ограничений
+
+	codes           CodeSource   // заранее зарезервированные коды, nil - код проверяется перед вставкой
+	quota           QuotaChecker // квоты пользователей, nil - без ограничений
+	reportThreshold int          // число разных отправителей жалоб, после которого ссылка уходит в карантин, 0 - без карантина
+
+	clicksMu      sync.Mutex
+	variantClicks map[variantKey]int64 // переходы по вариантам, еще не записанные в хранилище
+
+	randIntN func(n int) int  // источник случайности для A/B-вариантов
+	now      func() time.Time // текущее время для окон активности
+}
+
+// Option настраивает необязательные возможности сервиса
+type Option func(*URLShortener)
+
+// WithGeoTargeting разрешает правила перенаправления по стране клиента
+func WithGeoTargeting(enabled bool) Option {
+	return func(s *URLShortener) {
+		s.geoTargeting = enabled
+	}
 }
 
 func (s *URLShortener) GetUserLinks(ctx context.Context, userID int64) ([]models.ShortenedLink, error) {
@@ -49,15 +92,49 @@ func (s *URLShortener) GetUserLinks(ctx context.Context, userID int64) ([]models
 }
 
 // NewServiceURLShortener создает новый экземпляр сервиса
-func NewServiceURLShortener(storage URLStorage, baseURL string) *URLShortener {
-	return &URLShortener{
-		storage: storage,
-		baseURL: baseURL,
+func NewServiceURLShortener(storage URLStorage, baseURL string, opts ...Option) *URLShortener {
+	s := &URLShortener{
+		storage:  storage,
+		baseURL:  baseURL,
+		randIntN: mathrand.IntN,
+		now:      time.Now,
+
+		normalization:   DefaultURLNormalization,
+		reportThreshold: DefaultReportThreshold,
+		variantClicks:   make(map[variantKey]int64),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-// GetURL возвращает оригинальный URL по короткому ключу
+// GetURL возвращает оригинальный URL по короткому ключу.
+// Для удаленных, заблокированных, помещенных в карантин ссылок и ссылок вне окна активности
+// вместе с ошибкой возвращается и сама ссылка
 func (s *URLShortener) GetURL(ctx context.Context, shortKey string) (models.ShortenedLink, error) {
+	url, err := s.getLink(ctx, shortKey)
+	if err != nil {
+		return url, err
+	}
+
+	if url.Moderation == models.ModerationBanned {
+		return url, models.ErrBanned
+	}
+
+	if err := s.checkActiveWindow(url); err != nil {
+		return url, err
+	}
+
+	if url.Moderation == models.ModerationQuarantined {
+		return url, models.ErrQuarantined
+	}
+
+	return url, nil
+}
+
+// getLink возвращает ссылку без учета окна активности: владельцу она доступна и до начала, и после окончания
+func (s *URLShortener) getLink(ctx context.Context, shortKey string) (models.ShortenedLink, error) {
 	if shortKey == "" {
 		return models.ShortenedLink{}, models.ErrInvalidData
 	}
@@ -71,14 +148,37 @@ func (s *URLShortener) GetURL(ctx context.Context, shortKey string) (models.Shor
 		return models.ShortenedLink{}, fmt.Errorf("failed to get URL: %w", err)
 	}
 
-	// Проверяем флаг удаления - если удалена, возвращаем 410 Gone
+	// Проверяем флаг удаления - если удалена, возвращаем 410 Gone.
+	// Саму ссылку тоже отдаем, чтобы ее можно было показать в предпросмотре
 	if url.DeletedFlag {
-		return models.ShortenedLink{}, models.ErrGone
+		return url, models.ErrGone
 	}
 
 	return url, nil
 }
 
+// GetLinkInfo возвращает ссылку вместе с ее состоянием для режима предпросмотра
+func (s *URLShortener) GetLinkInfo(ctx context.Context, shortKey string) (models.ShortenedLink, models.LinkStatus, error) {
+	url, err := s.GetURL(ctx, shortKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrGone):
+			return url, models.LinkStatusDeleted, nil
+		case errors.Is(err, models.ErrNotYetActive):
+			return url, models.LinkStatusScheduled, nil
+		case errors.Is(err, models.ErrExpired):
+			return url, models.LinkStatusExpired, nil
+		case errors.Is(err, models.ErrBanned):
+			return url, models.LinkStatusBanned, nil
+		case errors.Is(err, models.ErrQuarantined):
+			return url, models.LinkStatusQuarantined, nil
+		}
+		return models.ShortenedLink{}, "", err
+	}
+
+	return url, models.LinkStatusActive, nil
+}
+
 // GetShortURL возвращает полный короткий URL это как раз здесь :8080/ добавляется
 func (s *URLShortener) GetShortURL(shortKey string) string {
 	return fmt.Sprintf("%s/%s", s.baseURL, shortKey)
@@ -86,24 +186,75 @@ func (s *URLShortener) GetShortURL(shortKey string) string {
 
 // SetURL создает новую короткую ссылку или возвращает существующую
 func (s *URLShortener) SetURL(ctx context.Context, model models.ShortenedLink) (models.ShortenedLink, error) {
-	if model.OriginalURL == "" || model.UserID <= 0 {
+	if model.UserID <= 0 {
 		return models.ShortenedLink{}, models.ErrInvalidData
 	}
 
-	token, err := s.generateUniqueToken(ctx)
+	originalURL, err := s.NormalizeURL(model.OriginalURL)
+	if err != nil {
+		return models.ShortenedLink{}, err
+	}
+
+	if !IsValidRedirectStatus(model.RedirectStatus) {
+		return models.ShortenedLink{}, fmt.Errorf("%w: unsupported redirect status %d", models.ErrInvalidData, model.RedirectStatus)
+	}
+
+	rules, err := s.normalizeRedirectRules(model.RedirectRules)
+	if err != nil {
+		return models.ShortenedLink{}, err
+	}
+
+	variants, err := normalizeVariants(model.Variants)
+	if err != nil {
+		return models.ShortenedLink{}, err
+	}
+
+	if err := validateActiveWindow(model.ActiveFrom, model.ActiveUntil); err != nil {
+		return models.ShortenedLink{}, err
+	}
+
+	if err := s.checkDestinations(originalURL, rules, variants); err != nil {
+		return models.ShortenedLink{}, err
+	}
+
+	if err := s.checkQuota(ctx, model.UserID, 1); err != nil {
+		return models.ShortenedLink{}, err
+	}
+
+	codes, err := s.takeCodes(ctx, []string{originalURL})
 	if err != nil {
 		return models.ShortenedLink{}, fmt.Errorf("failed to generate token: %w", err)
 	}
 
 	newURL := models.ShortenedLink{
-		OriginalURL: model.OriginalURL,
-		ShortCode:   token,
+		OriginalURL: originalURL,
+		ShortCode:   codes[0],
 		UserID:      model.UserID,
 		CreatedAt:   time.Now().UTC(),
+		Title:       model.Title,
+
+		RedirectStatus: model.RedirectStatus,
+		PassQuery:      model.PassQuery,
+		PassPath:       model.PassPath,
+		UTMTemplateID:  model.UTMTemplateID,
+		RedirectRules:  rules,
+		Variants:       variants,
+		StickyVariants: model.StickyVariants,
+		ActiveFrom:     model.ActiveFrom,
+		ActiveUntil:    model.ActiveUntil,
 	}
 
-	result, err := s.storage.ShortenedLinkCreate(ctx, newURL)
+	var result models.ShortenedLink
+	err = s.withQuota(ctx, model.UserID, 1, func(ctx context.Context) error {
+		var err error
+		result, err = s.storage.ShortenedLinkCreate(ctx, newURL)
+		return err
+	})
 	if err != nil {
+		var quotaErr *models.QuotaExceeded
+		if errors.As(err, &quotaErr) {
+			return models.ShortenedLink{}, err
+		}
 		if errors.Is(err, models.ErrConflict) {
 			return result, models.ErrConflict
 		}
@@ -119,9 +270,41 @@ func (s *URLShortener) BatchCreate(ctx context.Context, urls []models.ShortenedL
 		return nil, models.ErrInvalidData
 	}
 
+	if err := s.CheckBatchSize(len(urls)); err != nil {
+		return nil, err
+	}
+
 	longUrls := make([]string, len(urls))
 	for i, url := range urls {
-		longUrls[i] = url.OriginalURL
+		originalURL, err := s.NormalizeURL(url.OriginalURL)
+		if err != nil {
+			return nil, err
+		}
+		urls[i].OriginalURL = originalURL
+
+		if !IsValidRedirectStatus(url.RedirectStatus) {
+			return nil, fmt.Errorf("%w: unsupported redirect status %d", models.ErrInvalidData, url.RedirectStatus)
+		}
+		rules, err := s.normalizeRedirectRules(url.RedirectRules)
+		if err != nil {
+			return nil, err
+		}
+		urls[i].RedirectRules = rules
+
+		variants, err := normalizeVariants(url.Variants)
+		if err != nil {
+			return nil, err
+		}
+		urls[i].Variants = variants
+
+		if err := validateActiveWindow(url.ActiveFrom, url.ActiveUntil); err != nil {
+			return nil, err
+		}
+
+		if err := s.checkDestinations(originalURL, rules, variants); err != nil {
+			return nil, err
+		}
+		longUrls[i] = originalURL
 	}
 
 	existingURLs, err := s.storage.ShortenedLinkBatchExists(ctx, longUrls)
@@ -144,11 +327,6 @@ func (s *URLShortener) BatchCreate(ctx context.Context, urls []models.ShortenedL
 		if existingURL, exists := existingMap[url.OriginalURL]; exists {
 			result = append(result, existingURL)
 		} else {
-			token, err := s.generateUniqueToken(ctx)
-			if err != nil {
-				return nil, fmt.Errorf("failed to generate token: %w", err)
-			}
-			url.ShortCode = token
 			url.CreatedAt = time.Now()
 			urlsToCreate = append(urlsToCreate, url)
 			allExist = false
@@ -159,14 +337,153 @@ func (s *URLShortener) BatchCreate(ctx context.Context, urls []models.ShortenedL
 		return result, models.ErrConflict
 	}
 
-	createdURLs, err := s.storage.ShortenedLinkBatchCreate(ctx, urlsToCreate)
+	// Пакет создается от имени одного пользователя, квота считается только по новым ссылкам
+	if err := s.checkQuota(ctx, urlsToCreate[0].UserID, len(urlsToCreate)); err != nil {
+		return nil, err
+	}
+
+	// Коды для всего пакета берутся одним обращением к пулу
+	targets := make([]string, len(urlsToCreate))
+	for i, url := range urlsToCreate {
+		targets[i] = url.OriginalURL
+	}
+	codes, err := s.takeCodes(ctx, targets)
 	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	for i := range urlsToCreate {
+		urlsToCreate[i].ShortCode = codes[i]
+	}
+
+	var createdURLs []models.ShortenedLink
+	err = s.withQuota(ctx, urlsToCreate[0].UserID, len(urlsToCreate), func(ctx context.Context) error {
+		var err error
+		createdURLs, err = s.storage.ShortenedLinkBatchCreate(ctx, urlsToCreate)
+		return err
+	})
+	if err != nil {
+		var quotaErr *models.QuotaExceeded
+		if errors.As(err, &quotaErr) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to create URLs: %w", err)
 	}
 
 	return append(result, createdURLs...), nil
 }
 
+// UpdateLink меняет настройки ссылки, принадлежащей пользователю
+func (s *URLShortener) UpdateLink(ctx context.Context, userID int64, shortKey string, update models.ShortenedLinkUpdate) (models.ShortenedLink, error) {
+	if userID <= 0 || shortKey == "" {
+		return models.ShortenedLink{}, models.ErrInvalidData
+	}
+
+	if update.RedirectStatus != nil && !IsValidRedirectStatus(*update.RedirectStatus) {
+		return models.ShortenedLink{}, fmt.Errorf("%w: unsupported redirect status %d", models.ErrInvalidData, *update.RedirectStatus)
+	}
+
+	var rules []models.RedirectRule
+	if update.RedirectRules != nil {
+		var err error
+		if rules, err = s.normalizeRedirectRules(*update.RedirectRules); err != nil {
+			return models.ShortenedLink{}, err
+		}
+	}
+
+	var variants []models.LinkVariant
+	if update.Variants != nil {
+		var err error
+		if variants, err = normalizeVariants(*update.Variants); err != nil {
+			return models.ShortenedLink{}, err
+		}
+	}
+
+	// Основной адрес не меняется, проверяем только новые цели
+	if err := s.checkDestinations("", rules, variants); err != nil {
+		return models.ShortenedLink{}, err
+	}
+
+	var result models.ShortenedLink
+	err := s.storage.WithinTx(ctx, func(ctx context.Context) error {
+		link, err := s.getLink(ctx, shortKey)
+		if err != nil {
+			return err
+		}
+
+		// Чужие ссылки для пользователя не существуют
+		if link.UserID != userID {
+			return fmt.Errorf("%w: URL not found", models.ErrUnfound)
+		}
+
+		if update.Title != nil {
+			link.Title = *update.Title
+		}
+		if update.RedirectStatus != nil {
+			link.RedirectStatus = *update.RedirectStatus
+		}
+		if update.PassQuery != nil {
+			link.PassQuery = *update.PassQuery
+		}
+		if update.PassPath != nil {
+			link.PassPath = *update.PassPath
+		}
+		if update.RedirectRules != nil {
+			link.RedirectRules = rules
+		}
+		if update.Variants != nil {
+			link.Variants = variants
+		}
+		if update.StickyVariants != nil {
+			link.StickyVariants = *update.StickyVariants
+		}
+		if update.ActiveFrom != nil {
+			link.ActiveFrom = *update.ActiveFrom
+		}
+		if update.ActiveUntil != nil {
+			link.ActiveUntil = *update.ActiveUntil
+		}
+		// Окно проверяется целиком: новая граница должна сочетаться с сохраненной
+		if err := validateActiveWindow(link.ActiveFrom, link.ActiveUntil); err != nil {
+			return err
+		}
+		if update.UTMTemplate != nil {
+			link.UTMTemplateID = 0
+			if *update.UTMTemplate != "" {
+				tpl, err := s.getUTMTemplate(ctx, userID, *update.UTMTemplate)
+				if err != nil {
+					return err
+				}
+				link.UTMTemplateID = tpl.ID
+			}
+		}
+
+		result, err = s.storage.ShortenedLinkUpdate(ctx, link)
+		if err != nil {
+			return fmt.Errorf("failed to update URL: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.ShortenedLink{}, err
+	}
+
+	return result, nil
+}
+
+// IsValidRedirectStatus проверяет, что код перенаправления поддерживается.
+// 0 означает значение по умолчанию из конфигурации сервера.
+func IsValidRedirectStatus(status int) bool {
+	switch status {
+	case 0,
+		http.StatusMovedPermanently,
+		http.StatusFound,
+		http.StatusTemporaryRedirect,
+		http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
 func (s *URLShortener) BatchDelete(ctx context.Context, id int64, shortCode []string) {
 	const (
 		numOfRetry   = 5
@@ -179,7 +496,7 @@ func (s *URLShortener) BatchDelete(ctx context.Context, id int64, shortCode []st
 	batchDataCh := make(chan []string, numOfWorkers)
 	wgWorkers := sync.WaitGroup{}
 
-	mainCtx, _ := context.WithCancel(context.Background())
+	mainCtx, cancel := context.WithCancel(context.Background())
 
 	for i := 0; i < numOfWorkers; i++ {
 		wgWorkers.Add(1)
@@ -240,6 +557,7 @@ func (s *URLShortener) BatchDelete(ctx context.Context, id int64, shortCode []st
 	}()
 
 	go func() {
+		defer cancel()
 		/*
 			по идее здесь просто логгируем все возникшие ошибки,
 			пока что у меня логгер лишь на уровне middleware
@@ -277,15 +595,13 @@ func (s *URLShortener) PingDataBase(ctx context.Context) error {
 // 	return s.storage.List(ctx, limit, offset)
 // }
 
-const (
-	maxAttempts  = 10
-	tokenLength  = 8
-	tokenLetters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
-)
+const maxAttempts = 10
 
+// generateUniqueToken подбирает свободный код без пула: проверка не атомарна со вставкой,
+// дубликат отсекается только ограничением уникальности хранилища
 func (s *URLShortener) generateUniqueToken(ctx context.Context) (string, error) {
 	for i := 0; i < maxAttempts; i++ {
-		token := generateRandomToken()
+		token := short_code.RandomCode()
 		_, err := s.storage.ShortenedLinkGetByShortKey(ctx, token)
 		if errors.Is(err, models.ErrUnfound) {
 			return token, nil
@@ -298,14 +614,3 @@ func (s *URLShortener) generateUniqueToken(ctx context.Context) (string, error)
 
 	return "", errors.New("failed to generate unique token after several attempts")
 }
-
-func generateRandomToken() string {
-	b := make([]byte, tokenLength)
-	letterCount := big.NewInt(int64(len(tokenLetters)))
-
-	for i := range b {
-		n, _ := rand.Int(rand.Reader, letterCount)
-		b[i] = tokenLetters[n.Int64()]
-	}
-	return string(b)
-}
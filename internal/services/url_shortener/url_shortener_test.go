@@ -88,6 +88,94 @@ func TestURLShortener_GetURL(t *testing.T) {
 	}
 }
 
+func TestURLShortener_GetLinkInfo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	service := NewServiceURLShortener(mockStorage, "http://short")
+
+	tests := []struct {
+		name        string
+		shortKey    string
+		mockSetup   func()
+		wantURL     models.ShortenedLink
+		wantStatus  models.LinkStatus
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name:     "Активная ссылка",
+			shortKey: "abc123",
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					ShortenedLinkGetByShortKey(gomock.Any(), "abc123").
+					Return(models.ShortenedLink{
+						OriginalURL: "http://long.url",
+						ShortCode:   "abc123",
+						Title:       "Long",
+					}, nil)
+			},
+			wantURL: models.ShortenedLink{
+				OriginalURL: "http://long.url",
+				ShortCode:   "abc123",
+				Title:       "Long",
+			},
+			wantStatus: models.LinkStatusActive,
+		},
+		{
+			name:     "Удаленная ссылка показывается со статусом deleted",
+			shortKey: "deleted1",
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					ShortenedLinkGetByShortKey(gomock.Any(), "deleted1").
+					Return(models.ShortenedLink{
+						OriginalURL: "http://old.url",
+						ShortCode:   "deleted1",
+						DeletedFlag: true,
+					}, nil)
+			},
+			wantURL: models.ShortenedLink{
+				OriginalURL: "http://old.url",
+				ShortCode:   "deleted1",
+				DeletedFlag: true,
+			},
+			wantStatus: models.LinkStatusDeleted,
+		},
+		{
+			name:     "URL не найден",
+			shortKey: "notfound",
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					ShortenedLinkGetByShortKey(gomock.Any(), "notfound").
+					Return(models.ShortenedLink{}, models.ErrUnfound)
+			},
+			wantErr:     true,
+			expectedErr: models.ErrUnfound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mockSetup != nil {
+				tt.mockSetup()
+			}
+
+			got, status, err := service.GetLinkInfo(context.Background(), tt.shortKey)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantURL, got)
+			assert.Equal(t, tt.wantStatus, status)
+		})
+	}
+}
+
 func TestURLShortener_GetShortURL(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -136,7 +224,7 @@ func TestURLShortener_SetURL(t *testing.T) {
 		{
 			name: "Успешное создание короткой ссылки",
 			input: models.ShortenedLink{
-				OriginalURL: "http://long.url",
+				OriginalURL: "http://long.url/",
 				UserID:      1,
 			},
 			mockSetup: func() {
@@ -158,7 +246,7 @@ func TestURLShortener_SetURL(t *testing.T) {
 					})
 			},
 			wantURL: models.ShortenedLink{
-				OriginalURL: "http://long.url",
+				OriginalURL: "http://long.url/",
 				UserID:      1,
 			},
 			wantErr: false,
@@ -166,7 +254,7 @@ func TestURLShortener_SetURL(t *testing.T) {
 		{
 			name: "URL уже существует",
 			input: models.ShortenedLink{
-				OriginalURL: "http://existing.url",
+				OriginalURL: "http://existing.url/",
 				UserID:      1,
 			},
 			mockSetup: func() {
@@ -180,7 +268,7 @@ func TestURLShortener_SetURL(t *testing.T) {
 					ShortenedLinkCreate(gomock.Any(), gomock.Any()).
 					Return(models.ShortenedLink{
 						ID:          1,
-						OriginalURL: "http://existing.url",
+						OriginalURL: "http://existing.url/",
 						ShortCode:   "existing",
 						UserID:      1,
 						CreatedAt:   time.Now(),
@@ -201,6 +289,19 @@ func TestURLShortener_SetURL(t *testing.T) {
 			wantErr:     true,
 			expectedErr: models.ErrInvalidData,
 		},
+		{
+			name: "Невалидные данные - неподдерживаемый код перенаправления",
+			input: models.ShortenedLink{
+				OriginalURL:    "http://valid.url",
+				UserID:         1,
+				RedirectStatus: 305,
+			},
+			mockSetup: func() {
+				// Нет вызовов к хранилищу для невалидных данных
+			},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
 		{
 			name: "Невалидные данные - нулевой UserID",
 			input: models.ShortenedLink{
@@ -263,13 +364,13 @@ func TestURLShortener_BatchCreate(t *testing.T) {
 		{
 			name: "Успешное пакетное создание",
 			input: []models.ShortenedLink{
-				{OriginalURL: "http://url1", UserID: 1},
-				{OriginalURL: "http://url2", UserID: 1},
+				{OriginalURL: "http://url1/", UserID: 1},
+				{OriginalURL: "http://url2/", UserID: 1},
 			},
 			mockSetup: func() {
 				// Проверка существующих URL
 				mockStorage.EXPECT().
-					ShortenedLinkBatchExists(gomock.Any(), []string{"http://url1", "http://url2"}).
+					ShortenedLinkBatchExists(gomock.Any(), []string{"http://url1/", "http://url2/"}).
 					Return([]models.ShortenedLink{}, nil)
 
 				// Проверка существующих shortCode после Генерации shortCode
@@ -289,22 +390,22 @@ func TestURLShortener_BatchCreate(t *testing.T) {
 					})
 			},
 			wantResult: []models.ShortenedLink{
-				{OriginalURL: "http://url1", UserID: 1},
-				{OriginalURL: "http://url2", UserID: 1},
+				{OriginalURL: "http://url1/", UserID: 1},
+				{OriginalURL: "http://url2/", UserID: 1},
 			},
 		},
 		{
 			name: "Часть URL уже существует",
 			input: []models.ShortenedLink{
-				{OriginalURL: "http://existing", UserID: 1},
-				{OriginalURL: "http://new", UserID: 1},
+				{OriginalURL: "http://existing/", UserID: 1},
+				{OriginalURL: "http://new/", UserID: 1},
 			},
 			mockSetup: func() {
 				// Возвращаем один существующий URL
 				mockStorage.EXPECT().
-					ShortenedLinkBatchExists(gomock.Any(), []string{"http://existing", "http://new"}).
+					ShortenedLinkBatchExists(gomock.Any(), []string{"http://existing/", "http://new/"}).
 					Return([]models.ShortenedLink{
-						{OriginalURL: "http://existing", ShortCode: "exist123", UserID: 1},
+						{OriginalURL: "http://existing/", ShortCode: "exist123", UserID: 1},
 					}, nil)
 
 				// Проверка существующих shortCode после Генерации shortCode
@@ -317,32 +418,32 @@ func TestURLShortener_BatchCreate(t *testing.T) {
 					ShortenedLinkBatchCreate(gomock.Any(), gomock.Any()).
 					DoAndReturn(func(ctx context.Context, urls []models.ShortenedLink) ([]models.ShortenedLink, error) {
 						assert.Len(t, urls, 1)
-						assert.Equal(t, "http://new", urls[0].OriginalURL)
+						assert.Equal(t, "http://new/", urls[0].OriginalURL)
 						return urls, nil
 					})
 			},
 			wantResult: []models.ShortenedLink{
-				{OriginalURL: "http://existing", ShortCode: "exist123", UserID: 1},
-				{OriginalURL: "http://new", UserID: 1},
+				{OriginalURL: "http://existing/", ShortCode: "exist123", UserID: 1},
+				{OriginalURL: "http://new/", UserID: 1},
 			},
 		},
 		{
 			name: "Все URL уже существуют",
 			input: []models.ShortenedLink{
-				{OriginalURL: "http://existing1", UserID: 1},
-				{OriginalURL: "http://existing2", UserID: 1},
+				{OriginalURL: "http://existing1/", UserID: 1},
+				{OriginalURL: "http://existing2/", UserID: 1},
 			},
 			mockSetup: func() {
 				mockStorage.EXPECT().
-					ShortenedLinkBatchExists(gomock.Any(), []string{"http://existing1", "http://existing2"}).
+					ShortenedLinkBatchExists(gomock.Any(), []string{"http://existing1/", "http://existing2/"}).
 					Return([]models.ShortenedLink{
-						{OriginalURL: "http://existing1", ShortCode: "exist1", UserID: 1},
-						{OriginalURL: "http://existing2", ShortCode: "exist2", UserID: 1},
+						{OriginalURL: "http://existing1/", ShortCode: "exist1", UserID: 1},
+						{OriginalURL: "http://existing2/", ShortCode: "exist2", UserID: 1},
 					}, nil)
 			},
 			wantResult: []models.ShortenedLink{
-				{OriginalURL: "http://existing1", ShortCode: "exist1", UserID: 1},
-				{OriginalURL: "http://existing2", ShortCode: "exist2", UserID: 1},
+				{OriginalURL: "http://existing1/", ShortCode: "exist1", UserID: 1},
+				{OriginalURL: "http://existing2/", ShortCode: "exist2", UserID: 1},
 			},
 			wantErr:     true,
 			expectedErr: models.ErrConflict,
@@ -388,6 +489,148 @@ func TestURLShortener_BatchCreate(t *testing.T) {
 	}
 }
 
+func TestURLShortener_UpdateLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	service := NewServiceURLShortener(mockStorage, "http://short")
+
+	permanent := 308
+	unsupported := 303
+	utmTemplate := "newsletter"
+	launch := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	beforeLaunch := launch.Add(-time.Hour)
+
+	tests := []struct {
+		name        string
+		userID      int64
+		shortKey    string
+		update      models.ShortenedLinkUpdate
+		mockSetup   func()
+		wantURL     models.ShortenedLink
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name:     "Смена кода перенаправления",
+			userID:   1,
+			shortKey: "abc123",
+			update:   models.ShortenedLinkUpdate{RedirectStatus: &permanent},
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					WithinTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+						return fn(ctx)
+					})
+				mockStorage.EXPECT().
+					ShortenedLinkGetByShortKey(gomock.Any(), "abc123").
+					Return(models.ShortenedLink{ShortCode: "abc123", OriginalURL: "http://long.url", UserID: 1, Title: "Long"}, nil)
+				mockStorage.EXPECT().
+					ShortenedLinkUpdate(gomock.Any(), models.ShortenedLink{
+						ShortCode: "abc123", OriginalURL: "http://long.url", UserID: 1, Title: "Long", RedirectStatus: 308,
+					}).
+					DoAndReturn(func(ctx context.Context, url models.ShortenedLink) (models.ShortenedLink, error) {
+						return url, nil
+					})
+			},
+			wantURL: models.ShortenedLink{ShortCode: "abc123", OriginalURL: "http://long.url", UserID: 1, Title: "Long", RedirectStatus: 308},
+		},
+		{
+			name:     "Чужая ссылка",
+			userID:   2,
+			shortKey: "abc123",
+			update:   models.ShortenedLinkUpdate{RedirectStatus: &permanent},
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					WithinTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+						return fn(ctx)
+					})
+				mockStorage.EXPECT().
+					ShortenedLinkGetByShortKey(gomock.Any(), "abc123").
+					Return(models.ShortenedLink{ShortCode: "abc123", UserID: 1}, nil)
+			},
+			wantErr:     true,
+			expectedErr: models.ErrUnfound,
+		},
+		{
+			name:     "Привязка UTM-шаблона по имени",
+			userID:   1,
+			shortKey: "abc123",
+			update:   models.ShortenedLinkUpdate{UTMTemplate: &utmTemplate},
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					WithinTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+						return fn(ctx)
+					})
+				mockStorage.EXPECT().
+					ShortenedLinkGetByShortKey(gomock.Any(), "abc123").
+					Return(models.ShortenedLink{ShortCode: "abc123", OriginalURL: "http://long.url", UserID: 1}, nil)
+				mockStorage.EXPECT().
+					UTMTemplateGetByName(gomock.Any(), int64(1), "newsletter").
+					Return(models.UTMTemplate{ID: 5, UserID: 1, Name: "newsletter"}, nil)
+				mockStorage.EXPECT().
+					ShortenedLinkUpdate(gomock.Any(), models.ShortenedLink{
+						ShortCode: "abc123", OriginalURL: "http://long.url", UserID: 1, UTMTemplateID: 5,
+					}).
+					DoAndReturn(func(ctx context.Context, url models.ShortenedLink) (models.ShortenedLink, error) {
+						return url, nil
+					})
+			},
+			wantURL: models.ShortenedLink{ShortCode: "abc123", OriginalURL: "http://long.url", UserID: 1, UTMTemplateID: 5},
+		},
+		{
+			name:     "Новая граница окна не сочетается с сохраненной",
+			userID:   1,
+			shortKey: "abc123",
+			update:   models.ShortenedLinkUpdate{ActiveUntil: &beforeLaunch},
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					WithinTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+						return fn(ctx)
+					})
+				// Ссылка еще не активна, но владелец может ее менять
+				mockStorage.EXPECT().
+					ShortenedLinkGetByShortKey(gomock.Any(), "abc123").
+					Return(models.ShortenedLink{ShortCode: "abc123", UserID: 1, ActiveFrom: launch}, nil)
+			},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:        "Неподдерживаемый код перенаправления",
+			userID:      1,
+			shortKey:    "abc123",
+			update:      models.ShortenedLinkUpdate{RedirectStatus: &unsupported},
+			mockSetup:   func() {},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mockSetup != nil {
+				tt.mockSetup()
+			}
+
+			got, err := service.UpdateLink(context.Background(), tt.userID, tt.shortKey, tt.update)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantURL, got)
+		})
+	}
+}
+
 func TestURLShortener_GetUserLinks(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -0,0 +1,152 @@
+package url_shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"urlshortener/internal/domain/models"
+)
+
+var utmTemplateNameRe = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// CreateUTMTemplate сохраняет именованный UTM-шаблон пользователя
+func (s *URLShortener) CreateUTMTemplate(ctx context.Context, tpl models.UTMTemplate) (models.UTMTemplate, error) {
+	if tpl.UserID <= 0 {
+		return models.UTMTemplate{}, models.ErrInvalidData
+	}
+
+	tpl.Name = strings.TrimSpace(tpl.Name)
+	if !utmTemplateNameRe.MatchString(tpl.Name) {
+		return models.UTMTemplate{}, fmt.Errorf("%w: template name must be 1-64 characters of [A-Za-z0-9._-]", models.ErrInvalidData)
+	}
+
+	if len(utmValues(tpl)) == 0 {
+		return models.UTMTemplate{}, fmt.Errorf("%w: template must set at least one utm parameter", models.ErrInvalidData)
+	}
+
+	tpl.ID = 0
+	tpl.CreatedAt = time.Now().UTC()
+
+	result, err := s.storage.UTMTemplateCreate(ctx, tpl)
+	if err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			return models.UTMTemplate{}, fmt.Errorf("%w: template %q already exists", models.ErrConflict, tpl.Name)
+		}
+		return models.UTMTemplate{}, fmt.Errorf("failed to create UTM template: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetUserUTMTemplates возвращает UTM-шаблоны пользователя
+func (s *URLShortener) GetUserUTMTemplates(ctx context.Context, userID int64) ([]models.UTMTemplate, error) {
+	if userID <= 0 {
+		return nil, models.ErrInvalidData
+	}
+
+	templates, err := s.storage.UTMTemplateGetBatchByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get UTM templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// DeleteUTMTemplate удаляет шаблон, ссылки с этим шаблоном перестают получать его параметры
+func (s *URLShortener) DeleteUTMTemplate(ctx context.Context, userID int64, name string) error {
+	if userID <= 0 || name == "" {
+		return models.ErrInvalidData
+	}
+
+	if err := s.storage.UTMTemplateDelete(ctx, userID, name); err != nil {
+		if errors.Is(err, models.ErrUnfound) {
+			return fmt.Errorf("%w: template %q not found", models.ErrUnfound, name)
+		}
+		return fmt.Errorf("failed to delete UTM template: %w", err)
+	}
+
+	return nil
+}
+
+// ApplyUTMTemplate подготавливает ссылку перед созданием: в режиме create параметры
+// шаблона сразу дописываются в OriginalURL, в режиме redirect шаблон привязывается к ссылке
+func (s *URLShortener) ApplyUTMTemplate(ctx context.Context, link models.ShortenedLink, name, mode string) (models.ShortenedLink, error) {
+	if name == "" {
+		return link, nil
+	}
+
+	if mode == "" {
+		mode = models.UTMApplyOnCreate
+	}
+	if mode != models.UTMApplyOnCreate && mode != models.UTMApplyOnRedirect {
+		return models.ShortenedLink{}, fmt.Errorf("%w: unsupported utm mode %q", models.ErrInvalidData, mode)
+	}
+
+	tpl, err := s.getUTMTemplate(ctx, link.UserID, name)
+	if err != nil {
+		return models.ShortenedLink{}, err
+	}
+
+	if mode == models.UTMApplyOnRedirect {
+		link.UTMTemplateID = tpl.ID
+		return link, nil
+	}
+
+	// Адрес проверяется до применения шаблона, чтобы ошибка указывала на исходную причину
+	originalURL, err := s.NormalizeURL(link.OriginalURL)
+	if err != nil {
+		return models.ShortenedLink{}, err
+	}
+	originalURL, err = applyUTM(originalURL, tpl)
+	if err != nil {
+		return models.ShortenedLink{}, err
+	}
+	link.OriginalURL = originalURL
+	link.UTMTemplateID = 0
+
+	return link, nil
+}
+
+// getUTMTemplate ищет шаблон пользователя по имени. Неизвестный шаблон - ошибка во входных данных
+func (s *URLShortener) getUTMTemplate(ctx context.Context, userID int64, name string) (models.UTMTemplate, error) {
+	tpl, err := s.storage.UTMTemplateGetByName(ctx, userID, name)
+	if err != nil {
+		if errors.Is(err, models.ErrUnfound) {
+			return models.UTMTemplate{}, fmt.Errorf("%w: unknown UTM template %q", models.ErrInvalidData, name)
+		}
+		return models.UTMTemplate{}, fmt.Errorf("failed to get UTM template: %w", err)
+	}
+	return tpl, nil
+}
+
+// applyUTM дописывает параметры шаблона в URL, не трогая уже заданные параметры
+func applyUTM(rawURL string, tpl models.UTMTemplate) (string, error) {
+	dest, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid URL", models.ErrInvalidData)
+	}
+
+	dest.RawQuery = mergeQuery(dest.RawQuery, utmValues(tpl))
+	return dest.String(), nil
+}
+
+// utmValues возвращает непустые utm_* параметры шаблона
+func utmValues(tpl models.UTMTemplate) url.Values {
+	values := url.Values{}
+	for key, value := range map[string]string{
+		"utm_source":   tpl.Source,
+		"utm_medium":   tpl.Medium,
+		"utm_campaign": tpl.Campaign,
+		"utm_term":     tpl.Term,
+		"utm_content":  tpl.Content,
+	} {
+		if value = strings.TrimSpace(value); value != "" {
+			values.Set(key, value)
+		}
+	}
+	return values
+}
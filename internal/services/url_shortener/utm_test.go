@@ -0,0 +1,176 @@
+package url_shortener
+
+import (
+	"context"
+	"testing"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestURLShortener_CreateUTMTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	service := NewServiceURLShortener(mockStorage, "http://short")
+
+	tests := []struct {
+		name        string
+		input       models.UTMTemplate
+		mockSetup   func()
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name:  "Успешное создание",
+			input: models.UTMTemplate{UserID: 1, Name: " newsletter ", Source: "mail"},
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					UTMTemplateCreate(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, tpl models.UTMTemplate) (models.UTMTemplate, error) {
+						assert.Equal(t, "newsletter", tpl.Name)
+						assert.False(t, tpl.CreatedAt.IsZero())
+						tpl.ID = 1
+						return tpl, nil
+					})
+			},
+		},
+		{
+			name:        "Недопустимое имя",
+			input:       models.UTMTemplate{UserID: 1, Name: "a b", Source: "mail"},
+			mockSetup:   func() {},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:        "Шаблон без параметров",
+			input:       models.UTMTemplate{UserID: 1, Name: "empty", Source: "  "},
+			mockSetup:   func() {},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:  "Имя уже занято",
+			input: models.UTMTemplate{UserID: 1, Name: "newsletter", Source: "mail"},
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					UTMTemplateCreate(gomock.Any(), gomock.Any()).
+					Return(models.UTMTemplate{}, models.ErrConflict)
+			},
+			wantErr:     true,
+			expectedErr: models.ErrConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := service.CreateUTMTemplate(context.Background(), tt.input)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), got.ID)
+		})
+	}
+}
+
+func TestURLShortener_ApplyUTMTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	service := NewServiceURLShortener(mockStorage, "http://short")
+
+	template := models.UTMTemplate{ID: 5, UserID: 1, Name: "newsletter", Source: "mail", Campaign: "spring"}
+	link := models.ShortenedLink{UserID: 1, OriginalURL: "https://example.com/page?utm_source=site#top"}
+
+	tests := []struct {
+		name        string
+		templateArg string
+		mode        string
+		mockSetup   func()
+		want        models.ShortenedLink
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name:      "Без шаблона ссылка не меняется",
+			mockSetup: func() {},
+			want:      link,
+		},
+		{
+			name:        "Режим create дописывает параметры в URL",
+			templateArg: "newsletter",
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					UTMTemplateGetByName(gomock.Any(), int64(1), "newsletter").
+					Return(template, nil)
+			},
+			want: models.ShortenedLink{
+				UserID:      1,
+				OriginalURL: "https://example.com/page?utm_source=site&utm_campaign=spring#top",
+			},
+		},
+		{
+			name:        "Режим redirect привязывает шаблон",
+			templateArg: "newsletter",
+			mode:        models.UTMApplyOnRedirect,
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					UTMTemplateGetByName(gomock.Any(), int64(1), "newsletter").
+					Return(template, nil)
+			},
+			want: models.ShortenedLink{
+				UserID:        1,
+				OriginalURL:   link.OriginalURL,
+				UTMTemplateID: 5,
+			},
+		},
+		{
+			name:        "Неизвестный шаблон",
+			templateArg: "missing",
+			mockSetup: func() {
+				mockStorage.EXPECT().
+					UTMTemplateGetByName(gomock.Any(), int64(1), "missing").
+					Return(models.UTMTemplate{}, models.ErrUnfound)
+			},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:        "Неизвестный режим",
+			templateArg: "newsletter",
+			mode:        "later",
+			mockSetup:   func() {},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := service.ApplyUTMTemplate(context.Background(), link, tt.templateArg, tt.mode)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
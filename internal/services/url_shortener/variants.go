@@ -0,0 +1,213 @@
+package url_shortener
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"urlshortener/internal/domain/models"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	maxLinkVariants  = 10
+	maxVariantWeight = 1000
+)
+
+var variantNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// normalizeVariants проверяет варианты и подставляет имена v1, v2... для безымянных
+func normalizeVariants(variants []models.LinkVariant) ([]models.LinkVariant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	if len(variants) > maxLinkVariants {
+		return nil, fmt.Errorf("%w: too many variants, max %d", models.ErrInvalidData, maxLinkVariants)
+	}
+
+	result := make([]models.LinkVariant, 0, len(variants))
+	names := make(map[string]bool, len(variants))
+	totalWeight := 0
+
+	for i, variant := range variants {
+		variant.Name = strings.TrimSpace(variant.Name)
+		if variant.Name == "" {
+			variant.Name = "v" + strconv.Itoa(i+1)
+		}
+		if !variantNameRe.MatchString(variant.Name) {
+			return nil, fmt.Errorf("%w: variant %d: name must be 1-32 characters of [A-Za-z0-9_-]", models.ErrInvalidData, i)
+		}
+		if names[variant.Name] {
+			return nil, fmt.Errorf("%w: variant %d: duplicate name %q", models.ErrInvalidData, i, variant.Name)
+		}
+		names[variant.Name] = true
+
+		if variant.Weight < 0 || variant.Weight > maxVariantWeight {
+			return nil, fmt.Errorf("%w: variant %d: weight must be between 0 and %d", models.ErrInvalidData, i, maxVariantWeight)
+		}
+		totalWeight += variant.Weight
+
+		target, err := url.Parse(variant.TargetURL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return nil, fmt.Errorf("%w: variant %d: target must be an absolute http(s) URL", models.ErrInvalidData, i)
+		}
+
+		variant.Clicks = 0
+		result = append(result, variant)
+	}
+
+	if totalWeight == 0 {
+		return nil, fmt.Errorf("%w: at least one variant must have a positive weight", models.ErrInvalidData)
+	}
+
+	return result, nil
+}
+
+// chooseVariant выбирает вариант по весу. Закрепленный вариант используется,
+// пока он существует и не поставлен на паузу
+func (s *URLShortener) chooseVariant(link models.ShortenedLink, sticky string) (models.LinkVariant, bool) {
+	if len(link.Variants) == 0 {
+		return models.LinkVariant{}, false
+	}
+
+	totalWeight := 0
+	for _, variant := range link.Variants {
+		if link.StickyVariants && sticky != "" && variant.Name == sticky && variant.Weight > 0 {
+			return variant, true
+		}
+		totalWeight += variant.Weight
+	}
+	if totalWeight <= 0 {
+		return models.LinkVariant{}, false
+	}
+
+	point := s.randIntN(totalWeight)
+	for _, variant := range link.Variants {
+		if point < variant.Weight {
+			return variant, true
+		}
+		point -= variant.Weight
+	}
+
+	return models.LinkVariant{}, false
+}
+
+// variantKey вариант ссылки в буфере переходов
+type variantKey struct {
+	linkID  int64
+	variant string
+}
+
+// RecordVariantClick учитывает переход на выбранный вариант в памяти, не задерживая перенаправление.
+// В хранилище переходы записывает FlushVariantClicks
+func (s *URLShortener) RecordVariantClick(ctx context.Context, link models.ShortenedLink, variant string) error {
+	if link.ID <= 0 || variant == "" {
+		return models.ErrInvalidData
+	}
+
+	s.clicksMu.Lock()
+	s.variantClicks[variantKey{linkID: link.ID, variant: variant}]++
+	s.clicksMu.Unlock()
+	return nil
+}
+
+// FlushVariantClicks записывает накопленные переходы в хранилище одним вызовом.
+// Если запись не удалась, переходы возвращаются в буфер до следующей попытки
+func (s *URLShortener) FlushVariantClicks(ctx context.Context) error {
+	s.clicksMu.Lock()
+	pending := s.variantClicks
+	s.variantClicks = make(map[variantKey]int64, len(pending))
+	s.clicksMu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	clicks := make([]models.VariantClicks, 0, len(pending))
+	for key, count := range pending {
+		clicks = append(clicks, models.VariantClicks{LinkID: key.linkID, Variant: key.variant, Clicks: count})
+	}
+	// Одинаковый порядок строк у всех экземпляров не дает параллельным записям взаимно блокироваться
+	slices.SortFunc(clicks, func(a, b models.VariantClicks) int {
+		return cmp.Or(cmp.Compare(a.LinkID, b.LinkID), strings.Compare(a.Variant, b.Variant))
+	})
+
+	if err := s.storage.ShortenedLinkVariantAddClicks(ctx, clicks); err != nil {
+		s.clicksMu.Lock()
+		for key, count := range pending {
+			s.variantClicks[key] += count
+		}
+		s.clicksMu.Unlock()
+		return fmt.Errorf("failed to record variant clicks: %w", err)
+	}
+	return nil
+}
+
+// RunVariantClicks записывает накопленные переходы каждые interval до отмены ctx.
+// Последние переходы перед остановкой нужно записать отдельным вызовом FlushVariantClicks
+func (s *URLShortener) RunVariantClicks(ctx context.Context, interval time.Duration, log *zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.FlushVariantClicks(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to flush variant clicks")
+			}
+		}
+	}
+}
+
+// pendingVariantClicks переходы по вариантам ссылки, еще не записанные в хранилище
+func (s *URLShortener) pendingVariantClicks(linkID int64) map[string]int64 {
+	s.clicksMu.Lock()
+	defer s.clicksMu.Unlock()
+
+	pending := make(map[string]int64)
+	for key, count := range s.variantClicks {
+		if key.linkID == linkID {
+			pending[key.variant] = count
+		}
+	}
+	return pending
+}
+
+// GetLinkStats возвращает ссылку пользователя с числом переходов по каждому варианту
+func (s *URLShortener) GetLinkStats(ctx context.Context, userID int64, shortKey string) (models.ShortenedLink, error) {
+	if userID <= 0 || shortKey == "" {
+		return models.ShortenedLink{}, models.ErrInvalidData
+	}
+
+	link, err := s.getLink(ctx, shortKey)
+	if err != nil && !errors.Is(err, models.ErrGone) {
+		return models.ShortenedLink{}, err
+	}
+
+	// Чужие ссылки для пользователя не существуют
+	if link.UserID != userID {
+		return models.ShortenedLink{}, fmt.Errorf("%w: URL not found", models.ErrUnfound)
+	}
+
+	clicks, err := s.storage.ShortenedLinkVariantStats(ctx, link.ID)
+	if err != nil {
+		return models.ShortenedLink{}, fmt.Errorf("failed to get variant stats: %w", err)
+	}
+
+	// Переходы этого экземпляра, которые еще не дошли до хранилища, тоже учитываются
+	pending := s.pendingVariantClicks(link.ID)
+	for i := range link.Variants {
+		link.Variants[i].Clicks = clicks[link.Variants[i].Name] + pending[link.Variants[i].Name]
+	}
+
+	return link, nil
+}
@@ -0,0 +1,242 @@
+package url_shortener
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"urlshortener/internal/domain/models"
+	"urlshortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestNormalizeVariants(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []models.LinkVariant
+		want        []models.LinkVariant
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name: "Безымянные варианты получают имена по порядку",
+			input: []models.LinkVariant{
+				{TargetURL: "https://a.example.com", Weight: 3},
+				{Name: "control", TargetURL: "https://b.example.com", Weight: 0},
+				{TargetURL: "https://c.example.com", Weight: 1, Clicks: 10},
+			},
+			want: []models.LinkVariant{
+				{Name: "v1", TargetURL: "https://a.example.com", Weight: 3},
+				{Name: "control", TargetURL: "https://b.example.com", Weight: 0},
+				{Name: "v3", TargetURL: "https://c.example.com", Weight: 1},
+			},
+		},
+		{
+			name: "Повторяющиеся имена",
+			input: []models.LinkVariant{
+				{Name: "a", TargetURL: "https://a.example.com", Weight: 1},
+				{Name: "a", TargetURL: "https://b.example.com", Weight: 1},
+			},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:        "Все варианты на паузе",
+			input:       []models.LinkVariant{{TargetURL: "https://a.example.com", Weight: 0}},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:        "Отрицательный вес",
+			input:       []models.LinkVariant{{TargetURL: "https://a.example.com", Weight: -1}},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+		{
+			name:        "Некорректный адрес",
+			input:       []models.LinkVariant{{TargetURL: "ftp://a.example.com", Weight: 1}},
+			wantErr:     true,
+			expectedErr: models.ErrInvalidData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeVariants(tt.input)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestURLShortener_ChooseVariant(t *testing.T) {
+	service := NewServiceURLShortener(nil, "http://short")
+
+	link := models.ShortenedLink{
+		StickyVariants: true,
+		Variants: []models.LinkVariant{
+			{Name: "a", TargetURL: "https://a.example.com", Weight: 1},
+			{Name: "paused", TargetURL: "https://p.example.com", Weight: 0},
+			{Name: "b", TargetURL: "https://b.example.com", Weight: 3},
+		},
+	}
+
+	t.Run("Распределение по весам", func(t *testing.T) {
+		for point, want := range []string{"a", "b", "b", "b"} {
+			service.randIntN = func(n int) int {
+				assert.Equal(t, 4, n)
+				return point
+			}
+			got, ok := service.chooseVariant(link, "")
+			require.True(t, ok)
+			assert.Equal(t, want, got.Name)
+		}
+	})
+
+	t.Run("Закрепленный вариант", func(t *testing.T) {
+		service.randIntN = func(n int) int { return 3 }
+		got, ok := service.chooseVariant(link, "a")
+		require.True(t, ok)
+		assert.Equal(t, "a", got.Name)
+	})
+
+	t.Run("Закрепленный вариант на паузе выбирается заново", func(t *testing.T) {
+		service.randIntN = func(n int) int { return 0 }
+		got, ok := service.chooseVariant(link, "paused")
+		require.True(t, ok)
+		assert.Equal(t, "a", got.Name)
+	})
+
+	t.Run("Cookie не учитывается без закрепления", func(t *testing.T) {
+		service.randIntN = func(n int) int { return 3 }
+		unsticky := link
+		unsticky.StickyVariants = false
+		got, ok := service.chooseVariant(unsticky, "a")
+		require.True(t, ok)
+		assert.Equal(t, "b", got.Name)
+	})
+
+	t.Run("Ссылка без вариантов", func(t *testing.T) {
+		_, ok := service.chooseVariant(models.ShortenedLink{}, "")
+		assert.False(t, ok)
+	})
+}
+
+func TestURLShortener_GetLinkStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockURLStorage(ctrl)
+	service := NewServiceURLShortener(mockStorage, "http://short")
+
+	link := models.ShortenedLink{
+		ID:        7,
+		ShortCode: "abc123",
+		UserID:    1,
+		Variants: []models.LinkVariant{
+			{Name: "a", TargetURL: "https://a.example.com", Weight: 1},
+			{Name: "b", TargetURL: "https://b.example.com", Weight: 1},
+		},
+	}
+
+	t.Run("Переходы по вариантам", func(t *testing.T) {
+		mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc123").Return(link, nil)
+		mockStorage.EXPECT().ShortenedLinkVariantStats(gomock.Any(), int64(7)).
+			Return(map[string]int64{"a": 5, "removed": 2}, nil)
+
+		got, err := service.GetLinkStats(context.Background(), 1, "abc123")
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), got.Variants[0].Clicks)
+		assert.Equal(t, int64(0), got.Variants[1].Clicks)
+	})
+
+	t.Run("Незаписанные переходы учитываются", func(t *testing.T) {
+		require.NoError(t, service.RecordVariantClick(context.Background(), link, "b"))
+		mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc123").Return(link, nil)
+		mockStorage.EXPECT().ShortenedLinkVariantStats(gomock.Any(), int64(7)).
+			Return(map[string]int64{"a": 5}, nil)
+
+		got, err := service.GetLinkStats(context.Background(), 1, "abc123")
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), got.Variants[0].Clicks)
+		assert.Equal(t, int64(1), got.Variants[1].Clicks)
+	})
+
+	t.Run("Чужая ссылка", func(t *testing.T) {
+		mockStorage.EXPECT().ShortenedLinkGetByShortKey(gomock.Any(), "abc123").Return(link, nil)
+
+		_, err := service.GetLinkStats(context.Background(), 2, "abc123")
+		assert.ErrorIs(t, err, models.ErrUnfound)
+	})
+}
+
+func TestURLShortener_FlushVariantClicks(t *testing.T) {
+	link := models.ShortenedLink{ID: 7, ShortCode: "abc123", UserID: 1}
+	other := models.ShortenedLink{ID: 3, ShortCode: "xyz789", UserID: 1}
+
+	tests := []struct {
+		name        string
+		setupMocks  func(m *mocks.MockURLStorage)
+		wantErr     bool
+		wantPending map[string]int64
+	}{
+		{
+			name: "Переходы записываются одним вызовом в постоянном порядке",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().ShortenedLinkVariantAddClicks(gomock.Any(), []models.VariantClicks{
+					{LinkID: 3, Variant: "a", Clicks: 1},
+					{LinkID: 7, Variant: "a", Clicks: 2},
+					{LinkID: 7, Variant: "b", Clicks: 1},
+				}).Return(nil)
+			},
+			wantPending: map[string]int64{},
+		},
+		{
+			name: "При ошибке переходы остаются в буфере",
+			setupMocks: func(m *mocks.MockURLStorage) {
+				m.EXPECT().ShortenedLinkVariantAddClicks(gomock.Any(), gomock.Any()).Return(errors.New("connection reset"))
+			},
+			wantErr:     true,
+			wantPending: map[string]int64{"a": 2, "b": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockURLStorage(ctrl)
+			tt.setupMocks(mockStorage)
+			service := NewServiceURLShortener(mockStorage, "http://short")
+
+			ctx := context.Background()
+			require.NoError(t, service.RecordVariantClick(ctx, link, "a"))
+			require.NoError(t, service.RecordVariantClick(ctx, link, "b"))
+			require.NoError(t, service.RecordVariantClick(ctx, link, "a"))
+			require.NoError(t, service.RecordVariantClick(ctx, other, "a"))
+
+			err := service.FlushVariantClicks(ctx)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantPending, service.pendingVariantClicks(link.ID))
+
+			// Пустой буфер в хранилище не ходит
+			if !tt.wantErr {
+				require.NoError(t, service.FlushVariantClicks(ctx))
+			}
+		})
+	}
+}
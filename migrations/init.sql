@@ -3,17 +3,111 @@ CREATE TABLE IF NOT EXISTS users(
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE IF NOT EXISTS utm_templates (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    utm_source TEXT NOT NULL DEFAULT '',
+    utm_medium TEXT NOT NULL DEFAULT '',
+    utm_campaign TEXT NOT NULL DEFAULT '',
+    utm_term TEXT NOT NULL DEFAULT '',
+    utm_content TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, name)
+);
+
 CREATE TABLE IF NOT EXISTS urls (
     id BIGSERIAL PRIMARY KEY,
-    short_key VARCHAR(10) UNIQUE NOT NULL,
+    short_key VARCHAR(64) UNIQUE NOT NULL,
     original_url TEXT NOT NULL UNIQUE,
     user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
     is_deleted BOOLEAN NOT NULL DEFAULT false,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    deleted_at TIMESTAMP NULL DEFAULT NULL
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    redirect_status SMALLINT NOT NULL DEFAULT 0,
+    pass_query BOOLEAN NOT NULL DEFAULT false,
+    pass_path BOOLEAN NOT NULL DEFAULT false,
+    utm_template_id BIGINT NULL REFERENCES utm_templates(id) ON DELETE SET NULL,
+    redirect_rules JSONB NOT NULL DEFAULT '[]',
+    variants JSONB NOT NULL DEFAULT '[]',
+    sticky_variants BOOLEAN NOT NULL DEFAULT false,
+    active_from TIMESTAMPTZ NULL DEFAULT NULL,
+    active_until TIMESTAMPTZ NULL DEFAULT NULL,
+    moderation VARCHAR(16) NOT NULL DEFAULT ''
+);
+
+-- CREATE TABLE IF NOT EXISTS не меняет уже существующую таблицу, поэтому колонки,
+-- добавленные после первой версии схемы, на старой базе добавляются здесь
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS redirect_status SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS pass_query BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS pass_path BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_template_id BIGINT NULL REFERENCES utm_templates(id) ON DELETE SET NULL;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS redirect_rules JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS variants JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS sticky_variants BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS active_from TIMESTAMPTZ NULL DEFAULT NULL;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS active_until TIMESTAMPTZ NULL DEFAULT NULL;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS moderation VARCHAR(16) NOT NULL DEFAULT '';
+-- Коды длиннее 10 символов появились вместе со стратегиями генерации, увеличение длины VARCHAR не переписывает таблицу
+ALTER TABLE urls ALTER COLUMN short_key TYPE VARCHAR(64);
+
+CREATE TABLE IF NOT EXISTS url_variant_clicks (
+    url_id BIGINT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    variant VARCHAR(32) NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (url_id, variant)
+);
+
+CREATE TABLE IF NOT EXISTS abuse_reports (
+    id BIGSERIAL PRIMARY KEY,
+    url_id BIGINT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    reason VARCHAR(32) NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    reporter VARCHAR(64) NOT NULL DEFAULT '',
+    resolved BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key VARCHAR(200) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL DEFAULT true,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS short_codes (
+    code VARCHAR(64) PRIMARY KEY,
+    reserved_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE short_codes ALTER COLUMN code TYPE VARCHAR(64);
+
+CREATE TABLE IF NOT EXISTS short_code_counter (
+    id SMALLINT PRIMARY KEY,
+    next_id BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    owner VARCHAR(64) NOT NULL DEFAULT '',
+    status_code INTEGER NOT NULL DEFAULT 0,
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    body BYTEA NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS owner VARCHAR(64) NOT NULL DEFAULT '';
+
 CREATE INDEX IF NOT EXISTS idx_urls_user_id ON urls(user_id) WHERE is_deleted = false;
 CREATE INDEX IF NOT EXISTS idx_urls_short_key ON urls(short_key)WHERE is_deleted = false;
 CREATE INDEX IF NOT EXISTS idx_urls_original_url ON urls(original_url)WHERE is_deleted = false;
 CREATE INDEX IF NOT EXISTS idx_urls_is_deleted ON urls(is_deleted);
+CREATE INDEX IF NOT EXISTS idx_abuse_reports_pending ON abuse_reports(url_id) WHERE resolved = false;
+CREATE INDEX IF NOT EXISTS idx_rate_limits_updated_at ON rate_limits(updated_at);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);